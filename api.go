@@ -0,0 +1,401 @@
+package steamtracker
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Every /api/v1 response is an APIResponse: data on success, error
+// otherwise, and pagination for lists.
+type APIResponse struct {
+	Data       any            `json:"data,omitempty"`
+	Pagination *APIPagination `json:"pagination,omitempty"`
+	Error      *APIError      `json:"error,omitempty"`
+}
+
+type APIPagination struct {
+	Page       int    `json:"page,omitempty"`
+	PerPage    int    `json:"per_page"`
+	TotalCount *int64 `json:"total_count,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// APIError codes are stable and meant for programs, messages are for
+// people and may change.
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+const (
+	APIErrorInvalidQuery     = "invalid_query"
+	APIErrorNotFound         = "not_found"
+	APIErrorMethodNotAllowed = "method_not_allowed"
+	APIErrorInternal         = "internal_error"
+)
+
+func writeAPIResponse(w http.ResponseWriter, status int, resp *APIResponse) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Error().Err(err).Msg("Failed to encode response")
+	}
+}
+
+func writeAPIData(w http.ResponseWriter, data any, pagination *APIPagination) {
+	writeAPIResponse(w, http.StatusOK, &APIResponse{Data: data, Pagination: pagination})
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeAPIResponse(w, status, &APIResponse{Error: &APIError{Code: code, Message: message}})
+}
+
+// writeAPIQueryError reports invalid query parameters, listing each one in
+// details if they failed to parse or to validate.
+func writeAPIQueryError(w http.ResponseWriter, err error) {
+	apiErr := &APIError{Code: APIErrorInvalidQuery, Message: err.Error()}
+
+	var bindErr *BindError
+	if errors.As(err, &bindErr) {
+		apiErr.Message = "invalid query parameters"
+		apiErr.Details = bindErr.Fields
+	}
+
+	writeAPIResponse(w, http.StatusBadRequest, &APIResponse{Error: apiErr})
+}
+
+// APIRoute is an /api/v1 endpoint. APIRoutes is what the mux serves and is
+// checked against openapi.json in tests.
+type APIRoute struct {
+	Method  string
+	Pattern string
+	// Scope is what the route requires of an API token. Routes without one
+	// are public.
+	Scope   string
+	Handler http.HandlerFunc
+}
+
+func (st *SteamTracker) APIRoutes() []APIRoute {
+	return []APIRoute{
+		{http.MethodGet, "/api/v1/openapi.json", "", st.GetOpenAPI},
+		{http.MethodGet, "/api/v1/players", ScopeReadPlayers, st.GetV1Players},
+		{http.MethodGet, "/api/v1/players/current", ScopeReadPlayers, st.GetV1CurrentPlayers},
+		{http.MethodGet, "/api/v1/players/{steam_id}", ScopeReadPlayers, st.GetV1Player},
+		{http.MethodGet, "/api/v1/players/{steam_id}/events", ScopeReadPlayers, st.GetV1PlayerEvents},
+		{http.MethodGet, "/api/v1/players/{steam_id}/sessions", ScopeReadPlayers, st.GetV1PlayerSessions},
+		{http.MethodGet, "/api/v1/player_events", ScopeReadPlayers, st.GetV1PlayerEvents},
+		{http.MethodGet, "/api/v1/player_rollups", ScopeReadPlayers, st.GetV1PlayerRollups},
+		{http.MethodGet, "/api/v1/audit_logs", ScopeReadAudit, st.GetV1AuditLogs},
+		{http.MethodGet, "/api/v1/audit_logs/head", ScopeReadAudit, st.GetV1AuditChainHead},
+		{http.MethodGet, "/api/v1/export/{table}", ScopeAdmin, st.GetV1Export},
+		{http.MethodGet, "/api/v1/events/stream", ScopeReadPlayers, st.GetV1EventStream},
+		{http.MethodGet, "/api/v1/ws", ScopeReadPlayers, st.GetV1WebSocket},
+	}
+}
+
+// legacyAPIDeprecatedAt is when the unversioned /api routes were superseded
+// by /api/v1.
+var legacyAPIDeprecatedAt = time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+// deprecated serves a pre-v1 route unchanged, but points clients at its
+// /api/v1 successor with Deprecation (RFC 9745) and Link headers.
+func deprecated(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		successor := "/api/v1" + strings.TrimPrefix(r.URL.Path, "/api")
+		w.Header().Set("Deprecation", fmt.Sprintf("@%d", legacyAPIDeprecatedAt.Unix()))
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		h(w, r)
+	}
+}
+
+// Handler returns the HTTP handler serving the dashboard and the API.
+//
+// Routes are registered with method patterns, so GET routes also answer
+// HEAD. Requests that match no route get a JSON 404, or a JSON 405 with
+// Allow if the path exists for other methods; OPTIONS is answered with 204
+// and Allow.
+func (st *SteamTracker) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	for _, route := range st.APIRoutes() {
+		mux.HandleFunc(route.Method+" "+route.Pattern, st.requireScope(route.Scope, route.Handler))
+	}
+
+	mux.HandleFunc("GET /api/players", st.requireScope(ScopeReadPlayers, deprecated(st.GetSearchPlayers)))
+	mux.HandleFunc("GET /api/players/current", st.requireScope(ScopeReadPlayers, deprecated(st.GetCurrentPlayers)))
+	mux.HandleFunc("GET /api/player_events", st.requireScope(ScopeReadPlayers, deprecated(st.GetSearchPlayerEvents)))
+	mux.HandleFunc("GET /api/audit_logs", st.requireScope(ScopeReadAudit, deprecated(st.GetSearchAuditLogs)))
+	mux.HandleFunc("GET /api/audit_logs/head", st.requireScope(ScopeReadAudit, deprecated(st.GetAuditChainHead)))
+	mux.HandleFunc("GET /api/player_rollups", st.requireScope(ScopeReadPlayers, deprecated(st.GetSearchPlayerRollups)))
+	mux.HandleFunc("GET /api/export/{table}", st.requireScope(ScopeAdmin, deprecated(st.GetExport)))
+	mux.HandleFunc("GET /api/events/stream", st.requireScope(ScopeReadPlayers, deprecated(st.GetV1EventStream)))
+	mux.HandleFunc("GET /api/ws", st.requireScope(ScopeReadPlayers, deprecated(st.GetV1WebSocket)))
+	mux.HandleFunc("GET /graphql", st.requireScope(ScopeReadPlayers, st.ServeGraphQL))
+	mux.HandleFunc("POST /graphql", st.requireScope(ScopeReadPlayers, st.ServeGraphQL))
+	mux.HandleFunc("GET /{$}", st.requireDashboardAuth(st.GetIndex))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		// Let the mux work out whether the path exists for other methods,
+		// then answer in JSON instead of its plain text.
+		unmatched := &unmatchedWriter{header: make(http.Header)}
+		mux.ServeHTTP(unmatched, r)
+		allow := unmatched.header.Get("Allow")
+
+		switch {
+		case unmatched.status == http.StatusMethodNotAllowed && r.Method == http.MethodOptions:
+			w.Header().Set("Allow", allow+", "+http.MethodOptions)
+			w.WriteHeader(http.StatusNoContent)
+		case unmatched.status == http.StatusMethodNotAllowed:
+			w.Header().Set("Allow", allow+", "+http.MethodOptions)
+			writeAPIError(w, http.StatusMethodNotAllowed, APIErrorMethodNotAllowed, fmt.Sprintf("%s is not allowed on %s, use %s", r.Method, r.URL.Path, allow))
+		default:
+			writeAPIError(w, http.StatusNotFound, APIErrorNotFound, fmt.Sprintf("no such endpoint: %s", r.URL.Path))
+		}
+	})
+}
+
+// unmatchedWriter records the status and headers the mux sends for a
+// request without a matching route.
+type unmatchedWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *unmatchedWriter) Header() http.Header         { return w.header }
+func (w *unmatchedWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *unmatchedWriter) WriteHeader(status int)      { w.status = status }
+
+//go:embed openapi.json
+var openAPI []byte
+
+// OpenAPI returns the OpenAPI 3 document describing /api/v1.
+func OpenAPI() []byte {
+	return openAPI
+}
+
+func (st *SteamTracker) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if _, err := w.Write(openAPI); err != nil {
+		log.Error().Err(err).Msg("Failed to write response")
+	}
+}
+
+func (st *SteamTracker) GetV1Players(w http.ResponseWriter, r *http.Request) {
+	query := SearchPlayersQuery{}
+	if err := bindQuery(r, &query); err != nil {
+		writeAPIQueryError(w, err)
+		return
+	}
+
+	result, err := st.SearchPlayers(r.Context(), &query)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, APIErrorInternal, fmt.Sprintf("failed to search players: %v", err))
+		return
+	}
+
+	writeAPIData(w, result.Players, &APIPagination{
+		Page:       result.Page,
+		PerPage:    result.PerPage,
+		TotalCount: result.TotalCount,
+		NextCursor: result.NextCursor,
+	})
+}
+
+func (st *SteamTracker) GetV1CurrentPlayers(w http.ResponseWriter, r *http.Request) {
+	query := GetCurrentPlayersQuery{}
+	if err := bindQuery(r, &query); err != nil {
+		writeAPIQueryError(w, err)
+		return
+	}
+
+	result, err := st.CurrentPlayers(r.Context(), &query)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, APIErrorInternal, fmt.Sprintf("failed to get current players: %v", err))
+		return
+	}
+
+	writeAPIData(w, result.Players, nil)
+}
+
+func (st *SteamTracker) GetV1Player(w http.ResponseWriter, r *http.Request) {
+	query := GetCurrentPlayerQuery{}
+	if err := bindQuery(r, &query, "steam_id"); err != nil {
+		writeAPIQueryError(w, err)
+		return
+	}
+
+	player, err := st.CurrentPlayer(r.Context(), &query)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, APIErrorInternal, fmt.Sprintf("failed to get player: %v", err))
+		return
+	}
+	if player == nil {
+		writeAPIError(w, http.StatusNotFound, APIErrorNotFound, fmt.Sprintf("player %s is not tracked", query.SteamID))
+		return
+	}
+
+	writeAPIData(w, player, nil)
+}
+
+func (st *SteamTracker) GetV1PlayerSessions(w http.ResponseWriter, r *http.Request) {
+	query := GetPlayerSessionsQuery{}
+	if err := bindQuery(r, &query, "steam_id"); err != nil {
+		writeAPIQueryError(w, err)
+		return
+	}
+
+	sessions, err := st.PlayerSessions(r.Context(), &query)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, APIErrorInternal, fmt.Sprintf("failed to get player sessions: %v", err))
+		return
+	}
+
+	writeAPIData(w, sessions, nil)
+}
+
+// GetV1PlayerEvents serves both /player_events and /players/{steam_id}/events.
+func (st *SteamTracker) GetV1PlayerEvents(w http.ResponseWriter, r *http.Request) {
+	query := SearchPlayerEventsQuery{}
+	if err := bindQuery(r, &query, "steam_id"); err != nil {
+		writeAPIQueryError(w, err)
+		return
+	}
+
+	result, err := st.SearchPlayerEvents(&query)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, APIErrorInternal, fmt.Sprintf("failed to search player events: %v", err))
+		return
+	}
+
+	writeAPIData(w, result.PlayerEvents, &APIPagination{
+		Page:       result.Page,
+		PerPage:    result.PerPage,
+		TotalCount: result.TotalCount,
+		NextCursor: result.NextCursor,
+	})
+}
+
+func (st *SteamTracker) GetV1PlayerRollups(w http.ResponseWriter, r *http.Request) {
+	query := SearchPlayerRollupsQuery{}
+	if err := bindQuery(r, &query); err != nil {
+		writeAPIQueryError(w, err)
+		return
+	}
+
+	result, err := st.SearchPlayerRollups(r.Context(), &query)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, APIErrorInternal, fmt.Sprintf("failed to search player rollups: %v", err))
+		return
+	}
+
+	writeAPIData(w, result.Rollups, &APIPagination{
+		Page:       result.Page,
+		PerPage:    result.PerPage,
+		TotalCount: &result.TotalCount,
+	})
+}
+
+// auditLogResource is the /api/v1 form of an audit log. AuditLog.MarshalJSON
+// renames the ID and splices the raw payload into the top level for the
+// dashboard; here the payload stays under raw.
+type auditLogResource struct {
+	ID        int64           `json:"id"`
+	Level     string          `json:"level"`
+	Message   string          `json:"message"`
+	Action    string          `json:"action"`
+	SteamID   *SteamID        `json:"steam_id"`
+	Error     string          `json:"error"`
+	LoggedAt  *time.Time      `json:"logged_at"`
+	PrevHash  string          `json:"prev_hash"`
+	Hash      string          `json:"hash"`
+	CreatedAt time.Time       `json:"created_at"`
+	Snippet   string          `json:"snippet,omitempty"`
+	Raw       json.RawMessage `json:"raw"`
+}
+
+func newAuditLogResource(al *AuditLog) *auditLogResource {
+	raw := json.RawMessage(al.Raw)
+	if len(raw) == 0 {
+		raw = json.RawMessage("null")
+	}
+
+	return &auditLogResource{
+		ID:        al.ID,
+		Level:     al.Level,
+		Message:   al.Message,
+		Action:    al.Action,
+		SteamID:   al.SteamID,
+		Error:     al.Error,
+		LoggedAt:  al.LoggedAt,
+		PrevHash:  al.PrevHash,
+		Hash:      al.Hash,
+		CreatedAt: al.CreatedAt,
+		Snippet:   al.Snippet,
+		Raw:       raw,
+	}
+}
+
+func (st *SteamTracker) GetV1AuditLogs(w http.ResponseWriter, r *http.Request) {
+	query := SearchAuditLogsQuery{}
+	if err := bindQuery(r, &query); err != nil {
+		writeAPIQueryError(w, err)
+		return
+	}
+
+	result, err := st.SearchAuditLogs(&query)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, APIErrorInternal, fmt.Sprintf("failed to search audit logs: %v", err))
+		return
+	}
+
+	auditLogs := make([]*auditLogResource, 0, len(result.AuditLogs))
+	for _, al := range result.AuditLogs {
+		auditLogs = append(auditLogs, newAuditLogResource(al))
+	}
+
+	writeAPIData(w, auditLogs, &APIPagination{
+		Page:       result.Page,
+		PerPage:    result.PerPage,
+		TotalCount: result.TotalCount,
+		NextCursor: result.NextCursor,
+	})
+}
+
+func (st *SteamTracker) GetV1AuditChainHead(w http.ResponseWriter, r *http.Request) {
+	head, err := st.AuditChainHead(r.Context())
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, APIErrorInternal, fmt.Sprintf("failed to get audit chain head: %v", err))
+		return
+	}
+	if head == nil {
+		writeAPIError(w, http.StatusNotFound, APIErrorNotFound, "no audit logs")
+		return
+	}
+
+	writeAPIData(w, head, nil)
+}
+
+// GetV1Export streams the export file itself rather than an envelope; only
+// errors before the first row use the envelope.
+func (st *SteamTracker) GetV1Export(w http.ResponseWriter, r *http.Request) {
+	query := ExportQuery{Table: r.PathValue("table")}
+	if err := bindQuery(r, &query); err != nil {
+		writeAPIQueryError(w, err)
+		return
+	}
+
+	st.writeExport(w, r, &query)
+}
@@ -0,0 +1,1348 @@
+package steamtracker_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"gorm.io/gorm"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+	steamtrackerv1 "github.com/willywotz/steam-tracker/proto/steamtracker/v1"
+)
+
+type openAPIDocument struct {
+	OpenAPI string `json:"openapi"`
+	Paths   map[string]map[string]struct {
+		Deprecated bool                       `json:"deprecated"`
+		Responses  map[string]json.RawMessage `json:"responses"`
+	} `json:"paths"`
+}
+
+func loadOpenAPI(t *testing.T) *openAPIDocument {
+	t.Helper()
+
+	doc := openAPIDocument{}
+	if err := json.Unmarshal(steamtracker.OpenAPI(), &doc); err != nil {
+		t.Fatalf("Failed to parse openapi.json: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Fatalf("Expected an OpenAPI 3 document, got %q", doc.OpenAPI)
+	}
+
+	return &doc
+}
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	doc := loadOpenAPI(t)
+
+	// Deprecated operations are legacy aliases outside APIRoutes; they only
+	// need to be served.
+	handler := st.Handler()
+	documented := make([]string, 0)
+	for path, operations := range doc.Paths {
+		for method, operation := range operations {
+			if !operation.Deprecated {
+				documented = append(documented, strings.ToUpper(method)+" "+path)
+				continue
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(strings.ToUpper(method), path, nil))
+			if w.Code == http.StatusNotFound || w.Code == http.StatusMethodNotAllowed {
+				t.Errorf("openapi.json documents %s %s but the handler answers %d", strings.ToUpper(method), path, w.Code)
+			}
+		}
+	}
+
+	served := make([]string, 0)
+	for _, route := range st.APIRoutes() {
+		served = append(served, route.Method+" "+route.Pattern)
+	}
+
+	slices.Sort(documented)
+	slices.Sort(served)
+	if !slices.Equal(documented, served) {
+		t.Errorf("openapi.json documents\n%v\nbut the handler serves\n%v", documented, served)
+	}
+}
+
+func TestAPIV1ResponsesMatchOpenAPI(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	doc := loadOpenAPI(t)
+	handler := st.Handler()
+
+	steamID := uniqueSteamID()
+	if err := st.AddPlayer(&steamtracker.Player{SteamID: steamID, PersonaName: "Test Player", PersonaState: steamtracker.PersonaStateOnline}); err != nil {
+		t.Fatalf("Failed to add player: %v", err)
+	}
+
+	for path, operations := range doc.Paths {
+		for method, operation := range operations {
+			target := strings.ReplaceAll(path, "{table}", "players")
+			target = strings.ReplaceAll(target, "{steam_id}", steamID.String())
+			t.Run(strings.ToUpper(method)+" "+target, func(t *testing.T) {
+				// The event stream only ends when the client goes away.
+				ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+				defer cancel()
+
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, httptest.NewRequestWithContext(ctx, strings.ToUpper(method), target, nil))
+
+				if _, ok := operation.Responses[strconv.Itoa(w.Code)]; !ok {
+					t.Fatalf("Status %d is not documented: %s", w.Code, w.Body.String())
+				}
+
+				if path == "/api/v1/openapi.json" || strings.HasPrefix(path, "/api/v1/export/") || path == "/api/v1/events/stream" {
+					return
+				}
+
+				envelope := map[string]json.RawMessage{}
+				if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+					t.Fatalf("Response is not a JSON object: %v", err)
+				}
+				for key := range envelope {
+					if key != "data" && key != "pagination" && key != "error" {
+						t.Errorf("Unexpected envelope key %q", key)
+					}
+				}
+				if w.Code == http.StatusOK && envelope["data"] == nil {
+					t.Errorf("Expected data in a successful response: %s", w.Body.String())
+				}
+				if w.Code != http.StatusOK && envelope["error"] == nil {
+					t.Errorf("Expected error in a failed response: %s", w.Body.String())
+				}
+			})
+		}
+	}
+}
+
+func TestAPIV1Errors(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	handler := st.Handler()
+
+	tests := []struct {
+		target string
+		status int
+		code   string
+	}{
+		{"/api/v1/players?cursor=invalid", http.StatusBadRequest, steamtracker.APIErrorInvalidQuery},
+		{"/api/v1/player_rollups?granularity=week", http.StatusBadRequest, steamtracker.APIErrorInvalidQuery},
+		{"/api/v1/audit_logs/head", http.StatusNotFound, steamtracker.APIErrorNotFound},
+		{"/api/v1/export/secrets", http.StatusBadRequest, steamtracker.APIErrorInvalidQuery},
+		{"/api/v1/unknown", http.StatusNotFound, steamtracker.APIErrorNotFound},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+		resp := steamtracker.APIResponse{}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Errorf("%s: response is not JSON: %v", tt.target, err)
+			continue
+		}
+		if w.Code != tt.status || resp.Error == nil || resp.Error.Code != tt.code {
+			t.Errorf("%s: expected %d %s, got %d %s", tt.target, tt.status, tt.code, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestLegacyAPIRoutesAreDeprecated(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	handler := st.Handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/player_events?limit=5", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if !strings.HasPrefix(w.Header().Get("Deprecation"), "@") {
+		t.Errorf("Expected a Deprecation date, got %q", w.Header().Get("Deprecation"))
+	}
+	if link := w.Header().Get("Link"); link != `</api/v1/player_events>; rel="successor-version"` {
+		t.Errorf("Unexpected Link header %q", link)
+	}
+
+	result := map[string]any{}
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Response is not JSON: %v", err)
+	}
+	if _, ok := result["player_events"]; !ok {
+		t.Errorf("Expected the legacy response format, got %s", w.Body.String())
+	}
+}
+
+func TestRouting(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	handler := st.Handler()
+
+	tests := []struct {
+		method string
+		target string
+		status int
+		code   string
+		allow  string
+	}{
+		{http.MethodGet, "/", http.StatusOK, "", ""},
+		{http.MethodGet, "/favicon.ico", http.StatusNotFound, steamtracker.APIErrorNotFound, ""},
+		{http.MethodGet, "/api/playerz", http.StatusNotFound, steamtracker.APIErrorNotFound, ""},
+		{http.MethodGet, "/api/v1/players/1/friends", http.StatusNotFound, steamtracker.APIErrorNotFound, ""},
+		{http.MethodPost, "/api/v1/players", http.StatusMethodNotAllowed, steamtracker.APIErrorMethodNotAllowed, "GET, HEAD, OPTIONS"},
+		{http.MethodDelete, "/api/players", http.StatusMethodNotAllowed, steamtracker.APIErrorMethodNotAllowed, "GET, HEAD, OPTIONS"},
+		{http.MethodOptions, "/api/v1/players/1/sessions", http.StatusNoContent, "", "GET, HEAD, OPTIONS"},
+		{http.MethodHead, "/api/v1/player_events", http.StatusOK, "", ""},
+		{http.MethodGet, "/api/v1/players/abc", http.StatusBadRequest, steamtracker.APIErrorInvalidQuery, ""},
+		{http.MethodGet, "/api/v1/players/1", http.StatusNotFound, steamtracker.APIErrorNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if allow := w.Header().Get("Allow"); allow != tt.allow {
+				t.Errorf("Expected Allow %q, got %q", tt.allow, allow)
+			}
+			if tt.code == "" {
+				return
+			}
+
+			resp := steamtracker.APIResponse{}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Response is not JSON: %v", err)
+			}
+			if resp.Error == nil || resp.Error.Code != tt.code {
+				t.Errorf("Expected error code %s, got %s", tt.code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestPlayerResources(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	handler := st.Handler()
+	steamID := uniqueSteamID()
+
+	for _, state := range []steamtracker.PersonaState{
+		steamtracker.PersonaStateOnline,
+		steamtracker.PersonaStateAway,
+		steamtracker.PersonaStateOffline,
+		steamtracker.PersonaStateBusy,
+	} {
+		if err := st.AddPlayer(&steamtracker.Player{SteamID: steamID, PersonaName: "Test Player", PersonaState: state}); err != nil {
+			t.Fatalf("Failed to add player: %v", err)
+		}
+	}
+
+	get := func(target string, data any) {
+		t.Helper()
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d: %s", target, w.Code, w.Body.String())
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &steamtracker.APIResponse{Data: data}); err != nil {
+			t.Fatalf("GET %s: response is not JSON: %v", target, err)
+		}
+	}
+
+	prefix := "/api/v1/players/" + steamID.String()
+
+	player := steamtracker.PlayerCurrentState{}
+	get(prefix, &player)
+	if player.SteamID != steamID || player.PersonaState != steamtracker.PersonaStateBusy {
+		t.Errorf("Expected player %s to be Busy, got %+v", steamID, player)
+	}
+
+	events := make([]*steamtracker.PlayerEvent, 0)
+	get(prefix+"/events?steam_id=1", &events)
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 events, got %d", len(events))
+	}
+	for _, e := range events {
+		if e.SteamID != steamID {
+			t.Errorf("Expected only events of %s, got %s", steamID, e.SteamID)
+		}
+	}
+
+	sessions := make([]*steamtracker.PlayerSession, 0)
+	get(prefix+"/sessions", &sessions)
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+	}
+	if sessions[0].EndedAt == nil || sessions[0].StartedAt.After(*sessions[0].EndedAt) {
+		t.Errorf("Expected the first session to have ended, got %+v", sessions[0])
+	}
+	if sessions[1].EndedAt != nil {
+		t.Errorf("Expected the second session to be ongoing, got %+v", sessions[1])
+	}
+}
+
+// readStreamEvent returns the id and data of the next event on an SSE stream.
+func readStreamEvent(t *testing.T, r *bufio.Reader) (string, *steamtracker.PlayerEvent) {
+	t.Helper()
+
+	id := ""
+	e := &steamtracker.PlayerEvent{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "" && id != "":
+			return id, e
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), e); err != nil {
+				t.Fatalf("Event data is not JSON: %v", err)
+			}
+		}
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	server := httptest.NewServer(st.Handler())
+	t.Cleanup(server.Close)
+
+	watched, other := uniqueSteamID(), uniqueSteamID()
+
+	connect := func(lastEventID string) *bufio.Reader {
+		t.Helper()
+
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL+"/api/v1/events/stream?steam_ids="+watched.String(), nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to connect to the event stream: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("Expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		return bufio.NewReader(resp.Body)
+	}
+
+	live := connect("")
+
+	for _, player := range []*steamtracker.Player{
+		{SteamID: other, PersonaName: "Other Player", PersonaState: steamtracker.PersonaStateOnline},
+		{SteamID: watched, PersonaName: "Test Player", PersonaState: steamtracker.PersonaStateOnline},
+		{SteamID: watched, PersonaName: "Test Player", PersonaState: steamtracker.PersonaStateBusy},
+	} {
+		if err := st.AddPlayer(player); err != nil {
+			t.Fatalf("Failed to add player: %v", err)
+		}
+	}
+
+	firstID, first := readStreamEvent(t, live)
+	if first.SteamID != watched || first.Type != steamtracker.PlayerEventTypeFirstSeen {
+		t.Errorf("Expected the first event of %s, got %+v", watched, first)
+	}
+	if firstID != strconv.FormatInt(first.ID, 10) {
+		t.Errorf("Expected the SSE id to be the event ID %d, got %s", first.ID, firstID)
+	}
+	if _, second := readStreamEvent(t, live); second.PersonaState != steamtracker.PersonaStateBusy {
+		t.Errorf("Expected the second event to be Busy, got %s", second.PersonaState)
+	}
+
+	resumed := connect(firstID)
+	if _, e := readStreamEvent(t, resumed); e.PersonaState != steamtracker.PersonaStateBusy {
+		t.Errorf("Expected to resume with the Busy event, got %+v", e)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/events/stream", nil)
+	req.Header.Set("Last-Event-ID", "yesterday")
+	st.Handler().ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid Last-Event-ID, got %d", w.Code)
+	}
+}
+
+func TestEventStreamHeartbeat(t *testing.T) {
+	st, err := steamtracker.Open(&steamtracker.Config{
+		DatabaseDSN:          testDSNs(t)["sqlite"],
+		DisableAuth:          true,
+		EventStreamHeartbeat: 10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open tracker: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	server := httptest.NewServer(st.Handler())
+	t.Cleanup(server.Close)
+
+	// The unversioned path the stream was first asked for is served too.
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL+"/api/events/stream", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to connect to the event stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if link := resp.Header.Get("Link"); link != `</api/v1/events/stream>; rel="successor-version"` {
+		t.Errorf("Unexpected Link header %q", link)
+	}
+
+	r := bufio.NewReader(resp.Body)
+	for range 2 {
+		line, err := r.ReadString('\n')
+		for err == nil && line == "\n" {
+			line, err = r.ReadString('\n')
+		}
+		if err != nil {
+			t.Fatalf("Failed to read event stream: %v", err)
+		}
+		if line != ": heartbeat\n" {
+			t.Fatalf("Expected a heartbeat on the idle stream, got %q", line)
+		}
+	}
+}
+
+// blockingStreamWriter is an event stream client that stops reading: writing
+// an event blocks until unblock is closed.
+type blockingStreamWriter struct {
+	header  http.Header
+	ready   chan struct{}
+	unblock chan struct{}
+	events  atomic.Int64
+}
+
+func (w *blockingStreamWriter) Header() http.Header { return w.header }
+func (w *blockingStreamWriter) WriteHeader(int)     { close(w.ready) }
+func (w *blockingStreamWriter) Flush()              {}
+
+func (w *blockingStreamWriter) Write(p []byte) (int, error) {
+	if strings.Contains(string(p), "event: player_event") {
+		<-w.unblock
+		w.events.Add(1)
+	}
+	return len(p), nil
+}
+
+func TestEventStreamDropsSlowClients(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	steamID := uniqueSteamID()
+
+	w := &blockingStreamWriter{header: make(http.Header), ready: make(chan struct{}), unblock: make(chan struct{})}
+	served := make(chan struct{})
+	go func() {
+		st.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/events/stream", nil))
+		close(served)
+	}()
+	<-w.ready
+
+	// More events than the client's buffer holds, while it reads none of
+	// them. Recording them must not wait for the client.
+	const published = 70
+	recorded := make(chan error, 1)
+	go func() {
+		states := []steamtracker.PersonaState{steamtracker.PersonaStateOnline, steamtracker.PersonaStateBusy}
+		for i := range published {
+			if err := st.AddPlayer(&steamtracker.Player{SteamID: steamID, PersonaName: "Test Player", PersonaState: states[i%2]}); err != nil {
+				recorded <- err
+				return
+			}
+		}
+		recorded <- nil
+	}()
+	select {
+	case err := <-recorded:
+		if err != nil {
+			t.Fatalf("Failed to add player: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected recording events not to wait for a slow client")
+	}
+
+	// The dropped client is sent what was buffered, then disconnected.
+	close(w.unblock)
+	select {
+	case <-served:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the slow client to be disconnected")
+	}
+	if sent := w.events.Load(); sent == 0 || sent >= published {
+		t.Errorf("Expected some but not all of the %d events to be sent before the drop, got %d", published, sent)
+	}
+	if got := countPlayerEvents(t, st, steamID); got != published {
+		t.Errorf("Expected every event to be stored, got %d", got)
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	st, err := steamtracker.Open(&steamtracker.Config{
+		DatabaseDSN:      testDSNs(t)["sqlite"],
+		DisableTask:      true,
+		DisableAuth:      true,
+		WebSocketOrigins: []string{"*.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to open tracker: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	server := httptest.NewServer(st.Handler())
+	t.Cleanup(server.Close)
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws"
+
+	if _, resp, err := websocket.Dial(t.Context(), wsURL, &websocket.DialOptions{
+		HTTPHeader: http.Header{"Origin": {"https://evil.example.org"}},
+	}); err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected 403 for a foreign origin, got %v", err)
+	}
+
+	legacy, resp, err := websocket.Dial(t.Context(), "ws"+strings.TrimPrefix(server.URL, "http")+"/api/ws", nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to the legacy route: %v", err)
+	}
+	if resp.Header.Get("Deprecation") == "" {
+		t.Error("Expected the legacy route to send Deprecation")
+	}
+	legacy.Close(websocket.StatusNormalClosure, "")
+
+	conn, _, err := websocket.Dial(t.Context(), wsURL, &websocket.DialOptions{
+		HTTPHeader: http.Header{"Origin": {"https://dashboard.example.com"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.CloseNow()
+
+	send := func(msg string) {
+		t.Helper()
+		if err := conn.Write(t.Context(), websocket.MessageText, []byte(msg)); err != nil {
+			t.Fatalf("Failed to send %s: %v", msg, err)
+		}
+	}
+	receive := func() *steamtracker.WSMessage {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+		defer cancel()
+
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatalf("Failed to receive a message: %v", err)
+		}
+		msg := &steamtracker.WSMessage{}
+		if err := json.Unmarshal(data, msg); err != nil {
+			t.Fatalf("Message is not JSON: %v", err)
+		}
+		return msg
+	}
+
+	watched, other := uniqueSteamID(), uniqueSteamID()
+
+	send(`{"type":"subscribe","id":"1","steam_ids":["` + watched.String() + `"]}`)
+	if msg := receive(); msg.Type != steamtracker.WSMessageSubscribe || msg.ID != "1" || !slices.Equal(msg.SteamIDs, []steamtracker.SteamID{watched}) {
+		t.Fatalf("Expected the subscription to be confirmed, got %+v", msg)
+	}
+
+	for _, steamID := range []steamtracker.SteamID{other, watched} {
+		if err := st.AddPlayer(&steamtracker.Player{SteamID: steamID, PersonaName: "Test Player", PersonaState: steamtracker.PersonaStateOnline}); err != nil {
+			t.Fatalf("Failed to add player: %v", err)
+		}
+	}
+	if msg := receive(); msg.Type != steamtracker.WSMessageEvent || msg.Event == nil || msg.Event.SteamID != watched {
+		t.Fatalf("Expected an event of %s, got %+v", watched, msg)
+	}
+
+	tests := []struct {
+		send string
+		code string
+	}{
+		{`{"type":"poll_now","id":"2"}`, steamtracker.APIErrorUnavailable},
+		{`{"type":"subscribe","steam_ids":[]}`, steamtracker.APIErrorInvalidMessage},
+		{`{"type":"shutdown"}`, steamtracker.APIErrorInvalidMessage},
+		{`not json`, steamtracker.APIErrorInvalidMessage},
+	}
+	for _, tt := range tests {
+		send(tt.send)
+		if msg := receive(); msg.Type != steamtracker.WSMessageError || msg.Error == nil || msg.Error.Code != tt.code {
+			t.Errorf("%s: expected error %s, got %+v", tt.send, tt.code, msg)
+		}
+	}
+
+	send(`{"type":"unsubscribe","id":"3"}`)
+	if msg := receive(); msg.Type != steamtracker.WSMessageUnsubscribe || msg.ID != "3" || len(msg.SteamIDs) != 0 {
+		t.Fatalf("Expected every subscription to be removed, got %+v", msg)
+	}
+
+	limited := false
+	for range 20 {
+		send(`{"type":"unsubscribe"}`)
+		if msg := receive(); msg.Type == steamtracker.WSMessageError && msg.Error.Code == steamtracker.APIErrorRateLimited {
+			limited = true
+			break
+		}
+	}
+	if !limited {
+		t.Error("Expected messages to be rate limited")
+	}
+	send(`not json`)
+	if msg := receive(); msg.Type != steamtracker.WSMessageError || msg.Error.Code != steamtracker.APIErrorRateLimited {
+		t.Errorf("Expected invalid messages to count toward the limit, got %+v", msg)
+	}
+
+	conn.Close(websocket.StatusNormalClosure, "")
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+func postGraphQL(t *testing.T, handler http.Handler, query string, variables map[string]any) (int, *graphQLResponse) {
+	t.Helper()
+
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))))
+
+	resp := graphQLResponse{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Response is not JSON: %v: %s", err, w.Body.String())
+	}
+	return w.Code, &resp
+}
+
+func TestGraphQL(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	handler := st.Handler()
+	alice, bob := uniqueSteamID(), uniqueSteamID()
+
+	add := func(steamID steamtracker.SteamID, state steamtracker.PersonaState) {
+		t.Helper()
+		if err := st.AddPlayer(&steamtracker.Player{SteamID: steamID, PersonaName: "Player " + steamID.String(), PersonaState: state}); err != nil {
+			t.Fatalf("Failed to add player: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	add(alice, steamtracker.PersonaStateOnline)
+	add(bob, steamtracker.PersonaStateOnline)
+	since := time.Now()
+	time.Sleep(5 * time.Millisecond)
+	add(alice, steamtracker.PersonaStateOffline)
+	add(alice, steamtracker.PersonaStateOnline)
+
+	post := func(query string, variables map[string]any) (int, *graphQLResponse) {
+		t.Helper()
+		return postGraphQL(t, handler, query, variables)
+	}
+
+	status, resp := post(`query($ids: [ID!], $since: DateTime) {
+		players(steamIds: $ids) {
+			steamId
+			personaState
+			recentEvents(limit: 2) { type personaState player { steamId } }
+			sessions(since: $since) { endedAt }
+		}
+	}`, map[string]any{"ids": []string{alice.String(), bob.String()}, "since": since.Format(time.RFC3339Nano)})
+	if status != http.StatusOK || len(resp.Errors) > 0 {
+		t.Fatalf("Expected 200 without errors, got %d: %+v", status, resp.Errors)
+	}
+
+	data := struct {
+		Players []struct {
+			SteamID      string `json:"steamId"`
+			PersonaState string `json:"personaState"`
+			RecentEvents []struct {
+				Type         string `json:"type"`
+				PersonaState string `json:"personaState"`
+				Player       struct {
+					SteamID string `json:"steamId"`
+				} `json:"player"`
+			} `json:"recentEvents"`
+			Sessions []struct {
+				EndedAt *time.Time `json:"endedAt"`
+			} `json:"sessions"`
+		} `json:"players"`
+	}{}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("Unexpected data: %v", err)
+	}
+	if len(data.Players) != 2 {
+		t.Fatalf("Expected 2 players, got %d", len(data.Players))
+	}
+
+	for _, p := range data.Players {
+		if p.PersonaState != "ONLINE" {
+			t.Errorf("Expected %s to be ONLINE, got %s", p.SteamID, p.PersonaState)
+		}
+		for _, e := range p.RecentEvents {
+			if e.Player.SteamID != p.SteamID {
+				t.Errorf("Expected the events of %s to belong to them, got %s", p.SteamID, e.Player.SteamID)
+			}
+		}
+
+		switch p.SteamID {
+		case alice.String():
+			if len(p.RecentEvents) != 2 || p.RecentEvents[0].PersonaState != "ONLINE" || p.RecentEvents[1].PersonaState != "OFFLINE" {
+				t.Errorf("Expected alice's 2 newest events, newest first, got %+v", p.RecentEvents)
+			}
+			// The first session started before since and is still counted.
+			if len(p.Sessions) != 2 || p.Sessions[0].EndedAt == nil || p.Sessions[1].EndedAt != nil {
+				t.Errorf("Expected alice to have an ended and an ongoing session, got %+v", p.Sessions)
+			}
+		case bob.String():
+			if len(p.RecentEvents) != 1 || p.RecentEvents[0].Type != "FIRST_SEEN" {
+				t.Errorf("Expected bob's first_seen event, got %+v", p.RecentEvents)
+			}
+			if len(p.Sessions) != 1 || p.Sessions[0].EndedAt != nil {
+				t.Errorf("Expected bob to have an ongoing session, got %+v", p.Sessions)
+			}
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape("{ player(steamId: \""+bob.String()+"\") { personaName } }"), nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Player "+bob.String()) {
+		t.Errorf("GET /graphql: expected bob's name, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if status, resp := post(`{ __schema { queryType { name } } }`, nil); status != http.StatusOK || len(resp.Errors) > 0 {
+		t.Errorf("Expected a shallow introspection query to succeed, got %d: %+v", status, resp.Errors)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		query  string
+		status int
+		code   string
+	}{
+		{"syntax", `{ players { steamId }`, http.StatusBadRequest, "invalid_query"},
+		{"unknown field", `{ players { steamID } }`, http.StatusBadRequest, "invalid_query"},
+		{"too deep", `{ players { recentEvents { player { recentEvents { player { recentEvents { player { recentEvents { id } } } } } } } } }`, http.StatusBadRequest, "limit_exceeded"},
+		{"too complex", `{ playerEvents(limit: 100) { nodes { player { recentEvents(limit: 100) { id } } } } }`, http.StatusBadRequest, "limit_exceeded"},
+		{"fragment too complex", `{ playerEvents(limit: 100) { ...events } } fragment events on PlayerEventPage { nodes { player { recentEvents(limit: 100) { id } } } }`, http.StatusBadRequest, "limit_exceeded"},
+		{"introspection too deep", `{ __schema { types { fields { type { ofType { ofType { ofType { ofType { ofType { name } } } } } } } } } }`, http.StatusBadRequest, "limit_exceeded"},
+		{"introspection too complex", `{ a: __schema { types { fields { type { fields { type { name } } } } } } b: __schema { types { fields { type { fields { type { name } } } } } } c: __schema { types { fields { type { fields { type { name } } } } } } d: __schema { types { fields { type { fields { type { name } } } } } } e: __schema { types { fields { type { fields { type { name } } } } } } }`, http.StatusBadRequest, "limit_exceeded"},
+		{"invalid limit", `{ players(limit: 0) { steamId } }`, http.StatusOK, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := post(tt.query, nil)
+			if status != tt.status || len(resp.Errors) == 0 {
+				t.Fatalf("Expected %d with errors, got %d: %+v", tt.status, status, resp.Errors)
+			}
+			if code, _ := resp.Errors[0].Extensions["code"].(string); code != tt.code {
+				t.Errorf("Expected code %q, got %q: %s", tt.code, code, resp.Errors[0].Message)
+			}
+		})
+	}
+}
+
+func TestGraphQLGameName(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	handler := st.Handler()
+	named, unnamed := uniqueSteamID(), uniqueSteamID()+1
+
+	if err := st.AddPlayer(&steamtracker.Player{SteamID: named, PersonaState: steamtracker.PersonaStateOnline, GameID: "570", GameName: "Dota 2"}); err != nil {
+		t.Fatalf("Failed to add player: %v", err)
+	}
+	addPlayer(t, st, unnamed, steamtracker.PersonaStateOnline, "730")
+
+	status, resp := postGraphQL(t, handler, `query($ids: [ID!]) {
+		players(steamIds: $ids) { steamId gameId gameName recentEvents { gameName } }
+	}`, map[string]any{"ids": []string{named.String(), unnamed.String()}})
+	if status != http.StatusOK || len(resp.Errors) > 0 {
+		t.Fatalf("Expected 200 without errors, got %d: %+v", status, resp.Errors)
+	}
+
+	data := struct {
+		Players []struct {
+			SteamID      string  `json:"steamId"`
+			GameName     *string `json:"gameName"`
+			RecentEvents []struct {
+				GameName *string `json:"gameName"`
+			} `json:"recentEvents"`
+		} `json:"players"`
+	}{}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatalf("Unexpected data: %v", err)
+	}
+	if len(data.Players) != 2 {
+		t.Fatalf("Expected 2 players, got %d", len(data.Players))
+	}
+
+	for _, p := range data.Players {
+		var want *string
+		if p.SteamID == named.String() {
+			name := "Dota 2"
+			want = &name
+		}
+		if !reflect.DeepEqual(p.GameName, want) {
+			t.Errorf("Expected the game name of %s to be %v, got %v", p.SteamID, want, p.GameName)
+		}
+		for _, e := range p.RecentEvents {
+			if !reflect.DeepEqual(e.GameName, want) {
+				t.Errorf("Expected the events of %s to name the game %v, got %v", p.SteamID, want, e.GameName)
+			}
+		}
+	}
+}
+
+func TestGraphQLQueryCount(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	handler := st.Handler()
+
+	var queries atomic.Int64
+	count := func(*gorm.DB) { queries.Add(1) }
+	if err := st.DB().Callback().Query().After("gorm:query").Register("test:count_queries", count); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+	if err := st.DB().Callback().Row().After("gorm:row").Register("test:count_rows", count); err != nil {
+		t.Fatalf("Failed to register callback: %v", err)
+	}
+
+	query := `{
+		players(limit: 100) {
+			steamId
+			gameName
+			recentEvents(limit: 5) { type gameName player { steamId personaName } }
+			sessions { startedAt }
+			rollups { onlineSeconds }
+		}
+	}`
+	queryCount := func(players int) int64 {
+		t.Helper()
+
+		queries.Store(0)
+		status, resp := postGraphQL(t, handler, query, nil)
+		if status != http.StatusOK || len(resp.Errors) > 0 {
+			t.Fatalf("Expected 200 without errors, got %d: %+v", status, resp.Errors)
+		}
+		data := struct {
+			Players []json.RawMessage `json:"players"`
+		}{}
+		if err := json.Unmarshal(resp.Data, &data); err != nil {
+			t.Fatalf("Unexpected data: %v", err)
+		}
+		if len(data.Players) != players {
+			t.Fatalf("Expected %d players, got %d", players, len(data.Players))
+		}
+		return queries.Load()
+	}
+
+	steamID := uniqueSteamID()
+	add := func(n int) {
+		t.Helper()
+		for range n {
+			if err := st.AddPlayer(&steamtracker.Player{SteamID: steamID, PersonaState: steamtracker.PersonaStateOnline, GameID: "570", GameName: "Dota 2"}); err != nil {
+				t.Fatalf("Failed to add player: %v", err)
+			}
+			steamID++
+		}
+	}
+
+	add(2)
+	few := queryCount(2)
+	add(6)
+	if many := queryCount(8); many != few {
+		t.Errorf("Expected the same number of queries for 2 and 8 players, got %d and %d", few, many)
+	}
+}
+
+func TestGRPC(t *testing.T) {
+	st, err := steamtracker.Open(&steamtracker.Config{DatabaseDSN: testDSNs(t)["sqlite"], DisableTask: true, DisableAuth: true})
+	if err != nil {
+		t.Fatalf("Failed to open tracker: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := st.GRPCServer()
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	client := steamtrackerv1.NewSteamTrackerServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	steamID := uniqueSteamID()
+	if err := st.AddPlayer(&steamtracker.Player{SteamID: steamID, PersonaName: "Test Player", PersonaState: steamtracker.PersonaStateOnline}); err != nil {
+		t.Fatalf("Failed to add player: %v", err)
+	}
+
+	players, err := client.SearchPlayers(ctx, &steamtrackerv1.SearchPlayersRequest{SteamId: proto.Int64(int64(steamID))})
+	if err != nil {
+		t.Fatalf("SearchPlayers failed: %v", err)
+	}
+	if len(players.GetPlayers()) != 1 || players.GetPlayers()[0].GetPersonaState() != steamtrackerv1.PersonaState_PERSONA_STATE_ONLINE {
+		t.Errorf("Expected the online player, got %v", players.GetPlayers())
+	}
+
+	events, err := client.SearchPlayerEvents(ctx, &steamtrackerv1.SearchPlayerEventsRequest{
+		SteamIds: []int64{int64(steamID)},
+		Types:    []steamtrackerv1.PlayerEventType{steamtrackerv1.PlayerEventType_PLAYER_EVENT_TYPE_FIRST_SEEN},
+		Cursor:   proto.String(""),
+	})
+	if err != nil {
+		t.Fatalf("SearchPlayerEvents failed: %v", err)
+	}
+	if len(events.GetPlayerEvents()) != 1 || events.GetPagination().GetNextCursor() != "" {
+		t.Errorf("Expected a single first_seen event, got %v", events)
+	}
+
+	if _, err := client.SearchAuditLogs(ctx, &steamtrackerv1.SearchAuditLogsRequest{Cursor: proto.String("invalid")}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an invalid cursor, got %v", err)
+	}
+
+	stream, err := client.WatchPlayerEvents(ctx, &steamtrackerv1.WatchPlayerEventsRequest{SteamIds: []int64{int64(steamID)}, LastEventId: proto.Int64(0)})
+	if err != nil {
+		t.Fatalf("WatchPlayerEvents failed: %v", err)
+	}
+	resumed, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive the missed event: %v", err)
+	}
+	if resumed.GetPlayerEvent().GetType() != steamtrackerv1.PlayerEventType_PLAYER_EVENT_TYPE_FIRST_SEEN {
+		t.Errorf("Expected the first_seen event first, got %v", resumed.GetPlayerEvent())
+	}
+
+	if err := st.AddPlayer(&steamtracker.Player{SteamID: uniqueSteamID(), PersonaName: "Other Player", PersonaState: steamtracker.PersonaStateOnline}); err != nil {
+		t.Fatalf("Failed to add player: %v", err)
+	}
+	if err := st.AddPlayer(&steamtracker.Player{SteamID: steamID, PersonaName: "Test Player", PersonaState: steamtracker.PersonaStateAway}); err != nil {
+		t.Fatalf("Failed to add player: %v", err)
+	}
+	live, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive the new event: %v", err)
+	}
+	if live.GetPlayerEvent().GetSteamId() != int64(steamID) || live.GetPlayerEvent().GetPersonaState() != steamtrackerv1.PersonaState_PERSONA_STATE_AWAY {
+		t.Errorf("Expected the player to be away, got %v", live.GetPlayerEvent())
+	}
+}
+
+func TestAuth(t *testing.T) {
+	st, err := steamtracker.Open(&steamtracker.Config{
+		DatabaseDSN:       testDSNs(t)["sqlite"],
+		DisableTask:       true,
+		DashboardUsername: "admin",
+		DashboardPassword: "hunter2",
+	})
+	if err != nil {
+		t.Fatalf("Failed to open tracker: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	ctx := context.Background()
+	reader, readerToken, err := st.CreateAPIToken(ctx, &steamtracker.CreateAPITokenCommand{Name: "reader", Scopes: []string{steamtracker.ScopeReadPlayers}})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	_, adminToken, err := st.CreateAPIToken(ctx, &steamtracker.CreateAPITokenCommand{Name: "admin", Scopes: []string{steamtracker.ScopeAdmin}})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	revoked, revokedToken, err := st.CreateAPIToken(ctx, &steamtracker.CreateAPITokenCommand{Name: "revoked", Scopes: []string{steamtracker.ScopeAdmin}})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	if err := st.RevokeAPIToken(ctx, revoked.ID); err != nil {
+		t.Fatalf("Failed to revoke token: %v", err)
+	}
+
+	if _, _, err := st.CreateAPIToken(ctx, &steamtracker.CreateAPITokenCommand{Name: "bad", Scopes: []string{"write:players"}}); err == nil {
+		t.Error("Expected an error for an unknown scope")
+	}
+	if err := st.RevokeAPIToken(ctx, -1); !errors.Is(err, steamtracker.ErrAPITokenNotFound) {
+		t.Errorf("Expected ErrAPITokenNotFound, got %v", err)
+	}
+
+	server := httptest.NewServer(st.Handler())
+	t.Cleanup(server.Close)
+
+	basic := func(username, password string) string {
+		r := http.Request{Header: http.Header{}}
+		r.SetBasicAuth(username, password)
+		return r.Header.Get("Authorization")
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		status        int
+		code          string
+	}{
+		{"openapi is public", "/api/v1/openapi.json", "", http.StatusOK, ""},
+		{"no credentials", "/api/v1/players", "", http.StatusUnauthorized, steamtracker.APIErrorUnauthorized},
+		{"unknown token", "/api/v1/players", "Bearer st_unknown", http.StatusUnauthorized, steamtracker.APIErrorUnauthorized},
+		{"revoked token", "/api/v1/players", "Bearer " + revokedToken, http.StatusUnauthorized, steamtracker.APIErrorUnauthorized},
+		{"reader reads players", "/api/v1/players", "Bearer " + readerToken, http.StatusOK, ""},
+		{"reader cannot read audit logs", "/api/v1/audit_logs", "Bearer " + readerToken, http.StatusForbidden, steamtracker.APIErrorForbidden},
+		{"reader cannot use legacy audit logs", "/api/audit_logs", "Bearer " + readerToken, http.StatusForbidden, steamtracker.APIErrorForbidden},
+		{"reader cannot export", "/api/v1/export/players", "Bearer " + readerToken, http.StatusForbidden, steamtracker.APIErrorForbidden},
+		{"admin reads audit logs", "/api/v1/audit_logs", "Bearer " + adminToken, http.StatusOK, ""},
+		{"dashboard reads players", "/api/v1/players/current", basic("admin", "hunter2"), http.StatusOK, ""},
+		{"dashboard cannot read audit logs", "/api/v1/audit_logs", basic("admin", "hunter2"), http.StatusForbidden, steamtracker.APIErrorForbidden},
+		{"wrong dashboard password", "/api/v1/players", basic("admin", "wrong"), http.StatusUnauthorized, steamtracker.APIErrorUnauthorized},
+		{"dashboard without credentials", "/", "", http.StatusUnauthorized, ""},
+		{"dashboard rejects tokens", "/", "Bearer " + adminToken, http.StatusUnauthorized, ""},
+		{"dashboard", "/", basic("admin", "hunter2"), http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+tt.path, nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Fatalf("Expected %d, got %d", tt.status, resp.StatusCode)
+			}
+			if tt.status == http.StatusUnauthorized && resp.Header.Get("WWW-Authenticate") == "" {
+				t.Error("Expected a WWW-Authenticate challenge")
+			}
+			if tt.code != "" {
+				body := steamtracker.APIResponse{}
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if body.Error == nil || body.Error.Code != tt.code {
+					t.Errorf("Expected error code %q, got %+v", tt.code, body.Error)
+				}
+			}
+		})
+	}
+
+	tokens, err := st.ListAPITokens(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list tokens: %v", err)
+	}
+	for _, token := range tokens {
+		if token.ID == reader.ID && token.LastUsedAt == nil {
+			t.Error("Expected the reader token to record its last use")
+		}
+		if token.ID == revoked.ID && token.RevokedAt == nil {
+			t.Error("Expected the revoked token to be revoked")
+		}
+	}
+
+	t.Run("graphql audit logs", func(t *testing.T) {
+		body := strings.NewReader(`{"query": "{ auditLogs { nodes { id } } }"}`)
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/graphql", body)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+readerToken)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		result := struct {
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}{}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(result.Errors) == 0 || !strings.Contains(result.Errors[0].Message, steamtracker.ScopeReadAudit) {
+			t.Errorf("Expected a scope error, got %+v", result.Errors)
+		}
+	})
+
+	t.Run("grpc", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Failed to listen: %v", err)
+		}
+		server := st.GRPCServer()
+		go func() { _ = server.Serve(ln) }()
+		t.Cleanup(server.Stop)
+
+		conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+		client := steamtrackerv1.NewSteamTrackerServiceClient(conn)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if _, err := client.SearchPlayers(ctx, &steamtrackerv1.SearchPlayersRequest{}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("Expected Unauthenticated without a token, got %v", err)
+		}
+
+		readerCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+readerToken)
+		if _, err := client.SearchPlayers(readerCtx, &steamtrackerv1.SearchPlayersRequest{}); err != nil {
+			t.Errorf("SearchPlayers failed: %v", err)
+		}
+		if _, err := client.SearchAuditLogs(readerCtx, &steamtrackerv1.SearchAuditLogsRequest{}); status.Code(err) != codes.PermissionDenied {
+			t.Errorf("Expected PermissionDenied for audit logs, got %v", err)
+		}
+
+		stream, err := client.WatchPlayerEvents(ctx, &steamtrackerv1.WatchPlayerEventsRequest{})
+		if err != nil {
+			t.Fatalf("WatchPlayerEvents failed: %v", err)
+		}
+		if _, err := stream.Recv(); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("Expected Unauthenticated for an unauthenticated stream, got %v", err)
+		}
+	})
+}
+
+func TestDashboardWithoutCredentials(t *testing.T) {
+	st, err := steamtracker.Open(&steamtracker.Config{
+		DatabaseDSN: testDSNs(t)["sqlite"],
+		DisableTask: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open tracker: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	ctx := context.Background()
+	_, readerToken, err := st.CreateAPIToken(ctx, &steamtracker.CreateAPITokenCommand{Name: "reader", Scopes: []string{steamtracker.ScopeReadPlayers}})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	_, auditorToken, err := st.CreateAPIToken(ctx, &steamtracker.CreateAPITokenCommand{Name: "auditor", Scopes: []string{steamtracker.ScopeReadAudit}})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"token without read:players", "Bearer " + auditorToken, http.StatusForbidden},
+		{"reader", "Bearer " + readerToken, http.StatusOK},
+	}
+
+	handler := st.Handler()
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.authorization != "" {
+			r.Header.Set("Authorization", tt.authorization)
+		}
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.status, w.Code)
+		}
+		if tt.status == http.StatusUnauthorized && !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Bearer") {
+			t.Errorf("%s: expected a Bearer challenge, got %q", tt.name, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestConfigValidateDashboardAuth(t *testing.T) {
+	tests := []struct {
+		name               string
+		disableAuth        bool
+		username, password string
+		valid              bool
+	}{
+		{"credentials", false, "admin", "hunter2", true},
+		{"no credentials", false, "", "", true},
+		{"no password", false, "admin", "", false},
+		{"auth disabled", true, "", "", true},
+	}
+
+	for _, tt := range tests {
+		cfg := steamtracker.Config{
+			DatabaseDSN:           "sqlite://steamtracker.db",
+			HTTPPort:              "8080",
+			SteamAPIKey:           "key",
+			SteamID:               "76561197960287930",
+			MaxTaskRetryCount:     3,
+			TaskInterval:          60,
+			AuditLogBufferSize:    1024,
+			AuditLogBatchSize:     100,
+			AuditLogFlushInterval: 1000,
+			AuditLogDropPolicy:    steamtracker.AuditLogDropPolicyBlock,
+			DisableAuth:           tt.disableAuth,
+			DashboardUsername:     tt.username,
+			DashboardPassword:     tt.password,
+		}
+		if err := cfg.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got %v", tt.name, tt.valid, err)
+		}
+	}
+}
+
+// TestAuthRecordsPrincipal checks that the http_request and grpc_request
+// debug logs name the principal of each request.
+func TestAuthRecordsPrincipal(t *testing.T) {
+	logs, err := os.Create(filepath.Join(t.TempDir(), "logs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	origLogger, origLevel := log.Logger, zerolog.GlobalLevel()
+	log.Logger = zerolog.New(logs)
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	t.Cleanup(func() {
+		log.Logger = origLogger
+		zerolog.SetGlobalLevel(origLevel)
+	})
+
+	st, err := steamtracker.Open(&steamtracker.Config{
+		DatabaseDSN: testDSNs(t)["sqlite"],
+		DisableTask: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open tracker: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	reader, readerToken, err := st.CreateAPIToken(ctx, &steamtracker.CreateAPITokenCommand{Name: "reader", Scopes: []string{steamtracker.ScopeReadPlayers}})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	principal := fmt.Sprintf("token %d (reader)", reader.ID)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/players", nil)
+	r.Header.Set("Authorization", "Bearer "+readerToken)
+	st.Handler().ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := st.GRPCServer()
+	go func() { _ = server.Serve(ln) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	readerCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+readerToken)
+	if _, err := steamtrackerv1.NewSteamTrackerServiceClient(conn).SearchPlayers(readerCtx, &steamtrackerv1.SearchPlayersRequest{}); err != nil {
+		t.Fatalf("SearchPlayers failed: %v", err)
+	}
+
+	data, err := os.ReadFile(logs.Name())
+	if err != nil {
+		t.Fatalf("Failed to read logs: %v", err)
+	}
+	principals := make(map[string][]string)
+	for line := range strings.Lines(string(data)) {
+		entry := struct {
+			Level     string `json:"level"`
+			Action    string `json:"action"`
+			Principal string `json:"principal"`
+		}{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Failed to decode log line %q: %v", line, err)
+		}
+		if entry.Level == zerolog.LevelDebugValue {
+			principals[entry.Action] = append(principals[entry.Action], entry.Principal)
+		}
+	}
+
+	for _, action := range []string{"http_request", "grpc_request"} {
+		if !slices.Contains(principals[action], principal) {
+			t.Errorf("Expected the %s debug logs to record %q, got %q", action, principal, principals[action])
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package steamtracker
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Audit logs form a hash chain in ID order: each row stores the hash of the
+// previous row and its own hash over that, its ID and its raw payload. Editing,
+// inserting or deleting a row breaks the chain from that row on, which
+// VerifyAuditChain detects. Rewriting the whole chain is only detectable
+// against a head that was pinned somewhere else, see GetAuditChainHead.
+//
+// Rows are chained as they are inserted, under st.auditChainMu, so only one
+// tracker process should write to a database at a time.
+
+// auditLogHash returns the chain hash of an audit log. Whitespace around raw
+// is ignored because it does not survive a round trip through the JSON type.
+func auditLogHash(prevHash string, id int64, raw []byte) string {
+	h := sha256.New()
+	h.Write([]byte(prevHash))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(strconv.FormatInt(id, 10)))
+	h.Write([]byte{'\n'})
+	h.Write(bytes.TrimSpace(raw))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// chainAuditLogs links auditLogs, sorted by ID and newer than every stored
+// audit log, onto the current head. The caller must hold st.auditChainMu
+// until tx commits.
+func chainAuditLogs(tx *gorm.DB, auditLogs []*AuditLog) error {
+	head, err := auditChainHead(tx)
+	if err != nil {
+		return fmt.Errorf("failed to read audit chain head: %w", err)
+	}
+
+	prevHash := ""
+	if head != nil {
+		prevHash = head.Hash
+	}
+
+	for _, auditLog := range auditLogs {
+		auditLog.PrevHash = prevHash
+		auditLog.Hash = auditLogHash(prevHash, auditLog.ID, auditLog.Raw)
+		prevHash = auditLog.Hash
+	}
+
+	return nil
+}
+
+type AuditChainHead struct {
+	ID        int64     `json:"id"`
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// auditChainHead returns the newest audit log, or nil if there is none.
+func auditChainHead(tx *gorm.DB) (*AuditChainHead, error) {
+	var head AuditChainHead
+	err := tx.Model(&AuditLog{}).Select("id", "hash", "created_at").Order("id DESC").Take(&head).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &head, nil
+}
+
+func (st *SteamTracker) AuditChainHead(ctx context.Context) (*AuditChainHead, error) {
+	return auditChainHead(st.db.WithContext(ctx))
+}
+
+type AuditChainBreak struct {
+	ID     int64  `json:"id"`
+	Reason string `json:"reason"`
+}
+
+type AuditChainReport struct {
+	Checked int64 `json:"checked"`
+	// FirstID and Anchor describe the oldest stored row. Anchor is its
+	// prev_hash, which is empty when the chain starts at its first row and
+	// set when older rows were removed, for example by retention.
+	FirstID int64           `json:"first_id"`
+	Anchor  string          `json:"anchor"`
+	Head    *AuditChainHead `json:"head"`
+	// PinnedFound reports whether the pinned hash passed to VerifyAuditChain
+	// is part of the verified chain.
+	PinnedFound bool             `json:"pinned_found"`
+	Break       *AuditChainBreak `json:"break,omitempty"`
+}
+
+// VerifyAuditChain walks the audit logs in ID order and stops at the first
+// row whose links or hash do not match. If pinned is set, it also checks
+// that a row with that hash is part of the chain.
+func (st *SteamTracker) VerifyAuditChain(ctx context.Context, pinned string) (*AuditChainReport, error) {
+	event := log.Info().Str("action", "verify_audit_chain")
+	defer func() { event.Send() }()
+
+	report := AuditChainReport{}
+	var prev *AuditLog
+
+	var lastID int64
+	first := true
+	for report.Break == nil {
+		auditLogs := make([]*AuditLog, 0, exportBatchSize)
+		ss := st.db.WithContext(ctx).Model(&AuditLog{}).Select("id", "raw", "prev_hash", "hash", "created_at")
+		if !first {
+			ss = ss.Where("id > ?", lastID)
+		}
+		if err := ss.Order("id").Limit(exportBatchSize).Find(&auditLogs).Error; err != nil {
+			err = fmt.Errorf("failed to read audit logs: %w", err)
+			event.Err(err)
+			return nil, err
+		}
+		if len(auditLogs) == 0 {
+			break
+		}
+
+		for _, auditLog := range auditLogs {
+			if prev == nil {
+				report.FirstID = auditLog.ID
+				report.Anchor = auditLog.PrevHash
+			}
+
+			switch {
+			case prev != nil && auditLog.PrevHash != prev.Hash:
+				report.Break = &AuditChainBreak{ID: auditLog.ID, Reason: "prev_hash does not match the previous row, a row was inserted or deleted before it"}
+			case auditLog.Hash != auditLogHash(auditLog.PrevHash, auditLog.ID, auditLog.Raw):
+				report.Break = &AuditChainBreak{ID: auditLog.ID, Reason: "hash does not match the row, it was modified"}
+			}
+			if report.Break != nil {
+				break
+			}
+
+			report.Checked++
+			if pinned != "" && auditLog.Hash == pinned {
+				report.PinnedFound = true
+			}
+			report.Head = &AuditChainHead{ID: auditLog.ID, Hash: auditLog.Hash, CreatedAt: auditLog.CreatedAt}
+			prev = auditLog
+		}
+
+		first = false
+		lastID = auditLogs[len(auditLogs)-1].ID
+	}
+
+	event.Int64("checked", report.Checked)
+	if report.Break != nil {
+		event.Int64("break_id", report.Break.ID).Str("reason", report.Break.Reason)
+	}
+
+	return &report, nil
+}
+
+// GetAuditChainHead returns the newest audit log's hash so it can be pinned
+// outside the database and later passed to "audit verify --pin".
+func (st *SteamTracker) GetAuditChainHead(w http.ResponseWriter, r *http.Request) {
+	head, err := st.AuditChainHead(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get audit chain head: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if head == nil {
+		http.Error(w, "No audit logs", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(head); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
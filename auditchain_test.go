@@ -0,0 +1,193 @@
+package steamtracker_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+// openAuditChain opens a tracker with n chained audit logs, plus a second
+// connection to its database for tampering with the rows behind its back.
+func openAuditChain(t *testing.T, n int) (*steamtracker.SteamTracker, *gorm.DB, []*steamtracker.AuditLog) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "steamtracker.db")
+	st := openTestTracker(t, "sqlite://"+path)
+
+	auditLogs := make([]*steamtracker.AuditLog, 0, n)
+	for i := range n {
+		auditLogs = append(auditLogs, createAuditLog(t, st, fmt.Sprintf(`{"level":"info","message":"line %d"}`, i)))
+		time.Sleep(2 * time.Millisecond) // leaves room between IDs for an inserted row
+	}
+
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	return st, db, auditLogs
+}
+
+func verifyAuditChain(t *testing.T, st *steamtracker.SteamTracker, pinned string) *steamtracker.AuditChainReport {
+	t.Helper()
+
+	report, err := st.VerifyAuditChain(t.Context(), pinned)
+	if err != nil {
+		t.Fatalf("Failed to verify audit chain: %v", err)
+	}
+	return report
+}
+
+func TestVerifyAuditChain(t *testing.T) {
+	t.Run("intact", func(t *testing.T) {
+		st, _, auditLogs := openAuditChain(t, 3)
+
+		report := verifyAuditChain(t, st, auditLogs[1].Hash)
+		if report.Break != nil || report.Checked != 3 || report.Anchor != "" || report.FirstID != auditLogs[0].ID {
+			t.Errorf("Expected an intact chain of 3 rows, got %+v", report)
+		}
+		if report.Head == nil || report.Head.Hash != auditLogs[2].Hash || !report.PinnedFound {
+			t.Errorf("Expected the head and the pinned hash to be found, got %+v", report)
+		}
+		if report := verifyAuditChain(t, st, "0000"); report.PinnedFound {
+			t.Error("Expected an unknown pinned hash not to be found")
+		}
+	})
+
+	tests := []struct {
+		name    string
+		tamper  func(db *gorm.DB, auditLogs []*steamtracker.AuditLog) error
+		breakAt func(auditLogs []*steamtracker.AuditLog) int64
+		checked int64 // rows verified before the break
+	}{
+		{
+			name: "edited",
+			tamper: func(db *gorm.DB, auditLogs []*steamtracker.AuditLog) error {
+				return db.Exec("UPDATE audit_logs SET raw = ? WHERE id = ?", `{"level":"info","message":"edited"}`, auditLogs[1].ID).Error
+			},
+			breakAt: func(auditLogs []*steamtracker.AuditLog) int64 { return auditLogs[1].ID },
+			checked: 1,
+		},
+		{
+			name: "inserted",
+			tamper: func(db *gorm.DB, auditLogs []*steamtracker.AuditLog) error {
+				// Even a correctly hashed row cannot be spliced in without
+				// breaking the link of the row after it.
+				id := auditLogs[1].ID - 1
+				raw := `{"level":"info","message":"inserted"}`
+				return db.Exec("INSERT INTO audit_logs (id, raw, prev_hash, hash, created_at) VALUES (?, ?, ?, ?, ?)",
+					id, raw, auditLogs[0].Hash, chainHash(auditLogs[0].Hash, id, raw), time.Now()).Error
+			},
+			breakAt: func(auditLogs []*steamtracker.AuditLog) int64 { return auditLogs[1].ID },
+			checked: 2,
+		},
+		{
+			name: "deleted",
+			tamper: func(db *gorm.DB, auditLogs []*steamtracker.AuditLog) error {
+				return db.Exec("DELETE FROM audit_logs WHERE id = ?", auditLogs[1].ID).Error
+			},
+			breakAt: func(auditLogs []*steamtracker.AuditLog) int64 { return auditLogs[2].ID },
+			checked: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, db, auditLogs := openAuditChain(t, 3)
+			if err := tt.tamper(db, auditLogs); err != nil {
+				t.Fatalf("Failed to tamper with the audit logs: %v", err)
+			}
+
+			report := verifyAuditChain(t, st, "")
+			if report.Break == nil || report.Break.ID != tt.breakAt(auditLogs) {
+				t.Fatalf("Expected the chain to break at %d, got %+v", tt.breakAt(auditLogs), report)
+			}
+			if report.Checked != tt.checked {
+				t.Errorf("Expected %d rows to verify before the break, got %d", tt.checked, report.Checked)
+			}
+		})
+	}
+}
+
+// chainHash computes a row's chain hash the way the tracker does, as anyone
+// with write access to the database could, to forge a well-formed row.
+func chainHash(prevHash string, id int64, raw string) string {
+	sum := sha256.Sum256([]byte(prevHash + "\n" + strconv.FormatInt(id, 10) + "\n" + raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestAuditChainHeadEndpoint(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	handler := st.Handler()
+
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/audit_logs/head", nil))
+		return w
+	}
+
+	if w := get(); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without audit logs, got %d: %s", w.Code, w.Body)
+	}
+
+	createAuditLog(t, st, `{"level":"info","message":"first"}`)
+	last := createAuditLog(t, st, `{"level":"info","message":"second"}`)
+
+	w := get()
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var body struct {
+		Data steamtracker.AuditChainHead `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if body.Data.ID != last.ID || body.Data.Hash != last.Hash {
+		t.Errorf("Expected the head to be %d %s, got %+v", last.ID, last.Hash, body.Data)
+	}
+}
+
+func TestImportRechainsAuditLogs(t *testing.T) {
+	src, _, _ := openAuditChain(t, 3)
+	dst, _, dstLogs := openAuditChain(t, 1)
+	pinned := dstLogs[0].Hash
+
+	var buf bytes.Buffer
+	if _, err := src.Export(t.Context(), &buf, &steamtracker.ExportQuery{Table: "audit_logs", Format: steamtracker.ExportFormatJSONL}); err != nil {
+		t.Fatalf("Failed to export audit logs: %v", err)
+	}
+	result, err := dst.Import(t.Context(), &buf, &steamtracker.ImportCommand{Table: "audit_logs", Format: steamtracker.ExportFormatJSONL})
+	if err != nil {
+		t.Fatalf("Failed to import audit logs: %v", err)
+	}
+	if result.Inserted != 3 || result.Remapped != 3 {
+		t.Errorf("Expected 3 audit logs to be inserted under new IDs, got %+v", result)
+	}
+
+	report := verifyAuditChain(t, dst, pinned)
+	if report.Break != nil || report.Checked != 4 || !report.PinnedFound {
+		t.Errorf("Expected the imported audit logs to extend the chain, got %+v", report)
+	}
+	if head := verifyAuditChain(t, src, "").Head; report.Head.Hash == head.Hash {
+		t.Error("Expected the imported audit logs to be hashed into the receiving chain")
+	}
+}
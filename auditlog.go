@@ -1,14 +1,35 @@
 package steamtracker
 
 import (
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/rs/zerolog"
 )
 
 type AuditLog struct {
-	ID        int64     `json:"id" gorm:"primaryKey"`
-	Raw       JSON      `json:"raw" gorm:"type:text"`
-	CreatedAt time.Time `json:"created_at"`
+	ID  int64 `json:"id" gorm:"primaryKey"`
+	Raw JSON  `json:"raw" gorm:"type:text"`
+
+	// The columns below are extracted from Raw so audit logs can be
+	// filtered without parsing every payload.
+	Level    string     `json:"level" gorm:"size:16;index"`
+	Message  string     `json:"message" gorm:"type:text"`
+	Action   string     `json:"action" gorm:"size:64;index"`
+	SteamID  *SteamID   `json:"steam_id" gorm:"index"`
+	Error    string     `json:"error" gorm:"type:text"`
+	LoggedAt *time.Time `json:"logged_at" gorm:"index"`
+
+	PrevHash string `json:"prev_hash" gorm:"size:64"`
+	Hash     string `json:"hash" gorm:"size:64"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+
+	// Snippet is the highlighted match of a full-text search.
+	Snippet string `json:"snippet,omitempty" gorm:"->;-:migration"`
 }
 
 func NewAuditLogFromString(raw string) *AuditLog {
@@ -28,6 +49,14 @@ func (al *AuditLog) MarshalJSON() ([]byte, error) {
 	buf = append(buf, `,"audit_created_at":"`...)
 	buf = append(buf, al.CreatedAt.Format(time.RFC3339)...)
 	buf = append(buf, '"')
+	if al.Snippet != "" {
+		snippet, err := json.Marshal(al.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, `,"audit_snippet":`...)
+		buf = append(buf, snippet...)
+	}
 	if al.Raw != nil {
 		buf = append(buf, ',')
 		buf = append(buf, al.Raw[:len(al.Raw)-1][1:]...)
@@ -38,20 +67,109 @@ func (al *AuditLog) MarshalJSON() ([]byte, error) {
 
 type CreateAuditLogCommand struct {
 	Raw JSON `json:"raw"`
+	// CreatedAt is when the line was logged. It defaults to the time the
+	// audit log is stored.
+	CreatedAt time.Time `json:"created_at"`
 }
 
 func (cmd *CreateAuditLogCommand) AuditLog() AuditLog {
-	return AuditLog{
-		Raw: cmd.Raw,
+	auditLog := AuditLog{
+		Raw:       cmd.Raw,
+		CreatedAt: cmd.CreatedAt,
+	}
+	if auditLog.CreatedAt.IsZero() {
+		auditLog.CreatedAt = time.Now()
+	}
+	auditLog.setFields(parseAuditLogFields(cmd.Raw))
+
+	return auditLog
+}
+
+// auditLogFields are the zerolog fields copied into their own columns.
+type auditLogFields struct {
+	Level    string
+	Message  string
+	Action   string
+	SteamID  *SteamID
+	Error    string
+	LoggedAt *time.Time
+}
+
+func (al *AuditLog) setFields(fields auditLogFields) {
+	al.Level = fields.Level
+	al.Message = fields.Message
+	al.Action = fields.Action
+	al.SteamID = fields.SteamID
+	al.Error = fields.Error
+	al.LoggedAt = fields.LoggedAt
+}
+
+// parseAuditLogFields reads the well-known zerolog fields from raw. Payloads
+// that are not JSON objects, or fields of an unexpected type, are ignored.
+func parseAuditLogFields(raw []byte) auditLogFields {
+	var payload struct {
+		Level   string          `json:"level"`
+		Message string          `json:"message"`
+		Action  string          `json:"action"`
+		SteamID json.RawMessage `json:"steam_id"`
+		Error   json.RawMessage `json:"error"`
+		Time    string          `json:"time"`
+	}
+
+	fields := auditLogFields{}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return fields
+	}
+
+	fields.Level = payload.Level
+	fields.Message = payload.Message
+	fields.Action = payload.Action
+
+	// steam_id is logged both as a number and as a string.
+	if v := strings.Trim(string(payload.SteamID), `"`); v != "" {
+		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+			steamID := SteamID(id)
+			fields.SteamID = &steamID
+		}
+	}
+
+	if len(payload.Error) > 0 && string(payload.Error) != "null" {
+		var message string
+		if err := json.Unmarshal(payload.Error, &message); err == nil {
+			fields.Error = message
+		} else {
+			fields.Error = string(payload.Error)
+		}
 	}
+
+	if payload.Time != "" {
+		if t, err := time.Parse(time.RFC3339Nano, payload.Time); err == nil {
+			fields.LoggedAt = &t
+		}
+	}
+
+	return fields
 }
 
 type SearchAuditLogsQuery struct {
 	Page  int `query:"page"`
 	Limit int `query:"limit"`
+	// Cursor switches to keyset pagination on id and takes precedence over
+	// Page. An empty cursor starts at the first row.
+	Cursor       *string `json:"cursor" query:"cursor,allowempty"`
+	IncludeTotal *bool   `json:"include_total"`
+
+	Q             *string    `json:"q"`
+	Level         *string    `json:"level"`
+	Action        *string    `json:"action"`
+	SteamID       *SteamID   `json:"steam_id"`
+	StartLoggedAt *time.Time `json:"start_logged_at"`
+	EndLoggedAt   *time.Time `json:"end_logged_at"`
+	HasError      *bool      `json:"has_error"`
 
 	SortBy struct {
-		ID *string `json:"id"`
+		ID       *string `json:"id"`
+		LoggedAt *string `json:"logged_at"`
 	} `json:"sort_by"`
 }
 
@@ -63,19 +181,48 @@ func (query *SearchAuditLogsQuery) Validate() error {
 		query.Limit = 25
 	}
 
-	if query.SortBy.ID != nil {
-		if *query.SortBy.ID != "asc" && *query.SortBy.ID != "desc" {
-			return fmt.Errorf("invalid sort order for id: %s, must be 'asc' or 'desc'", *query.SortBy.ID)
+	bindErr := &BindError{}
+
+	if query.Cursor != nil {
+		if _, err := DecodeCursor(*query.Cursor); err != nil {
+			bindErr.add("cursor", "must be a next_cursor returned by a previous page")
+		}
+		if query.SortBy.LoggedAt != nil {
+			bindErr.add("sort_by[logged_at]", "cannot be used with cursor")
+		}
+	}
+
+	if query.Q != nil && strings.TrimSpace(*query.Q) == "" {
+		query.Q = nil
+	}
+
+	if query.Level != nil {
+		*query.Level = strings.ToLower(*query.Level)
+		if _, err := zerolog.ParseLevel(*query.Level); err != nil || *query.Level == "" {
+			bindErr.add("level", fmt.Sprintf("must be a log level such as info or error, got %s", *query.Level))
 		}
 	}
 
-	return nil
+	if query.SteamID != nil && *query.SteamID < 0 {
+		bindErr.add("steam_id", "must not be negative")
+	}
+
+	if query.StartLoggedAt != nil && query.EndLoggedAt != nil && query.StartLoggedAt.After(*query.EndLoggedAt) {
+		bindErr.add("start_logged_at", "must not be after end_logged_at")
+	}
+
+	validateSortOrder(bindErr, "id", query.SortBy.ID)
+	validateSortOrder(bindErr, "logged_at", query.SortBy.LoggedAt)
+
+	return bindErr.err()
 }
 
 type SearchAuditLogsQueryResult struct {
-	TotalCount int64 `json:"total_count"`
-	Page       int   `json:"page"`
-	PerPage    int   `json:"per_page"`
+	// TotalCount is only set when the query asked for it, see IncludeTotal.
+	TotalCount *int64 `json:"total_count,omitempty"`
+	Page       int    `json:"page,omitempty"`
+	PerPage    int    `json:"per_page"`
+	NextCursor string `json:"next_cursor,omitempty"`
 
 	AuditLogs []*AuditLog `json:"audit_logs"`
 }
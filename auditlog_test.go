@@ -0,0 +1,127 @@
+package steamtracker_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+func createAuditLog(t *testing.T, st *steamtracker.SteamTracker, raw string) *steamtracker.AuditLog {
+	t.Helper()
+
+	auditLog, err := st.CreateAuditLog(&steamtracker.CreateAuditLogCommand{Raw: steamtracker.JSON(raw)})
+	if err != nil {
+		t.Fatalf("Failed to create audit log: %v", err)
+	}
+	return auditLog
+}
+
+func TestAuditLogFields(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	loggedAt := time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)
+	steamID := steamtracker.SteamID(76561197960287930)
+
+	tests := []struct {
+		name string
+		raw  string
+		want steamtracker.AuditLog
+	}{
+		{
+			name: "all fields",
+			raw:  `{"level":"error","message":"Failed","action":"add_player","steam_id":"76561197960287930","error":"boom","time":"2024-01-02T03:04:05.0000006Z"}`,
+			want: steamtracker.AuditLog{Level: "error", Message: "Failed", Action: "add_player", SteamID: &steamID, Error: "boom", LoggedAt: &loggedAt},
+		},
+		{
+			name: "numeric steam_id",
+			raw:  `{"level":"info","steam_id":76561197960287930}`,
+			want: steamtracker.AuditLog{Level: "info", SteamID: &steamID},
+		},
+		{
+			name: "structured error",
+			raw:  `{"level":"warn","error":{"code":1}}`,
+			want: steamtracker.AuditLog{Level: "warn", Error: `{"code":1}`},
+		},
+		{
+			name: "null error and bad values",
+			raw:  `{"error":null,"steam_id":"abc","time":"yesterday"}`,
+			want: steamtracker.AuditLog{},
+		},
+		{
+			name: "not an object",
+			raw:  `["level","error"]`,
+			want: steamtracker.AuditLog{},
+		},
+	}
+
+	for _, tt := range tests {
+		got := createAuditLog(t, st, tt.raw)
+		if got.Level != tt.want.Level || got.Message != tt.want.Message || got.Action != tt.want.Action || got.Error != tt.want.Error {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+		if (got.SteamID == nil) != (tt.want.SteamID == nil) || (got.SteamID != nil && *got.SteamID != *tt.want.SteamID) {
+			t.Errorf("%s: expected steam_id %v, got %v", tt.name, tt.want.SteamID, got.SteamID)
+		}
+		if (got.LoggedAt == nil) != (tt.want.LoggedAt == nil) || (got.LoggedAt != nil && !got.LoggedAt.Equal(*tt.want.LoggedAt)) {
+			t.Errorf("%s: expected logged_at %v, got %v", tt.name, tt.want.LoggedAt, got.LoggedAt)
+		}
+	}
+}
+
+func searchAuditLogMessages(t *testing.T, st *steamtracker.SteamTracker, query steamtracker.SearchAuditLogsQuery) []string {
+	t.Helper()
+
+	query.Limit = 100
+	if err := query.Validate(); err != nil {
+		t.Fatalf("Invalid query: %v", err)
+	}
+	result, err := st.SearchAuditLogs(&query)
+	if err != nil {
+		t.Fatalf("Failed to search audit logs: %v", err)
+	}
+
+	messages := make([]string, 0, len(result.AuditLogs))
+	for _, auditLog := range result.AuditLogs {
+		messages = append(messages, auditLog.Message)
+	}
+	slices.Sort(messages)
+	return messages
+}
+
+func TestSearchAuditLogsFilters(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	steamID := steamtracker.SteamID(76561197960287930)
+
+	createAuditLog(t, st, `{"level":"info","message":"a","action":"add_player","steam_id":"76561197960287930","time":"2024-01-01T00:00:00Z"}`)
+	createAuditLog(t, st, `{"level":"error","message":"b","action":"add_player","error":"boom","time":"2024-01-02T00:00:00Z"}`)
+	createAuditLog(t, st, `{"level":"info","message":"c","action":"task","time":"2024-01-03T00:00:00Z"}`)
+
+	day := func(d int) *time.Time {
+		v := time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC)
+		return &v
+	}
+	yes, no := true, false
+	level, action := "ERROR", "add_player"
+
+	tests := []struct {
+		name  string
+		query steamtracker.SearchAuditLogsQuery
+		want  []string
+	}{
+		{"level", steamtracker.SearchAuditLogsQuery{Level: &level}, []string{"b"}},
+		{"action", steamtracker.SearchAuditLogsQuery{Action: &action}, []string{"a", "b"}},
+		{"steam_id", steamtracker.SearchAuditLogsQuery{SteamID: &steamID}, []string{"a"}},
+		{"start_logged_at", steamtracker.SearchAuditLogsQuery{StartLoggedAt: day(2)}, []string{"b", "c"}},
+		{"end_logged_at", steamtracker.SearchAuditLogsQuery{EndLoggedAt: day(2)}, []string{"a", "b"}},
+		{"single instant", steamtracker.SearchAuditLogsQuery{StartLoggedAt: day(2), EndLoggedAt: day(2)}, []string{"b"}},
+		{"has_error", steamtracker.SearchAuditLogsQuery{HasError: &yes}, []string{"b"}},
+		{"no error", steamtracker.SearchAuditLogsQuery{HasError: &no}, []string{"a", "c"}},
+	}
+
+	for _, tt := range tests {
+		if got := searchAuditLogMessages(t, st, tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package steamtracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// ErrAuditLogSearchUnsupported is returned when the database has no FTS5
+// support. FTS5 is only available on SQLite, and the SQLite driver only
+// includes it when built with the sqlite_fts5 tag.
+var ErrAuditLogSearchUnsupported = errors.New("full-text search requires SQLite built with the sqlite_fts5 tag")
+
+const auditLogsFTSTable = "audit_logs_fts"
+
+// auditLogSearchStatements create the FTS5 index over audit_logs.raw and the
+// triggers that keep it in sync. The index stores no copy of the text; it
+// reads raw back from audit_logs when building snippets.
+var auditLogSearchStatements = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS audit_logs_fts USING fts5(raw, content='audit_logs', content_rowid='id')`,
+	`CREATE TRIGGER IF NOT EXISTS audit_logs_fts_insert AFTER INSERT ON audit_logs BEGIN
+		INSERT INTO audit_logs_fts(rowid, raw) VALUES (new.id, new.raw);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS audit_logs_fts_delete AFTER DELETE ON audit_logs BEGIN
+		INSERT INTO audit_logs_fts(audit_logs_fts, rowid, raw) VALUES ('delete', old.id, old.raw);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS audit_logs_fts_update AFTER UPDATE OF raw ON audit_logs BEGIN
+		INSERT INTO audit_logs_fts(audit_logs_fts, rowid, raw) VALUES ('delete', old.id, old.raw);
+		INSERT INTO audit_logs_fts(rowid, raw) VALUES (new.id, new.raw);
+	END`,
+}
+
+// fts5Available reports whether db is SQLite with FTS5 compiled in.
+func fts5Available(db *gorm.DB) bool {
+	if db.Dialector.Name() != "sqlite" {
+		return false
+	}
+
+	var used int
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&used).Error; err != nil {
+		return false
+	}
+
+	return used == 1
+}
+
+// createAuditLogSearch creates the index and its triggers when FTS5 is
+// available and reports whether it did. Existing rows are not indexed; see
+// ReindexAuditLogs.
+func createAuditLogSearch(tx *gorm.DB) (bool, error) {
+	if !fts5Available(tx) {
+		return false, nil
+	}
+
+	for _, stmt := range auditLogSearchStatements {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func dropAuditLogSearchTriggers(tx *gorm.DB) error {
+	for _, name := range []string{"audit_logs_fts_insert", "audit_logs_fts_delete", "audit_logs_fts_update"} {
+		if err := tx.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dropAuditLogSearch removes the index. Without FTS5 the virtual table
+// cannot be dropped, so only its triggers are.
+func dropAuditLogSearch(tx *gorm.DB) error {
+	if err := dropAuditLogSearchTriggers(tx); err != nil {
+		return err
+	}
+	if !fts5Available(tx) {
+		return nil
+	}
+
+	return tx.Exec("DROP TABLE IF EXISTS audit_logs_fts").Error
+}
+
+// syncAuditLogSearch reconciles an existing index with the running binary.
+// The sync triggers make every write to audit_logs fail on a binary without
+// FTS5, so such a binary drops them and leaves the index stale; a binary with
+// FTS5 that finds the triggers missing recreates them and rebuilds the index.
+func syncAuditLogSearch(tx *gorm.DB) error {
+	if tx.Dialector.Name() != "sqlite" || !tx.Migrator().HasTable(auditLogsFTSTable) {
+		return nil
+	}
+
+	var triggers int64
+	if err := tx.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = 'audit_logs_fts_insert'").Scan(&triggers).Error; err != nil {
+		return err
+	}
+
+	if !fts5Available(tx) {
+		if triggers == 0 {
+			return nil
+		}
+		log.Warn().Msg("SQLite was built without FTS5, disabling the audit log search index until a build with it starts")
+		return dropAuditLogSearchTriggers(tx)
+	}
+
+	if triggers > 0 {
+		return nil
+	}
+
+	log.Info().Msg("Rebuilding stale audit log search index")
+	if _, err := createAuditLogSearch(tx); err != nil {
+		return err
+	}
+	return tx.Exec("INSERT INTO audit_logs_fts(audit_logs_fts) VALUES ('rebuild')").Error
+}
+
+// hasAuditLogSearch reports whether the full-text index exists and can be
+// queried by this binary.
+func hasAuditLogSearch(tx *gorm.DB) bool {
+	return fts5Available(tx) && tx.Migrator().HasTable(auditLogsFTSTable)
+}
+
+// ReindexAuditLogs creates the full-text index if it is missing, for example
+// when the database was migrated by a binary built without FTS5, and
+// rebuilds it from every row in audit_logs. It returns the number of rows
+// indexed.
+func (st *SteamTracker) ReindexAuditLogs(ctx context.Context) (int64, error) {
+	event := log.Info().Str("action", "reindex_audit_logs")
+	defer func() { event.Send() }()
+
+	var count int64
+	err := st.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ok, err := createAuditLogSearch(tx)
+		if err != nil {
+			return fmt.Errorf("failed to create audit log search index: %w", err)
+		}
+		if !ok {
+			return ErrAuditLogSearchUnsupported
+		}
+
+		if err := tx.Exec("INSERT INTO audit_logs_fts(audit_logs_fts) VALUES ('rebuild')").Error; err != nil {
+			return fmt.Errorf("failed to rebuild audit log search index: %w", err)
+		}
+
+		if err := tx.Model(&AuditLog{}).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to count audit logs: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		event.Err(err)
+		return 0, err
+	}
+
+	event.Int64("rows", count)
+	return count, nil
+}
+
+// SQLite marks matches in snippets with these private use characters, which
+// highlightSnippet turns into <mark> tags once the rest of the text has been
+// HTML-escaped. Logged text, such as persona names, is never trusted markup.
+const (
+	snippetMatchStart = "\ue000"
+	snippetMatchEnd   = "\ue001"
+)
+
+// auditLogSnippetColumn selects the highlighted match of a full-text search.
+var auditLogSnippetColumn = fmt.Sprintf("snippet(audit_logs_fts, 0, '%s', '%s', '…', 16) AS snippet", snippetMatchStart, snippetMatchEnd)
+
+// highlightSnippet returns a snippet as HTML with the matches in <mark> tags.
+func highlightSnippet(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	return strings.NewReplacer(snippetMatchStart, "<mark>", snippetMatchEnd, "</mark>").Replace(snippet)
+}
+
+// ftsPhrase turns free text into an FTS5 prefix phrase query, so input such
+// as `no such ho` matches "no such host" and quotes or operators in the
+// input are taken literally.
+func ftsPhrase(q string) string {
+	return `"` + strings.ReplaceAll(q, `"`, `""`) + `"*`
+}
+
+// likePattern escapes q for use in a LIKE ... ESCAPE '!' substring match.
+// A backslash escape would be read differently by MySQL and the others.
+func likePattern(q string) string {
+	r := strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+	return "%" + r.Replace(q) + "%"
+}
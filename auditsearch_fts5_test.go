@@ -0,0 +1,20 @@
+//go:build sqlite_fts5
+
+package steamtracker_test
+
+import "testing"
+
+// With the tag, search must go through the index rather than silently fall
+// back to LIKE.
+func TestAuditLogSearchUsesFTS5(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	if !auditLogSearch(t, st) {
+		t.Fatal("Expected FTS5 to be available in a build with the sqlite_fts5 tag")
+	}
+
+	createAuditLog(t, st, `{"level":"info","message":"Indexed"}`)
+	auditLogs := searchAuditLogs(t, st, "indexed")
+	if len(auditLogs) != 1 || auditLogs[0].Snippet == "" {
+		t.Errorf("Expected a match with a snippet, got %v", auditLogs)
+	}
+}
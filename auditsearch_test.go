@@ -0,0 +1,89 @@
+package steamtracker_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+// auditLogSearch reports whether st searches audit logs through the FTS5
+// index, which needs the sqlite_fts5 build tag, rather than with LIKE.
+func auditLogSearch(t *testing.T, st *steamtracker.SteamTracker) bool {
+	t.Helper()
+
+	_, err := st.ReindexAuditLogs(t.Context())
+	if errors.Is(err, steamtracker.ErrAuditLogSearchUnsupported) {
+		return false
+	} else if err != nil {
+		t.Fatalf("Failed to reindex audit logs: %v", err)
+	}
+	return true
+}
+
+func searchAuditLogs(t *testing.T, st *steamtracker.SteamTracker, q string) []*steamtracker.AuditLog {
+	t.Helper()
+
+	query := steamtracker.SearchAuditLogsQuery{Q: &q, Limit: 100}
+	if err := query.Validate(); err != nil {
+		t.Fatalf("Invalid query: %v", err)
+	}
+	result, err := st.SearchAuditLogs(&query)
+	if err != nil {
+		t.Fatalf("Failed to search audit logs for %q: %v", q, err)
+	}
+	return result.AuditLogs
+}
+
+func TestSearchAuditLogsQuery(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	fullText := auditLogSearch(t, st)
+
+	createAuditLog(t, st, `{"level":"info","message":"Player renamed","persona_name":"<img src=x onerror=alert(1)>"}`)
+	createAuditLog(t, st, `{"level":"error","message":"Failed to poll","error":"dial tcp: lookup api.steampowered.com: no such host"}`)
+	createAuditLog(t, st, `{"level":"info","message":"Progress at 50% done"}`)
+
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"no such ho", []string{"Failed to poll"}},
+		{"onerror", []string{"Player renamed"}},
+		{`"level"`, []string{"Failed to poll", "Player renamed", "Progress at 50% done"}},
+		{"missing", []string{}},
+	}
+	if !fullText {
+		// LIKE wildcards in the query are matched literally.
+		tests = append(tests, struct {
+			q    string
+			want []string
+		}{"0%", []string{"Progress at 50% done"}})
+	}
+
+	for _, tt := range tests {
+		auditLogs := searchAuditLogs(t, st, tt.q)
+		got := make([]string, 0, len(auditLogs))
+		for _, auditLog := range auditLogs {
+			got = append(got, auditLog.Message)
+		}
+		if !slices.Equal(slices.Sorted(slices.Values(got)), tt.want) {
+			t.Errorf("%q: expected %v, got %v", tt.q, tt.want, got)
+		}
+	}
+
+	snippet := searchAuditLogs(t, st, "onerror")[0].Snippet
+	if !fullText {
+		if snippet != "" {
+			t.Errorf("Expected no snippet without full-text search, got %q", snippet)
+		}
+		return
+	}
+	if !strings.Contains(snippet, "<mark>onerror</mark>") {
+		t.Errorf("Expected the match to be highlighted, got %q", snippet)
+	}
+	if strings.Contains(snippet, "<img") || !strings.Contains(snippet, "&lt;img") {
+		t.Errorf("Expected logged markup to be escaped, got %q", snippet)
+	}
+}
@@ -0,0 +1,186 @@
+package steamtracker
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	AuditLogDropPolicyBlock = "block"
+	AuditLogDropPolicyDrop  = "drop"
+)
+
+// The audit log settings used when the Config leaves them zero.
+const (
+	defaultAuditLogBufferSize    = 1024
+	defaultAuditLogBatchSize     = 100
+	defaultAuditLogFlushInterval = time.Second
+)
+
+// AuditLogWriter is the zerolog sink that stores log lines as audit logs. It
+// hands lines to a background goroutine that inserts them in batches, either
+// when BatchSize lines are pending or every FlushInterval. When the buffer is
+// full, Write blocks (the "block" policy) or discards the line and counts it
+// (the "drop" policy). Each line keeps the time it was written, not the time
+// its batch is inserted.
+type AuditLogWriter struct {
+	insert        func(cmds []*CreateAuditLogCommand) error
+	batchSize     int
+	flushInterval time.Duration
+	dropWhenFull  bool
+
+	mu     sync.RWMutex // guards closed against concurrent sends
+	closed bool
+	lines  chan *CreateAuditLogCommand
+	done   chan struct{}
+
+	written  atomic.Uint64
+	dropped  atomic.Uint64
+	failed   atomic.Uint64
+	reported uint64 // dropped count already reported, owned by run
+}
+
+type AuditLogWriterStats struct {
+	Written uint64 `json:"written"`
+	Dropped uint64 `json:"dropped"`
+	Failed  uint64 `json:"failed"`
+	Pending int    `json:"pending"`
+}
+
+// NewAuditLogWriter starts a writer that passes batches of log lines to
+// insert, using the audit log settings of cfg or their defaults.
+func NewAuditLogWriter(insert func(cmds []*CreateAuditLogCommand) error, cfg *Config) *AuditLogWriter {
+	bufferSize := defaultAuditLogBufferSize
+	if cfg.AuditLogBufferSize > 0 {
+		bufferSize = cfg.AuditLogBufferSize
+	}
+	batchSize := defaultAuditLogBatchSize
+	if cfg.AuditLogBatchSize > 0 {
+		batchSize = cfg.AuditLogBatchSize
+	}
+	flushInterval := defaultAuditLogFlushInterval
+	if cfg.AuditLogFlushInterval > 0 {
+		flushInterval = time.Duration(cfg.AuditLogFlushInterval) * time.Millisecond
+	}
+
+	w := &AuditLogWriter{
+		insert:        insert,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		dropWhenFull:  cfg.AuditLogDropPolicy == AuditLogDropPolicyDrop,
+		lines:         make(chan *CreateAuditLogCommand, bufferSize),
+		done:          make(chan struct{}),
+	}
+
+	go w.run()
+
+	return w
+}
+
+func (w *AuditLogWriter) Write(p []byte) (int, error) {
+	// zerolog reuses p once Write returns.
+	line := &CreateAuditLogCommand{Raw: make(JSON, len(p)), CreatedAt: time.Now()}
+	copy(line.Raw, p)
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		w.dropped.Add(1)
+		return len(p), nil
+	}
+
+	if !w.dropWhenFull {
+		w.lines <- line
+		return len(p), nil
+	}
+
+	select {
+	case w.lines <- line:
+	default:
+		w.dropped.Add(1)
+	}
+
+	return len(p), nil
+}
+
+func (w *AuditLogWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*CreateAuditLogCommand, 0, w.batchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			if err := w.insert(batch); err != nil {
+				w.failed.Add(uint64(len(batch)))
+				// Logging through zerolog here would feed the failure back
+				// into this writer.
+				fmt.Fprintf(os.Stderr, "failed to write %d audit logs: %v\n", len(batch), err)
+			} else {
+				w.written.Add(uint64(len(batch)))
+			}
+			batch = make([]*CreateAuditLogCommand, 0, w.batchSize)
+		}
+
+		if dropped := w.dropped.Load(); dropped > w.reported {
+			log.Warn().
+				Uint64("dropped", dropped-w.reported).
+				Uint64("dropped_total", dropped).
+				Msg("Audit log buffer full, dropped log lines")
+			w.reported = dropped
+		}
+	}
+
+	for {
+		select {
+		case line, ok := <-w.lines:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, line)
+			if len(batch) >= w.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Close stops accepting lines and waits until everything buffered has been
+// written.
+func (w *AuditLogWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.lines)
+	w.mu.Unlock()
+
+	<-w.done
+
+	if failed := w.failed.Load(); failed > 0 {
+		return fmt.Errorf("failed to write %d audit logs", failed)
+	}
+
+	return nil
+}
+
+func (w *AuditLogWriter) Stats() AuditLogWriterStats {
+	return AuditLogWriterStats{
+		Written: w.written.Load(),
+		Dropped: w.dropped.Load(),
+		Failed:  w.failed.Load(),
+		Pending: len(w.lines),
+	}
+}
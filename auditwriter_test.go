@@ -0,0 +1,227 @@
+package steamtracker_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+// recordingInsert passes every batch to batches, optionally waiting for
+// release first.
+func recordingInsert(batches chan<- []*steamtracker.CreateAuditLogCommand, release <-chan struct{}) func(cmds []*steamtracker.CreateAuditLogCommand) error {
+	return func(cmds []*steamtracker.CreateAuditLogCommand) error {
+		batches <- cmds
+		if release != nil {
+			<-release
+		}
+		return nil
+	}
+}
+
+func auditWriterConfig(bufferSize, batchSize, flushInterval int, dropPolicy string) *steamtracker.Config {
+	return &steamtracker.Config{
+		AuditLogBufferSize:    bufferSize,
+		AuditLogBatchSize:     batchSize,
+		AuditLogFlushInterval: flushInterval,
+		AuditLogDropPolicy:    dropPolicy,
+	}
+}
+
+func writeAuditLine(t *testing.T, w *steamtracker.AuditLogWriter, i int) {
+	t.Helper()
+
+	if _, err := fmt.Fprintf(w, `{"level":"info","message":"line %d"}`, i); err != nil {
+		t.Fatalf("Failed to write line %d: %v", i, err)
+	}
+}
+
+func receiveBatch(t *testing.T, batches <-chan []*steamtracker.CreateAuditLogCommand) []*steamtracker.CreateAuditLogCommand {
+	t.Helper()
+
+	select {
+	case batch := <-batches:
+		return batch
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a batch")
+		return nil
+	}
+}
+
+func TestAuditLogWriterBatches(t *testing.T) {
+	batches := make(chan []*steamtracker.CreateAuditLogCommand, 10)
+	w := steamtracker.NewAuditLogWriter(recordingInsert(batches, nil), auditWriterConfig(10, 3, 3600*1000, steamtracker.AuditLogDropPolicyBlock))
+
+	for i := range 7 {
+		writeAuditLine(t, w, i)
+	}
+	for range 2 {
+		if batch := receiveBatch(t, batches); len(batch) != 3 {
+			t.Errorf("Expected a full batch of 3 lines, got %d", len(batch))
+		}
+	}
+
+	// The last line is only written when the writer is closed.
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	batch := receiveBatch(t, batches)
+	if len(batch) != 1 || string(batch[0].Raw) != `{"level":"info","message":"line 6"}` {
+		t.Errorf("Expected the last line to be flushed on close, got %v", batch)
+	}
+	if stats := w.Stats(); stats.Written != 7 || stats.Dropped != 0 || stats.Pending != 0 {
+		t.Errorf("Expected 7 written lines, got %+v", stats)
+	}
+
+	writeAuditLine(t, w, 7)
+	if stats := w.Stats(); stats.Dropped != 1 {
+		t.Errorf("Expected lines written after close to be dropped, got %+v", stats)
+	}
+}
+
+func TestAuditLogWriterFlushInterval(t *testing.T) {
+	batches := make(chan []*steamtracker.CreateAuditLogCommand, 10)
+	w := steamtracker.NewAuditLogWriter(recordingInsert(batches, nil), auditWriterConfig(10, 100, 10, steamtracker.AuditLogDropPolicyBlock))
+	defer w.Close()
+
+	before := time.Now()
+	writeAuditLine(t, w, 0)
+	after := time.Now()
+
+	batch := receiveBatch(t, batches)
+	if len(batch) != 1 {
+		t.Fatalf("Expected the line to be flushed on the interval, got %d lines", len(batch))
+	}
+	if at := batch[0].CreatedAt; at.Before(before) || at.After(after) {
+		t.Errorf("Expected the line to keep the time it was written, between %s and %s, got %s", before, after, at)
+	}
+}
+
+func TestAuditLogWriterDropPolicy(t *testing.T) {
+	batches := make(chan []*steamtracker.CreateAuditLogCommand, 10)
+	release := make(chan struct{})
+	w := steamtracker.NewAuditLogWriter(recordingInsert(batches, release), auditWriterConfig(2, 1, 3600*1000, steamtracker.AuditLogDropPolicyDrop))
+
+	// The first line is taken off the buffer and its insert hangs, so the
+	// next two fill the buffer and the rest are dropped.
+	writeAuditLine(t, w, 0)
+	receiveBatch(t, batches)
+	for i := 1; i < 6; i++ {
+		writeAuditLine(t, w, i)
+	}
+	if stats := w.Stats(); stats.Dropped != 3 || stats.Pending != 2 {
+		t.Errorf("Expected 3 dropped and 2 pending lines, got %+v", stats)
+	}
+
+	close(release)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	if stats := w.Stats(); stats.Written != 3 || stats.Dropped != 3 {
+		t.Errorf("Expected 3 written and 3 dropped lines, got %+v", stats)
+	}
+}
+
+func TestAuditLogWriterBlockPolicy(t *testing.T) {
+	batches := make(chan []*steamtracker.CreateAuditLogCommand, 10)
+	release := make(chan struct{})
+	w := steamtracker.NewAuditLogWriter(recordingInsert(batches, release), auditWriterConfig(1, 1, 3600*1000, steamtracker.AuditLogDropPolicyBlock))
+
+	writeAuditLine(t, w, 0)
+	receiveBatch(t, batches)
+	writeAuditLine(t, w, 1)
+
+	written := make(chan struct{})
+	go func() {
+		_, _ = fmt.Fprint(w, `{"level":"info","message":"line 2"}`)
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Fatal("Expected a write to a full buffer to block")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-written
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	if stats := w.Stats(); stats.Written != 3 || stats.Dropped != 0 {
+		t.Errorf("Expected all 3 lines to be written, got %+v", stats)
+	}
+}
+
+func TestAuditLogWriterCloseReportsFailures(t *testing.T) {
+	w := steamtracker.NewAuditLogWriter(func(cmds []*steamtracker.CreateAuditLogCommand) error {
+		return errors.New("database is gone")
+	}, auditWriterConfig(10, 100, 3600*1000, steamtracker.AuditLogDropPolicyBlock))
+
+	writeAuditLine(t, w, 0)
+	writeAuditLine(t, w, 1)
+	if err := w.Close(); err == nil {
+		t.Error("Expected Close to report the failed lines")
+	}
+	if stats := w.Stats(); stats.Failed != 2 {
+		t.Errorf("Expected 2 failed lines, got %+v", stats)
+	}
+}
+
+func TestCreateAuditLogsKeepsLoggedTime(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := st.CreateAuditLogs([]*steamtracker.CreateAuditLogCommand{{Raw: steamtracker.JSON(`{"level":"info","message":"test"}`), CreatedAt: at}}); err != nil {
+		t.Fatalf("Failed to create audit logs: %v", err)
+	}
+
+	rows := exportedRows(t, st, "audit_logs")
+	if len(rows) != 1 || rows[0]["created_at"] != at {
+		t.Errorf("Expected the audit log to be created at %s, got %v", at, rows)
+	}
+}
+
+func TestAuditLogWriterDefaults(t *testing.T) {
+	batches := make(chan []*steamtracker.CreateAuditLogCommand, 10)
+	w := steamtracker.NewAuditLogWriter(recordingInsert(batches, nil), &steamtracker.Config{})
+	t.Cleanup(func() { _ = w.Close() })
+
+	// The default flush interval sends a single line on its own.
+	writeAuditLine(t, w, 0)
+	if batch := receiveBatch(t, batches); len(batch) != 1 {
+		t.Errorf("Expected a batch of 1, got %d", len(batch))
+	}
+}
+
+func TestConfigValidateAuditLogSettings(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *steamtracker.Config)
+		valid  bool
+	}{
+		{"defaults", func(cfg *steamtracker.Config) {}, true},
+		{"drop policy", func(cfg *steamtracker.Config) { cfg.AuditLogDropPolicy = steamtracker.AuditLogDropPolicyDrop }, true},
+		{"unknown drop policy", func(cfg *steamtracker.Config) { cfg.AuditLogDropPolicy = "discard" }, false},
+		{"negative buffer size", func(cfg *steamtracker.Config) { cfg.AuditLogBufferSize = -1 }, false},
+		{"negative batch size", func(cfg *steamtracker.Config) { cfg.AuditLogBatchSize = -1 }, false},
+		{"negative flush interval", func(cfg *steamtracker.Config) { cfg.AuditLogFlushInterval = -1 }, false},
+	}
+
+	for _, tt := range tests {
+		cfg := steamtracker.Config{
+			DatabaseDSN:       "sqlite://steamtracker.db",
+			HTTPPort:          "8080",
+			SteamAPIKey:       "key",
+			SteamID:           "76561197960287930",
+			MaxTaskRetryCount: 3,
+			TaskInterval:      60,
+			DisableAuth:       true,
+		}
+		tt.modify(&cfg)
+		if err := cfg.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got %v", tt.name, tt.valid, err)
+		}
+	}
+}
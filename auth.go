@@ -0,0 +1,431 @@
+package steamtracker
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+
+	steamtrackerv1 "github.com/willywotz/steam-tracker/proto/steamtracker/v1"
+)
+
+// Scopes an API token can be granted. ScopeAdmin implies every other scope.
+const (
+	ScopeReadPlayers = "read:players"
+	ScopeReadAudit   = "read:audit"
+	ScopeAdmin       = "admin"
+)
+
+const (
+	APIErrorUnauthorized = "unauthorized"
+)
+
+const (
+	// apiTokenPrefix starts every API token, so leaked tokens are easy to
+	// recognize.
+	apiTokenPrefix = "st_"
+	// apiTokenLastUsedInterval is how often the last use of a token is
+	// written, at most.
+	apiTokenLastUsedInterval = time.Minute
+)
+
+var ErrAPITokenNotFound = errors.New("API token not found")
+
+// Scopes is a list of scopes, stored comma-separated.
+type Scopes []string
+
+// Has reports whether the scopes grant scope.
+func (s Scopes) Has(scope string) bool {
+	return slices.Contains(s, scope) || slices.Contains(s, ScopeAdmin)
+}
+
+func (s *Scopes) Scan(value interface{}) error {
+	var v string
+	switch value := value.(type) {
+	case nil:
+	case string:
+		v = value
+	case []byte:
+		v = string(value)
+	default:
+		return fmt.Errorf("unsupported type for Scopes: %T", value)
+	}
+
+	*s = Scopes{}
+	if v != "" {
+		*s = strings.Split(v, ",")
+	}
+	return nil
+}
+
+func (s Scopes) Value() (driver.Value, error) {
+	return strings.Join(s, ","), nil
+}
+
+// APIToken grants its scopes to requests that send it as a Bearer token.
+// Only a hash of the token is stored; the token itself is shown once, when
+// it is created.
+type APIToken struct {
+	ID   int64  `json:"id" gorm:"primaryKey"`
+	Name string `json:"name" gorm:"size:128"`
+	// Prefix is the start of the token, to tell tokens apart.
+	Prefix     string     `json:"prefix" gorm:"size:16"`
+	TokenHash  string     `json:"-" gorm:"size:64;uniqueIndex"`
+	Scopes     Scopes     `json:"scopes" gorm:"type:text"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+type CreateAPITokenCommand struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+func (cmd *CreateAPITokenCommand) Validate() error {
+	cmd.Name = strings.TrimSpace(cmd.Name)
+	if cmd.Name == "" {
+		return fmt.Errorf("token name cannot be empty")
+	}
+	if len(cmd.Name) > 128 {
+		return fmt.Errorf("token name is too long: %d characters, at most 128", len(cmd.Name))
+	}
+
+	if len(cmd.Scopes) == 0 {
+		return fmt.Errorf("token needs at least one scope")
+	}
+	for _, scope := range cmd.Scopes {
+		switch scope {
+		case ScopeReadPlayers, ScopeReadAudit, ScopeAdmin:
+		default:
+			return fmt.Errorf("invalid scope: %q, must be %s, %s or %s", scope, ScopeReadPlayers, ScopeReadAudit, ScopeAdmin)
+		}
+	}
+
+	return nil
+}
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIToken stores a new token and returns it with the token itself,
+// which cannot be read back later.
+func (st *SteamTracker) CreateAPIToken(ctx context.Context, cmd *CreateAPITokenCommand) (*APIToken, string, error) {
+	event := log.Info().Str("action", "create_api_token")
+	defer func() { event.Send() }()
+
+	if err := cmd.Validate(); err != nil {
+		event.Err(err)
+		return nil, "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		err = fmt.Errorf("failed to generate API token: %w", err)
+		event.Err(err)
+		return nil, "", err
+	}
+	token := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	apiToken := APIToken{
+		ID:        st.GenerateID(),
+		Name:      cmd.Name,
+		Prefix:    token[:len(apiTokenPrefix)+8],
+		TokenHash: hashAPIToken(token),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(cmd.Scopes))),
+	}
+	if err := st.db.WithContext(ctx).Create(&apiToken).Error; err != nil {
+		err = fmt.Errorf("failed to create API token: %w", err)
+		event.Err(err)
+		return nil, "", err
+	}
+
+	event.Int64("token_id", apiToken.ID).Str("name", apiToken.Name).Strs("scopes", apiToken.Scopes)
+	return &apiToken, token, nil
+}
+
+// RevokeAPIToken stops a token from being accepted. Revoking a revoked token
+// does nothing.
+func (st *SteamTracker) RevokeAPIToken(ctx context.Context, id int64) error {
+	event := log.Info().Str("action", "revoke_api_token").Int64("token_id", id)
+	defer func() { event.Send() }()
+
+	err := st.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		apiToken := APIToken{}
+		if err := tx.Where("id = ?", id).Take(&apiToken).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAPITokenNotFound
+		} else if err != nil {
+			return fmt.Errorf("failed to get API token: %w", err)
+		}
+		if apiToken.RevokedAt != nil {
+			return nil
+		}
+
+		if err := tx.Model(&apiToken).Update("revoked_at", time.Now()).Error; err != nil {
+			return fmt.Errorf("failed to revoke API token: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		event.Err(err)
+	}
+
+	return err
+}
+
+// ListAPITokens returns every token, revoked ones included, oldest first.
+func (st *SteamTracker) ListAPITokens(ctx context.Context) ([]*APIToken, error) {
+	tokens := make([]*APIToken, 0)
+	if err := st.db.WithContext(ctx).Order("created_at").Order("id").Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("failed to list API tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// authenticateAPIToken returns the active token matching token, or nil if
+// there is none.
+func (st *SteamTracker) authenticateAPIToken(ctx context.Context, token string) (*APIToken, error) {
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return nil, nil
+	}
+
+	apiToken := APIToken{}
+	err := st.db.WithContext(ctx).Where("token_hash = ? AND revoked_at IS NULL", hashAPIToken(token)).Take(&apiToken).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API token: %w", err)
+	}
+
+	now := time.Now()
+	if apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) >= apiTokenLastUsedInterval {
+		if err := st.db.WithContext(ctx).Model(&apiToken).Update("last_used_at", now).Error; err != nil {
+			log.Warn().Err(err).Int64("token_id", apiToken.ID).Msg("Failed to record API token use")
+		}
+	}
+
+	return &apiToken, nil
+}
+
+// Principal is who a request is made by.
+type Principal struct {
+	// Kind is "token", "dashboard" or, with authentication disabled,
+	// "anonymous".
+	Kind    string
+	Name    string
+	TokenID int64
+	Scopes  Scopes
+}
+
+func (p *Principal) String() string {
+	switch p.Kind {
+	case "token":
+		return fmt.Sprintf("token %d (%s)", p.TokenID, p.Name)
+	case "dashboard":
+		return "dashboard user " + p.Name
+	default:
+		return p.Kind
+	}
+}
+
+type principalContextKey struct{}
+
+// PrincipalFromContext returns the principal of the request ctx belongs to,
+// or nil outside of an authenticated request.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return p
+}
+
+func contextWithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, p)
+}
+
+// hasScope reports whether the principal of ctx was granted scope.
+func hasScope(ctx context.Context, scope string) bool {
+	p := PrincipalFromContext(ctx)
+	return p != nil && p.Scopes.Has(scope)
+}
+
+var anonymousPrincipal = &Principal{Kind: "anonymous", Scopes: Scopes{ScopeAdmin}}
+
+// dashboardScopes are granted to the dashboard user, enough for the pages
+// of the dashboard.
+var dashboardScopes = Scopes{ScopeReadPlayers}
+
+// authenticate returns the principal of a Bearer token or of the dashboard
+// credentials, or nil if the request has neither. err is set for an
+// Authorization header that is invalid or could not be checked.
+func (st *SteamTracker) authenticate(ctx context.Context, authorization string) (*Principal, error) {
+	if st.cfg.DisableAuth {
+		return anonymousPrincipal, nil
+	}
+	if authorization == "" {
+		return nil, nil
+	}
+
+	scheme, credentials, _ := strings.Cut(authorization, " ")
+	switch {
+	case strings.EqualFold(scheme, "Bearer"):
+		apiToken, err := st.authenticateAPIToken(ctx, strings.TrimSpace(credentials))
+		if err != nil {
+			return nil, err
+		}
+		if apiToken == nil {
+			return nil, fmt.Errorf("invalid or revoked API token")
+		}
+		return &Principal{Kind: "token", Name: apiToken.Name, TokenID: apiToken.ID, Scopes: apiToken.Scopes}, nil
+	case strings.EqualFold(scheme, "Basic") && st.cfg.DashboardUsername != "":
+		r := http.Request{Header: http.Header{"Authorization": {authorization}}}
+		username, password, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(username), []byte(st.cfg.DashboardUsername)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(st.cfg.DashboardPassword)) != 1 {
+			return nil, fmt.Errorf("invalid dashboard username or password")
+		}
+		return &Principal{Kind: "dashboard", Name: username, Scopes: dashboardScopes}, nil
+	default:
+		return nil, fmt.Errorf("unsupported authorization scheme: %s", scheme)
+	}
+}
+
+// requireScope serves h to requests whose principal was granted scope, and
+// records the principal of each request. An empty scope only records it.
+func (st *SteamTracker) requireScope(scope string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		event := log.Debug().Str("action", "http_request").Str("method", r.Method).Str("path", r.URL.Path)
+
+		p, err := st.authenticate(r.Context(), r.Header.Get("Authorization"))
+		switch {
+		case err != nil || (p == nil && scope != ""):
+			if err == nil {
+				err = fmt.Errorf("authentication required")
+			}
+			event.Err(err).Int("status", http.StatusUnauthorized).Send()
+			w.Header().Set("WWW-Authenticate", `Bearer realm="steamtracker"`)
+			writeAPIError(w, http.StatusUnauthorized, APIErrorUnauthorized, err.Error())
+			return
+		case scope != "" && !p.Scopes.Has(scope):
+			event.Str("principal", p.String()).Int("status", http.StatusForbidden).Send()
+			writeAPIError(w, http.StatusForbidden, APIErrorForbidden, fmt.Sprintf("this endpoint requires the %s scope", scope))
+			return
+		}
+
+		if p != nil {
+			event.Str("principal", p.String())
+			r = r.WithContext(contextWithPrincipal(r.Context(), p))
+		}
+		event.Send()
+
+		h(w, r)
+	}
+}
+
+// requireDashboardAuth asks for the dashboard credentials with Basic
+// authentication if they are configured. Otherwise the dashboard needs a
+// token with the read:players scope, like the API it reads.
+func (st *SteamTracker) requireDashboardAuth(h http.HandlerFunc) http.HandlerFunc {
+	if st.cfg.DisableAuth || st.cfg.DashboardUsername == "" {
+		return st.requireScope(ScopeReadPlayers, h)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		p, err := st.authenticate(r.Context(), r.Header.Get("Authorization"))
+		if err != nil || p == nil || p.Kind != "dashboard" {
+			log.Debug().Str("action", "http_request").Str("method", r.Method).Str("path", r.URL.Path).Int("status", http.StatusUnauthorized).Send()
+			w.Header().Set("WWW-Authenticate", `Basic realm="steamtracker", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		log.Debug().Str("action", "http_request").Str("method", r.Method).Str("path", r.URL.Path).Str("principal", p.String()).Send()
+		h(w, r.WithContext(contextWithPrincipal(r.Context(), p)))
+	}
+}
+
+// grpcMethodScopes are the scopes the methods of SteamTrackerService
+// require.
+var grpcMethodScopes = map[string]string{
+	steamtrackerv1.SteamTrackerService_SearchPlayers_FullMethodName:      ScopeReadPlayers,
+	steamtrackerv1.SteamTrackerService_SearchPlayerEvents_FullMethodName: ScopeReadPlayers,
+	steamtrackerv1.SteamTrackerService_SearchAuditLogs_FullMethodName:    ScopeReadAudit,
+	steamtrackerv1.SteamTrackerService_WatchPlayerEvents_FullMethodName:  ScopeReadPlayers,
+}
+
+// authenticateGRPC checks the Bearer token in the authorization metadata of
+// a call against the scope of its method.
+func (st *SteamTracker) authenticateGRPC(ctx context.Context, method string) (context.Context, error) {
+	event := log.Debug().Str("action", "grpc_request").Str("method", method)
+	defer func() { event.Send() }()
+
+	authorization := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+
+	p, err := st.authenticate(ctx, authorization)
+	if err == nil && p == nil {
+		err = fmt.Errorf("authentication required")
+	}
+	if err != nil {
+		event.Err(err)
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	event.Str("principal", p.String())
+
+	scope, ok := grpcMethodScopes[method]
+	if !ok {
+		scope = ScopeAdmin
+	}
+	if !p.Scopes.Has(scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "this method requires the %s scope", scope)
+	}
+
+	return contextWithPrincipal(ctx, p), nil
+}
+
+func (st *SteamTracker) grpcUnaryAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := st.authenticateGRPC(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (st *SteamTracker) grpcStreamAuth(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := st.authenticateGRPC(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &principalServerStream{ServerStream: ss, ctx: ctx})
+}
+
+// principalServerStream carries the principal of a stream in its context.
+type principalServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalServerStream) Context() context.Context { return s.ctx }
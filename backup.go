@@ -0,0 +1,360 @@
+package steamtracker
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var ErrBackupUnsupported = errors.New("backups are only supported for SQLite databases")
+
+const backupTimeFormat = "20060102T150405Z"
+
+// Backup writes a consistent snapshot of the database to path using
+// VACUUM INTO, which is safe while other connections keep writing.
+func (st *SteamTracker) Backup(ctx context.Context, path string) error {
+	event := log.Info().
+		Str("action", "backup_database").
+		Str("path", path)
+	defer func() { event.Send() }()
+
+	if st.Dialect() != DialectSQLite {
+		event.Err(ErrBackupUnsupported)
+		return ErrBackupUnsupported
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		err = fmt.Errorf("failed to create backup directory: %w", err)
+		event.Err(err)
+		return err
+	}
+
+	if err := st.db.WithContext(ctx).Exec("VACUUM INTO ?", path).Error; err != nil {
+		err = fmt.Errorf("failed to back up database: %w", err)
+		event.Err(err)
+		return err
+	}
+
+	return nil
+}
+
+// BackupToDir takes a backup into dir, named after the database and the
+// current time, optionally gzip-compressed, and returns its path.
+func (st *SteamTracker) BackupToDir(ctx context.Context, dir string, compress bool) (string, error) {
+	if dir == "" {
+		dir = "backups"
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.db", st.backupPrefix(), time.Now().UTC().Format(backupTimeFormat)))
+
+	if err := st.Backup(ctx, path); err != nil {
+		return "", err
+	}
+
+	if !compress {
+		return path, nil
+	}
+
+	if err := gzipFile(path, path+".gz"); err != nil {
+		return "", fmt.Errorf("failed to compress backup: %w", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return "", fmt.Errorf("failed to remove uncompressed backup: %w", err)
+	}
+
+	return path + ".gz", nil
+}
+
+func (st *SteamTracker) backupPrefix() string {
+	return strings.TrimSuffix(st.DatabaseName(), filepath.Ext(st.DatabaseName()))
+}
+
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+type backupFile struct {
+	path string
+	at   time.Time
+}
+
+// listBackups returns the backups of this database in dir, newest first.
+func (st *SteamTracker) listBackups(dir string) ([]backupFile, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	prefix := st.backupPrefix() + "-"
+	backups := make([]backupFile, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ".db")
+		at, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue // not one of ours
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), at: at})
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].at.After(backups[j].at) })
+	return backups, nil
+}
+
+// RotateBackups keeps the newest backup of each of the last keepDaily days
+// and of each of the last keepWeekly ISO weeks, and deletes the rest. The
+// newest backup is always kept. With both limits at zero nothing is deleted.
+func (st *SteamTracker) RotateBackups(dir string, keepDaily, keepWeekly int) ([]string, error) {
+	event := log.Info().
+		Str("action", "rotate_backups").
+		Str("dir", dir)
+	defer func() { event.Send() }()
+
+	if keepDaily <= 0 && keepWeekly <= 0 {
+		return nil, nil
+	}
+
+	backups, err := st.listBackups(dir)
+	if err != nil {
+		err = fmt.Errorf("failed to list backups: %w", err)
+		event.Err(err)
+		return nil, err
+	}
+
+	keep := make(map[string]bool)
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	for i, b := range backups {
+		day := b.at.Format("2006-01-02")
+		year, week := b.at.ISOWeek()
+		weekKey := fmt.Sprintf("%d-%02d", year, week)
+
+		if i == 0 {
+			keep[b.path] = true
+		}
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep[b.path] = true
+		}
+		if !weeks[weekKey] && len(weeks) < keepWeekly {
+			weeks[weekKey] = true
+			keep[b.path] = true
+		}
+	}
+
+	removed := make([]string, 0)
+	for _, b := range backups {
+		if keep[b.path] {
+			continue
+		}
+		if err := os.Remove(b.path); err != nil {
+			err = fmt.Errorf("failed to remove backup %s: %w", b.path, err)
+			event.Err(err)
+			return removed, err
+		}
+		removed = append(removed, b.path)
+	}
+
+	event.Int("removed", len(removed))
+	return removed, nil
+}
+
+func (st *SteamTracker) backupTask() {
+	st.wg.Add(1)
+	defer st.wg.Done()
+
+	if _, err := st.BackupToDir(st.ctx, st.cfg.BackupDir, st.cfg.BackupGzip); err != nil {
+		log.Error().Err(err).Msg("Failed to back up database")
+		return
+	}
+
+	if _, err := st.RotateBackups(st.cfg.BackupDir, st.cfg.BackupKeepDaily, st.cfg.BackupKeepWeekly); err != nil {
+		log.Error().Err(err).Msg("Failed to rotate backups")
+	}
+}
+
+type RestoreBackupCommand struct {
+	// From is the backup to restore, optionally gzip-compressed.
+	From string `json:"from"`
+	// DatabaseDSN is the SQLite database that will be replaced.
+	DatabaseDSN string `json:"database_dsn"`
+	// Confirm must equal the file name of the database being replaced.
+	Confirm string `json:"confirm"`
+}
+
+// RestoreBackup replaces a SQLite database file with a backup. The tracker
+// must not be running against the database while it is restored. The file
+// being replaced is kept next to it with a .pre-restore suffix.
+func RestoreBackup(cmd *RestoreBackupCommand) error {
+	event := log.Warn().
+		Str("action", "restore_backup").
+		Str("from", cmd.From)
+	defer func() { event.Send() }()
+
+	dialector, err := openDialector(cmd.DatabaseDSN)
+	if err != nil {
+		event.Err(err)
+		return err
+	}
+	sqliteDialector, ok := dialector.(*sqlite.Dialector)
+	if !ok {
+		event.Err(ErrBackupUnsupported)
+		return ErrBackupUnsupported
+	}
+
+	target := sqlitePath(sqliteDialector.DSN)
+	if name := filepath.Base(target); cmd.Confirm != name {
+		err := fmt.Errorf("confirmation %q does not match database name %q", cmd.Confirm, name)
+		event.Err(err)
+		return err
+	}
+
+	staged, err := stageBackup(cmd.From, filepath.Dir(target))
+	if err != nil {
+		event.Err(err)
+		return err
+	}
+	defer os.Remove(staged) // no-op once renamed into place
+
+	if err := verifyBackup(staged); err != nil {
+		err = fmt.Errorf("refusing to restore %s: %w", cmd.From, err)
+		event.Err(err)
+		return err
+	}
+
+	if _, err := os.Stat(target); err == nil {
+		previous := fmt.Sprintf("%s.pre-restore-%s", target, time.Now().UTC().Format(backupTimeFormat))
+		if err := os.Rename(target, previous); err != nil {
+			err = fmt.Errorf("failed to move current database aside: %w", err)
+			event.Err(err)
+			return err
+		}
+		event.Str("previous", previous)
+	}
+
+	// Stale WAL files belong to the old database and must not be replayed
+	// into the restored one.
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(target + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			err = fmt.Errorf("failed to remove %s: %w", target+suffix, err)
+			event.Err(err)
+			return err
+		}
+	}
+
+	if err := os.Rename(staged, target); err != nil {
+		err = fmt.Errorf("failed to move backup into place: %w", err)
+		event.Err(err)
+		return err
+	}
+
+	return nil
+}
+
+// stageBackup copies (and decompresses) the backup next to the target so the
+// final rename is atomic.
+func stageBackup(from, dir string) (string, error) {
+	in, err := os.Open(from)
+	if err != nil {
+		return "", fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer in.Close()
+
+	var r io.Reader = in
+	if strings.HasSuffix(from, ".gz") {
+		zr, err := gzip.NewReader(in)
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress backup: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	out, err := os.CreateTemp(dir, ".restore-*.db")
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging file: %w", err)
+	}
+
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return "", fmt.Errorf("failed to stage backup: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("failed to stage backup: %w", err)
+	}
+
+	return out.Name(), nil
+}
+
+func verifyBackup(path string) error {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	var result string
+	if err := db.Raw("PRAGMA integrity_check").Scan(&result).Error; err != nil {
+		return fmt.Errorf("integrity check failed: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("integrity check failed: %s", result)
+	}
+
+	if db.Migrator().HasTable(&SchemaVersion{}) {
+		var version int
+		if err := db.Model(&SchemaVersion{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error; err != nil {
+			return fmt.Errorf("failed to read schema version: %w", err)
+		}
+		if latest := LatestSchemaVersion(); version > latest {
+			return fmt.Errorf("backup schema version %d is newer than this binary supports (%d)", version, latest)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package steamtracker
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError describes one invalid query parameter.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// BindError lists every invalid query parameter of a request.
+type BindError struct {
+	Fields []FieldError
+}
+
+func (e *BindError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *BindError) add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// err returns e with its fields sorted by name, or nil if it has none.
+func (e *BindError) err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+
+	slices.SortStableFunc(e.Fields, func(a, b FieldError) int { return strings.Compare(a.Field, b.Field) })
+	return e
+}
+
+var (
+	timeType         = reflect.TypeFor[time.Time]()
+	steamIDType      = reflect.TypeFor[SteamID]()
+	personaStateType = reflect.TypeFor[PersonaState]()
+)
+
+// BindQuery fills dst, a pointer to a query struct, from URL query values.
+//
+// A field is named by its query tag, or its json tag if it has none, and
+// fields of nested structs are named parent[child], as in sort_by[id].
+// Slice fields take repeated and comma-separated values. A query tag of "-"
+// skips the field. Empty values are treated as missing unless the query tag
+// has the allowempty option. Parameters without a field are ignored.
+//
+// Every value that cannot be parsed is reported in the returned *BindError.
+func BindQuery(values url.Values, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("BindQuery: dst must be a pointer to a struct, got %T", dst)
+	}
+
+	bindErr := &BindError{}
+	bindStruct(values, v.Elem(), "", bindErr)
+	return bindErr.err()
+}
+
+func bindStruct(values url.Values, v reflect.Value, prefix string, bindErr *BindError) {
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, allowEmpty := queryParamName(f)
+		if name == "" {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "[" + name + "]"
+		}
+
+		if f.Type.Kind() == reflect.Struct && f.Type != timeType {
+			bindStruct(values, v.Field(i), name, bindErr)
+			continue
+		}
+
+		raw, ok := values[name]
+		if !ok {
+			continue
+		}
+		if f.Type.Kind() == reflect.Slice {
+			if err := appendQueryValues(v.Field(i), raw); err != nil {
+				bindErr.add(name, err.Error())
+			}
+			continue
+		}
+		if len(raw) > 1 {
+			bindErr.add(name, "must be given once")
+			continue
+		}
+		if raw[0] == "" && !allowEmpty {
+			continue
+		}
+
+		if err := setQueryValue(v.Field(i), raw[0]); err != nil {
+			bindErr.add(name, err.Error())
+		}
+	}
+}
+
+func queryParamName(f reflect.StructField) (string, bool) {
+	if tag, ok := f.Tag.Lookup("query"); ok {
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			return "", false
+		}
+		return name, slices.Contains(strings.Split(opts, ","), "allowempty")
+	}
+
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return "", false
+	}
+	return name, false
+}
+
+// appendQueryValues appends every value to the slice v. Values may be
+// repeated parameters, comma-separated or both.
+func appendQueryValues(v reflect.Value, raw []string) error {
+	for _, r := range raw {
+		for _, s := range strings.Split(r, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setQueryValue(elem, s); err != nil {
+				return fmt.Errorf("%q: %w", s, err)
+			}
+			v.Set(reflect.Append(v, elem))
+		}
+	}
+
+	return nil
+}
+
+func setQueryValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		p := reflect.New(v.Type().Elem())
+		if err := setQueryValue(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	switch {
+	case v.Type() == timeType:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("must be an RFC 3339 time, such as 2006-01-02T15:04:05Z")
+		}
+		v.Set(reflect.ValueOf(t))
+	case v.Type() == steamIDType:
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("must be a numeric Steam ID")
+		}
+		v.SetInt(id)
+	case v.Type() == personaStateType:
+		state, err := parsePersonaState(s)
+		if err != nil {
+			return fmt.Errorf("must be a persona state name or number")
+		}
+		v.Set(reflect.ValueOf(state))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("must be true or false")
+		}
+		v.SetBool(b)
+	case v.CanInt():
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		v.SetInt(n)
+	default:
+		return fmt.Errorf("unsupported parameter type %s", v.Type())
+	}
+
+	return nil
+}
+
+type validator interface {
+	Validate() error
+}
+
+// bindQuery binds the query parameters of r into query and validates it.
+// The named path wildcards are bound like parameters and, if the route has
+// them, take precedence over parameters of the same name.
+func bindQuery(r *http.Request, query validator, wildcards ...string) error {
+	values := r.URL.Query()
+	for _, name := range wildcards {
+		if v := r.PathValue(name); v != "" {
+			values[name] = []string{v}
+		}
+	}
+
+	if err := BindQuery(values, query); err != nil {
+		return err
+	}
+
+	return query.Validate()
+}
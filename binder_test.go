@@ -0,0 +1,116 @@
+package steamtracker_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+func TestQueryParameterValidation(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	handler := st.Handler()
+
+	tests := []struct {
+		name   string
+		target string
+		// fields lists the parameters reported as invalid, whether they
+		// fail to parse or fail validation, nil for a successful request.
+		fields []string
+	}{
+		{"players valid", "/api/v1/players?steam_id=76561197960287930&start_created_at=2024-01-01T00:00:00Z&sort_by[created_at]=DESC", nil},
+		{"players steam_id", "/api/v1/players?steam_id=abc", []string{"steam_id"}},
+		{"players every invalid parameter", "/api/v1/players?page=x&limit=y&steam_id=abc&start_created_at=yesterday&end_created_at=2024-13-01", []string{"end_created_at", "limit", "page", "start_created_at", "steam_id"}},
+		{"players repeated parameter", "/api/v1/players?steam_id=1&steam_id=2", []string{"steam_id"}},
+		{"players include_total", "/api/v1/players?include_total=maybe", []string{"include_total"}},
+		{"players empty cursor", "/api/v1/players?cursor=", nil},
+		{"players cursor", "/api/v1/players?cursor=invalid", []string{"cursor"}},
+		{"players empty values are ignored", "/api/v1/players?steam_id=&page=", nil},
+		{"players unknown parameters are ignored", "/api/v1/players?_=123", nil},
+
+		{"player_events valid", "/api/v1/player_events?steam_id=1&page=2&limit=10", nil},
+		{"player_events steam_id", "/api/v1/player_events?steam_id=-", []string{"steam_id"}},
+		{"player_events sort_by", "/api/v1/player_events?sort_by[created_at]=sideways", []string{"sort_by[created_at]"}},
+		{"player_events filters", "/api/v1/player_events?steam_ids=1,2&steam_ids=3&type=first_seen,game&persona_state=online&persona_state=Looking_To_Play,0&persona_name=bob&start_created_at=2024-01-01T00:00:00Z&end_created_at=2024-02-01T00:00:00Z", nil},
+		{"player_events steam_ids", "/api/v1/player_events?steam_ids=1,two", []string{"steam_ids"}},
+		{"player_events persona_state", "/api/v1/player_events?persona_state=online,asleep", []string{"persona_state"}},
+		{"player_events type", "/api/v1/player_events?type=login", []string{"type"}},
+		{"player_events time range", "/api/v1/player_events?start_created_at=2024-02-01T00:00:00Z&end_created_at=2024-01-01T00:00:00Z", []string{"start_created_at"}},
+		{"player_events every invalid value", "/api/v1/player_events?type=login&sort_by[created_at]=sideways&start_created_at=2024-02-01T00:00:00Z&end_created_at=2024-01-01T00:00:00Z", []string{"sort_by[created_at]", "start_created_at", "type"}},
+
+		{"audit_logs valid", "/api/v1/audit_logs?level=INFO&has_error=false&start_logged_at=2024-01-01T00:00:00Z&sort_by[id]=asc", nil},
+		{"audit_logs has_error", "/api/v1/audit_logs?has_error=sometimes", []string{"has_error"}},
+		{"audit_logs times", "/api/v1/audit_logs?start_logged_at=1&end_logged_at=2", []string{"end_logged_at", "start_logged_at"}},
+		{"audit_logs level", "/api/v1/audit_logs?level=loud", []string{"level"}},
+		{"audit_logs cursor", "/api/v1/audit_logs?cursor=invalid&sort_by[logged_at]=asc", []string{"cursor", "sort_by[logged_at]"}},
+
+		{"player_rollups valid", "/api/v1/player_rollups?granularity=day&steam_id=1&sort_by[bucket_start]=asc", nil},
+		{"player_rollups bucket", "/api/v1/player_rollups?start_bucket_start=monday&steam_id=x", []string{"start_bucket_start", "steam_id"}},
+		{"player_rollups granularity", "/api/v1/player_rollups?granularity=week", []string{"granularity"}},
+
+		{"players/current valid", "/api/v1/players/current?online=true", nil},
+		{"players/current online", "/api/v1/players/current?online=yes", []string{"online"}},
+
+		{"export since", "/api/v1/export/players?since=last-week", []string{"since"}},
+		{"export table is not a parameter", "/api/v1/export/players?table=secrets&format=csv", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if tt.fields == nil {
+				if w.Code != http.StatusOK {
+					t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+				}
+				return
+			}
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected 400, got %d: %s", w.Code, w.Body.String())
+			}
+
+			resp := struct {
+				Error struct {
+					Code    string                    `json:"code"`
+					Details []steamtracker.FieldError `json:"details"`
+				} `json:"error"`
+			}{}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Response is not JSON: %v", err)
+			}
+			if resp.Error.Code != steamtracker.APIErrorInvalidQuery {
+				t.Errorf("Expected code %s, got %s", steamtracker.APIErrorInvalidQuery, resp.Error.Code)
+			}
+
+			fields := make([]string, 0)
+			for _, f := range resp.Error.Details {
+				if f.Message == "" {
+					t.Errorf("Expected a message for %s", f.Field)
+				}
+				fields = append(fields, f.Field)
+			}
+			if !slices.Equal(fields, tt.fields) {
+				t.Errorf("Expected invalid fields %v, got %v", tt.fields, fields)
+			}
+		})
+	}
+}
+
+func TestLegacyQueryParameterValidation(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+
+	w := httptest.NewRecorder()
+	st.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/players?steam_id=abc", nil))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+	if body := w.Body.String(); body != "Invalid query parameters: steam_id: must be a numeric Steam ID\n" {
+		t.Errorf("Unexpected response %q", body)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+)
+
+func auditCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "audit",
+		Usage: "Manage the audit log",
+		Commands: []*cli.Command{
+			{
+				Name:  "reindex",
+				Usage: "Create the full-text search index if needed and rebuild it from all audit logs",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					st, err := openTracker(cmd)
+					if err != nil {
+						return err
+					}
+					defer st.Close()
+
+					if err := st.Migrate(); err != nil {
+						return fmt.Errorf("failed to migrate database: %w", err)
+					}
+
+					rows, err := st.ReindexAuditLogs(ctx)
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("indexed %d audit logs\n", rows)
+					return nil
+				},
+			},
+			{
+				Name:  "verify",
+				Usage: "Walk the audit log hash chain and report the first break",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "pin", Usage: "Hash of a previously pinned chain head that must still be part of the chain"},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					st, err := openTracker(cmd)
+					if err != nil {
+						return err
+					}
+					defer st.Close()
+
+					if err := st.Migrate(); err != nil {
+						return fmt.Errorf("failed to migrate database: %w", err)
+					}
+
+					report, err := st.VerifyAuditChain(ctx, cmd.String("pin"))
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("checked %d audit logs\n", report.Checked)
+					if report.Anchor != "" {
+						fmt.Printf("chain starts at audit log %d after removed rows, anchored on %s\n", report.FirstID, report.Anchor)
+					}
+					if report.Head != nil {
+						fmt.Printf("head: audit log %d, hash %s\n", report.Head.ID, report.Head.Hash)
+					}
+
+					if report.Break != nil {
+						return fmt.Errorf("audit chain broken at audit log %d: %s", report.Break.ID, report.Break.Reason)
+					}
+					if cmd.String("pin") != "" && !report.PinnedFound {
+						return fmt.Errorf("pinned hash %s is not part of the audit chain", cmd.String("pin"))
+					}
+
+					fmt.Println("audit chain intact")
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/urfave/cli/v3"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+func dbCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "db",
+		Usage: "Database maintenance commands",
+		Commands: []*cli.Command{
+			{
+				Name:  "reset",
+				Usage: "Delete all rows from the data tables after taking a backup",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "confirm", Required: true, Usage: "Name of the database to reset (the file name for SQLite)"},
+					&cli.StringSliceFlag{Name: "table", Usage: "Reset only this table (repeatable, default: all data tables)"},
+					&cli.BoolFlag{Name: "skip-backup", Usage: "Do not take a backup first (required for non-SQLite databases)"},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					st, err := openTracker(cmd)
+					if err != nil {
+						return err
+					}
+					defer st.Close()
+
+					if err := st.Migrate(); err != nil {
+						return fmt.Errorf("failed to migrate database: %w", err)
+					}
+
+					result, err := st.ResetDatabase(&steamtracker.ResetDatabaseCommand{
+						Confirm:    cmd.String("confirm"),
+						Tables:     cmd.StringSlice("table"),
+						BackupDir:  cmd.String("backup-dir"),
+						SkipBackup: cmd.Bool("skip-backup"),
+					})
+					if result.BackupPath != "" {
+						fmt.Printf("backup written to %s\n", result.BackupPath)
+					}
+					if err != nil {
+						return err
+					}
+
+					for table, deleted := range result.Tables {
+						fmt.Printf("reset %s: %d rows deleted\n", table, deleted)
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:  "backup",
+				Usage: "Take a consistent backup of the SQLite database now",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "rotate", Usage: "Apply the backup rotation limits afterwards"},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					st, err := openTracker(cmd)
+					if err != nil {
+						return err
+					}
+					defer st.Close()
+
+					path, err := st.BackupToDir(ctx, cmd.String("backup-dir"), cmd.Bool("backup-gzip"))
+					if err != nil {
+						return err
+					}
+					fmt.Printf("backup written to %s\n", path)
+
+					if !cmd.Bool("rotate") {
+						return nil
+					}
+
+					removed, err := st.RotateBackups(cmd.String("backup-dir"), cmd.Int("backup-keep-daily"), cmd.Int("backup-keep-weekly"))
+					for _, path := range removed {
+						fmt.Printf("removed %s\n", path)
+					}
+
+					return err
+				},
+			},
+			{
+				Name:  "restore",
+				Usage: "Replace the SQLite database with a backup (stop the tracker first)",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "from", Required: true, Usage: "Backup file to restore (.db or .db.gz)"},
+					&cli.StringFlag{Name: "confirm", Required: true, Usage: "File name of the database being replaced"},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if err := steamtracker.RestoreBackup(&steamtracker.RestoreBackupCommand{
+						From:        cmd.String("from"),
+						DatabaseDSN: cmd.String("database-dsn"),
+						Confirm:     cmd.String("confirm"),
+					}); err != nil {
+						return err
+					}
+
+					fmt.Printf("restored %s\n", cmd.String("from"))
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+func exportCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "Export a table as CSV, JSON Lines or Parquet",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "table", Required: true, Usage: "Table to export (" + strings.Join(steamtracker.ExportTableNames(), ", ") + ")"},
+			&cli.StringFlag{Name: "format", Value: steamtracker.ExportFormatJSONL, Usage: "Output format (csv, jsonl, parquet)"},
+			&cli.TimestampFlag{Name: "since", Usage: "Only rows created at or after this time (RFC 3339)", Config: cli.TimestampConfig{Layouts: []string{time.RFC3339}}},
+			&cli.TimestampFlag{Name: "until", Usage: "Only rows created before this time (RFC 3339)", Config: cli.TimestampConfig{Layouts: []string{time.RFC3339}}},
+			&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Output file (default: stdout)"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			query := steamtracker.ExportQuery{
+				Table:  cmd.String("table"),
+				Format: cmd.String("format"),
+			}
+			if cmd.IsSet("since") {
+				since := cmd.Timestamp("since")
+				query.Since = &since
+			}
+			if cmd.IsSet("until") {
+				until := cmd.Timestamp("until")
+				query.Until = &until
+			}
+			if err := query.Validate(); err != nil {
+				return fmt.Errorf("invalid export options: %w", err)
+			}
+
+			st, err := openTracker(cmd)
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+
+			if err := st.Migrate(); err != nil {
+				return fmt.Errorf("failed to migrate database: %w", err)
+			}
+
+			var out io.Writer = os.Stdout
+			if path := cmd.String("output"); path != "" {
+				f, err := os.Create(path)
+				if err != nil {
+					return fmt.Errorf("failed to create output file: %w", err)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			bw := bufio.NewWriter(out)
+			count, err := st.Export(ctx, bw, &query)
+			if err != nil {
+				return err
+			}
+			if err := bw.Flush(); err != nil {
+				return fmt.Errorf("failed to write output: %w", err)
+			}
+
+			fmt.Fprintf(os.Stderr, "exported %d rows from %s\n", count, query.Table)
+			return nil
+		},
+	}
+}
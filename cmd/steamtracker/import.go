@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+func importCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "import",
+		Usage: "Import rows exported by another tracker, or a whole tracker database",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "table", Usage: "Table to import into (" + strings.Join(steamtracker.ExportTableNames(), ", ") + ")"},
+			&cli.StringFlag{Name: "format", Usage: "Input format (csv, jsonl, parquet), guessed from the file extension by default"},
+			&cli.StringFlag{Name: "input", Aliases: []string{"i"}, Usage: "Input file (default: stdin)"},
+			&cli.StringFlag{Name: "from-db", Usage: "Import every table from another steamtracker SQLite file instead"},
+			&cli.BoolFlag{Name: "skip-rebuild", Usage: "Do not recompute derived data after importing"},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			st, err := openTracker(cmd)
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+
+			if err := st.Migrate(); err != nil {
+				return fmt.Errorf("failed to migrate database: %w", err)
+			}
+
+			var results []*steamtracker.ImportResult
+			if path := cmd.String("from-db"); path != "" {
+				results, err = st.ImportDatabase(ctx, path)
+			} else {
+				var result *steamtracker.ImportResult
+				result, err = importFile(ctx, st, cmd)
+				if result != nil {
+					results = append(results, result)
+				}
+			}
+			for _, r := range results {
+				fmt.Printf("%s: read %d, inserted %d (%d with new IDs), skipped %d existing\n", r.Table, r.Read, r.Inserted, r.Remapped, r.Skipped)
+			}
+			if err != nil {
+				return err
+			}
+
+			if cmd.Bool("skip-rebuild") {
+				return nil
+			}
+			if err := st.RebuildDerivedData(ctx, results); err != nil {
+				return err
+			}
+			fmt.Println("derived data rebuilt")
+
+			return nil
+		},
+	}
+}
+
+func importFile(ctx context.Context, st *steamtracker.SteamTracker, cmd *cli.Command) (*steamtracker.ImportResult, error) {
+	path := cmd.String("input")
+
+	importCmd := steamtracker.ImportCommand{
+		Table:  cmd.String("table"),
+		Format: cmd.String("format"),
+	}
+	if importCmd.Format == "" {
+		importCmd.Format = formatFromPath(path)
+	}
+	if err := importCmd.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid import options: %w", err)
+	}
+
+	var in io.Reader = bufio.NewReader(os.Stdin)
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open input file: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	return st.Import(ctx, in, &importCmd)
+}
+
+func formatFromPath(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".csv"):
+		return steamtracker.ExportFormatCSV
+	case strings.HasSuffix(path, ".parquet"):
+		return steamtracker.ExportFormatParquet
+	default:
+		return steamtracker.ExportFormatJSONL
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -19,37 +20,56 @@ func main() {
 		Usage:       "A command-line tool for tracking Steam player data",
 		Description: "This tool allows you to track and manage Steam player data using the Steam API.",
 		Flags: []cli.Flag{
-			&cli.StringFlag{Name: "database-dsn", Sources: cli.EnvVars("DATABASE_DSN")},
+			&cli.StringFlag{Name: "database-dsn", Usage: "Database DSN (sqlite://path, postgres://..., mysql://...)", Sources: cli.EnvVars("DATABASE_DSN")},
 			&cli.Int64Flag{Name: "snowflake-node-id", Sources: cli.EnvVars("SNOWFLAKE_NODE_ID")},
-			&cli.BoolFlag{Name: "reset-database", Sources: cli.EnvVars("RESET_DATABASE")},
 			&cli.StringFlag{Name: "http-port", Value: "8080", Sources: cli.EnvVars("HTTP_PORT")},
+			&cli.StringFlag{Name: "grpc-port", Usage: "Also serve the gRPC API on this port", Sources: cli.EnvVars("GRPC_PORT")},
 			&cli.StringFlag{Name: "log-level", Value: "info", Usage: "Set the logging level (debug, info, warn, error, fatal, panic)", Sources: cli.EnvVars("LOG_LEVEL")},
 			&cli.StringFlag{Name: "steam-api-key", Sources: cli.EnvVars("STEAM_API_KEY")},
+			&cli.StringFlag{Name: "steam-api-key-file", Usage: "Read the Steam API key from this file, re-read on SIGHUP", Sources: cli.EnvVars("STEAM_API_KEY_FILE")},
+			&cli.StringFlag{Name: "steam-api-key-ref", Usage: "Resolve the Steam API key from a reference (file://path or env://NAME), re-read on SIGHUP", Sources: cli.EnvVars("STEAM_API_KEY_REF")},
 			&cli.StringFlag{Name: "steam-id", Sources: cli.EnvVars("STEAM_ID")},
 			&cli.BoolFlag{Name: "disable-task", Sources: cli.EnvVars("DISABLE_TASK")},
 			&cli.IntFlag{Name: "max-task-retry-count", Value: 3, Sources: cli.EnvVars("MAX_TASK_RETRY_COUNT")},
 			&cli.IntFlag{Name: "task-interval", Value: 60, Sources: cli.EnvVars("TASK_INTERVAL")},
+			&cli.DurationFlag{Name: "retention-players-downsample-after", Usage: "Thin player snapshots older than this (e.g. 720h), 0 disables", Sources: cli.EnvVars("RETENTION_PLAYERS_DOWNSAMPLE_AFTER")},
+			&cli.DurationFlag{Name: "retention-players-downsample-interval", Value: time.Hour, Usage: "Keep one player snapshot per this interval once downsampled", Sources: cli.EnvVars("RETENTION_PLAYERS_DOWNSAMPLE_INTERVAL")},
+			&cli.DurationFlag{Name: "retention-players-max-age", Usage: "Delete player snapshots older than this, 0 keeps them", Sources: cli.EnvVars("RETENTION_PLAYERS_MAX_AGE")},
+			&cli.DurationFlag{Name: "retention-player-events-max-age", Usage: "Delete player events older than this, 0 keeps them", Sources: cli.EnvVars("RETENTION_PLAYER_EVENTS_MAX_AGE")},
+			&cli.DurationFlag{Name: "retention-audit-logs-max-age", Usage: "Delete audit logs older than this (e.g. 336h), 0 keeps them", Sources: cli.EnvVars("RETENTION_AUDIT_LOGS_MAX_AGE")},
+			&cli.IntFlag{Name: "retention-interval", Value: 3600, Usage: "Seconds between retention runs", Sources: cli.EnvVars("RETENTION_INTERVAL")},
+			&cli.IntFlag{Name: "retention-batch-size", Value: 1000, Usage: "Rows deleted per retention batch", Sources: cli.EnvVars("RETENTION_BATCH_SIZE")},
+			&cli.StringFlag{Name: "backup-dir", Value: "backups", Usage: "Directory for database backups", Sources: cli.EnvVars("BACKUP_DIR")},
+			&cli.IntFlag{Name: "backup-interval", Usage: "Seconds between scheduled backups, 0 disables them (SQLite only)", Sources: cli.EnvVars("BACKUP_INTERVAL")},
+			&cli.IntFlag{Name: "backup-keep-daily", Value: 7, Usage: "Number of daily backups to keep", Sources: cli.EnvVars("BACKUP_KEEP_DAILY")},
+			&cli.IntFlag{Name: "backup-keep-weekly", Value: 4, Usage: "Number of weekly backups to keep", Sources: cli.EnvVars("BACKUP_KEEP_WEEKLY")},
+			&cli.BoolFlag{Name: "backup-gzip", Usage: "Compress backups with gzip", Sources: cli.EnvVars("BACKUP_GZIP")},
+			&cli.IntFlag{Name: "audit-log-buffer-size", Value: 1024, Usage: "Log lines buffered before the audit log writer applies its drop policy", Sources: cli.EnvVars("AUDIT_LOG_BUFFER_SIZE")},
+			&cli.IntFlag{Name: "audit-log-batch-size", Value: 100, Usage: "Audit logs inserted per batch", Sources: cli.EnvVars("AUDIT_LOG_BATCH_SIZE")},
+			&cli.IntFlag{Name: "audit-log-flush-interval", Value: 1000, Usage: "Milliseconds between audit log flushes", Sources: cli.EnvVars("AUDIT_LOG_FLUSH_INTERVAL")},
+			&cli.StringFlag{Name: "audit-log-drop-policy", Value: "block", Usage: "What to do when the audit log buffer is full (block or drop)", Sources: cli.EnvVars("AUDIT_LOG_DROP_POLICY")},
+			&cli.IntFlag{Name: "event-stream-heartbeat", Value: 15000, Usage: "Milliseconds between heartbeats on an idle event stream", Sources: cli.EnvVars("EVENT_STREAM_HEARTBEAT")},
+			&cli.StringSliceFlag{Name: "websocket-origin", Usage: "Also accept WebSocket connections from this origin host, e.g. dashboard.example.com or *.example.com (repeatable)", Sources: cli.EnvVars("WEBSOCKET_ORIGINS")},
+			&cli.BoolFlag{Name: "disable-auth", Usage: "Serve every endpoint without an API token", Sources: cli.EnvVars("DISABLE_AUTH")},
+			&cli.StringFlag{Name: "dashboard-username", Usage: "Protect the dashboard with Basic authentication as this user", Sources: cli.EnvVars("DASHBOARD_USERNAME")},
+			&cli.StringFlag{Name: "dashboard-password", Usage: "Password of the dashboard user", Sources: cli.EnvVars("DASHBOARD_PASSWORD")},
 		},
-		Action: func(ctx context.Context, cmd *cli.Command) error {
+		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
 			level, err := zerolog.ParseLevel(cmd.String("log-level"))
 			if err != nil {
-				return fmt.Errorf("invalid log level: %w", err)
+				return ctx, fmt.Errorf("invalid log level: %w", err)
 			}
 			log.Logger = log.Level(level)
 
-			cfg := &steamtracker.Config{
-				DatabaseDSN:       cmd.String("database-dsn"),
-				SnowflakeNodeID:   cmd.Int64("snowflake-node-id"),
-				ResetDatabase:     cmd.Bool("reset-database"),
-				HTTPPort:          cmd.String("http-port"),
-				SteamAPIKey:       cmd.String("steam-api-key"),
-				SteamID:           cmd.String("steam-id"),
-				DisableTask:       cmd.Bool("disable-task"),
-				MaxTaskRetryCount: cmd.Int("max-task-retry-count"),
-				TaskInterval:      cmd.Int("task-interval"),
-				LogLevel:          level,
+			if _, ok := os.LookupEnv("RESET_DATABASE"); ok {
+				log.Warn().Msg("RESET_DATABASE is no longer supported and is ignored, use `steamtracker db reset` instead")
 			}
 
+			return ctx, nil
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			cfg := configFromCommand(cmd)
+
 			log.Info().Msg("Creating SteamTracker instance")
 			st, err := steamtracker.New(cfg)
 			if err != nil {
@@ -63,6 +83,16 @@ func main() {
 
 			return nil
 		},
+		Commands: []*cli.Command{
+			migrateCommand(),
+			dbCommand(),
+			retentionCommand(),
+			rollupCommand(),
+			exportCommand(),
+			importCommand(),
+			auditCommand(),
+			tokenCommand(),
+		},
 	}
 
 	if err := cmd.Run(context.Background(), os.Args); err != nil {
@@ -70,3 +100,87 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+func configFromCommand(cmd *cli.Command) *steamtracker.Config {
+	return &steamtracker.Config{
+		DatabaseDSN:       cmd.String("database-dsn"),
+		SnowflakeNodeID:   cmd.Int64("snowflake-node-id"),
+		HTTPPort:          cmd.String("http-port"),
+		GRPCPort:          cmd.String("grpc-port"),
+		SteamAPIKey:       cmd.String("steam-api-key"),
+		SteamAPIKeyRef:    steamAPIKeyRefFromCommand(cmd),
+		SteamID:           cmd.String("steam-id"),
+		DisableTask:       cmd.Bool("disable-task"),
+		MaxTaskRetryCount: cmd.Int("max-task-retry-count"),
+		TaskInterval:      cmd.Int("task-interval"),
+		LogLevel:          log.Logger.GetLevel(),
+
+		RetentionPolicies:  retentionPoliciesFromCommand(cmd),
+		RetentionInterval:  cmd.Int("retention-interval"),
+		RetentionBatchSize: cmd.Int("retention-batch-size"),
+
+		BackupDir:        cmd.String("backup-dir"),
+		BackupInterval:   cmd.Int("backup-interval"),
+		BackupKeepDaily:  cmd.Int("backup-keep-daily"),
+		BackupKeepWeekly: cmd.Int("backup-keep-weekly"),
+		BackupGzip:       cmd.Bool("backup-gzip"),
+
+		AuditLogBufferSize:    cmd.Int("audit-log-buffer-size"),
+		AuditLogBatchSize:     cmd.Int("audit-log-batch-size"),
+		AuditLogFlushInterval: cmd.Int("audit-log-flush-interval"),
+		AuditLogDropPolicy:    cmd.String("audit-log-drop-policy"),
+
+		EventStreamHeartbeat: cmd.Int("event-stream-heartbeat"),
+
+		WebSocketOrigins: cmd.StringSlice("websocket-origin"),
+
+		DisableAuth:       cmd.Bool("disable-auth"),
+		DashboardUsername: cmd.String("dashboard-username"),
+		DashboardPassword: cmd.String("dashboard-password"),
+	}
+}
+
+func steamAPIKeyRefFromCommand(cmd *cli.Command) string {
+	if path := cmd.String("steam-api-key-file"); path != "" {
+		return steamtracker.FileSecretRef(path)
+	}
+
+	return cmd.String("steam-api-key-ref")
+}
+
+func retentionPoliciesFromCommand(cmd *cli.Command) []steamtracker.RetentionPolicy {
+	policies := make([]steamtracker.RetentionPolicy, 0)
+
+	if cmd.Duration("retention-players-downsample-after") > 0 || cmd.Duration("retention-players-max-age") > 0 {
+		policy := steamtracker.RetentionPolicy{
+			Table:  "players",
+			MaxAge: cmd.Duration("retention-players-max-age"),
+		}
+		if after := cmd.Duration("retention-players-downsample-after"); after > 0 {
+			policy.DownsampleAfter = after
+			policy.DownsampleInterval = cmd.Duration("retention-players-downsample-interval")
+		}
+		policies = append(policies, policy)
+	}
+
+	if maxAge := cmd.Duration("retention-player-events-max-age"); maxAge > 0 {
+		policies = append(policies, steamtracker.RetentionPolicy{Table: "player_events", MaxAge: maxAge})
+	}
+
+	if maxAge := cmd.Duration("retention-audit-logs-max-age"); maxAge > 0 {
+		policies = append(policies, steamtracker.RetentionPolicy{Table: "audit_logs", MaxAge: maxAge})
+	}
+
+	return policies
+}
+
+// openTracker opens the database for maintenance subcommands. It does not
+// start the HTTP server or the polling task.
+func openTracker(cmd *cli.Command) (*steamtracker.SteamTracker, error) {
+	st, err := steamtracker.Open(configFromCommand(cmd))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return st, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+func migrateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "migrate",
+		Usage: "Manage versioned database schema migrations",
+		Commands: []*cli.Command{
+			{
+				Name:  "up",
+				Usage: "Apply pending migrations",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "to", Usage: "Stop after applying this version (default: latest)"},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					st, err := openTracker(cmd)
+					if err != nil {
+						return err
+					}
+					defer st.Close()
+
+					applied, err := st.MigrateUp(cmd.Int("to"))
+					for _, m := range applied {
+						fmt.Printf("applied %d %s\n", m.Version, m.Name)
+					}
+					if err != nil {
+						return err
+					}
+					if len(applied) == 0 {
+						fmt.Println("database is up to date")
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:  "down",
+				Usage: "Revert the most recently applied migrations",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "steps", Value: 1, Usage: "Number of migrations to revert"},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					st, err := openTracker(cmd)
+					if err != nil {
+						return err
+					}
+					defer st.Close()
+
+					reverted, err := st.MigrateDown(cmd.Int("steps"))
+					for _, m := range reverted {
+						fmt.Printf("reverted %d %s\n", m.Version, m.Name)
+					}
+
+					return err
+				},
+			},
+			{
+				Name:  "status",
+				Usage: "Show applied and pending migrations",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					st, err := openTracker(cmd)
+					if err != nil {
+						return err
+					}
+					defer st.Close()
+
+					statuses, err := st.MigrationStatus()
+					if err != nil {
+						return err
+					}
+
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+					for _, s := range statuses {
+						appliedAt := "pending"
+						if s.AppliedAt != nil {
+							appliedAt = s.AppliedAt.Format(time.RFC3339)
+						}
+						if s.Version > steamtracker.LatestSchemaVersion() {
+							appliedAt += " (unknown to this binary)"
+						}
+						fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+					}
+
+					return w.Flush()
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v3"
+)
+
+func retentionCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "retention",
+		Usage: "Apply the configured retention policies",
+		Commands: []*cli.Command{
+			{
+				Name:  "run",
+				Usage: "Prune and downsample rows according to the retention flags",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "dry-run", Usage: "Only report what would be pruned"},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					cfg := configFromCommand(cmd)
+					if err := cfg.ValidateRetention(); err != nil {
+						return fmt.Errorf("invalid retention configuration: %w", err)
+					}
+					if len(cfg.RetentionPolicies) == 0 {
+						fmt.Println("no retention policies configured")
+						return nil
+					}
+
+					st, err := openTracker(cmd)
+					if err != nil {
+						return err
+					}
+					defer st.Close()
+
+					if err := st.Migrate(); err != nil {
+						return fmt.Errorf("failed to migrate database: %w", err)
+					}
+
+					report, err := st.ApplyRetention(ctx, cmd.Bool("dry-run"))
+
+					verb := "pruned"
+					if report.DryRun {
+						verb = "would prune"
+					}
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					fmt.Fprintf(w, "TABLE\tEXPIRED (%s)\tDOWNSAMPLED (%s)\n", verb, verb)
+					for _, t := range report.Tables {
+						fmt.Fprintf(w, "%s\t%d\t%d\n", t.Table, t.Expired, t.Downsampled)
+					}
+					if flushErr := w.Flush(); err == nil {
+						err = flushErr
+					}
+
+					return err
+				},
+			},
+		},
+	}
+}
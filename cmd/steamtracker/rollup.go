@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+func rollupCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "rollup",
+		Usage: "Manage the hourly and daily presence rollup tables",
+		Commands: []*cli.Command{
+			{
+				Name:  "rebuild",
+				Usage: "Recompute rollups from the stored player snapshots, keeping those whose snapshots retention removed",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					st, err := openTracker(cmd)
+					if err != nil {
+						return err
+					}
+					defer st.Close()
+
+					if err := st.Migrate(); err != nil {
+						return fmt.Errorf("failed to migrate database: %w", err)
+					}
+
+					result, err := st.RebuildPlayerRollups(ctx, &steamtracker.RebuildPlayerRollupsCommand{})
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("rebuilt %d hourly and %d daily rollups\n", result.Hourly, result.Daily)
+					if result.KeptBefore != nil {
+						fmt.Printf("kept rollups before %s, which the stored snapshots no longer fully cover\n", result.KeptBefore.Format(time.DateOnly))
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+func tokenCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "token",
+		Usage: "Manage API tokens",
+		Commands: []*cli.Command{
+			{
+				Name:  "create",
+				Usage: "Create an API token and print it; it cannot be shown again",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "name", Required: true, Usage: "What the token is for"},
+					&cli.StringSliceFlag{Name: "scope", Required: true, Usage: fmt.Sprintf("Scope to grant: %s, %s or %s (repeatable)", steamtracker.ScopeReadPlayers, steamtracker.ScopeReadAudit, steamtracker.ScopeAdmin)},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					st, err := openTracker(cmd)
+					if err != nil {
+						return err
+					}
+					defer st.Close()
+
+					if err := st.Migrate(); err != nil {
+						return fmt.Errorf("failed to migrate database: %w", err)
+					}
+
+					apiToken, token, err := st.CreateAPIToken(ctx, &steamtracker.CreateAPITokenCommand{
+						Name:   cmd.String("name"),
+						Scopes: cmd.StringSlice("scope"),
+					})
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("created API token %d with scopes %s\n", apiToken.ID, strings.Join(apiToken.Scopes, ", "))
+					fmt.Println(token)
+					return nil
+				},
+			},
+			{
+				Name:  "revoke",
+				Usage: "Revoke an API token",
+				Flags: []cli.Flag{
+					&cli.Int64Flag{Name: "id", Required: true, Usage: "ID of the token, as shown by token list"},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					st, err := openTracker(cmd)
+					if err != nil {
+						return err
+					}
+					defer st.Close()
+
+					if err := st.Migrate(); err != nil {
+						return fmt.Errorf("failed to migrate database: %w", err)
+					}
+
+					if err := st.RevokeAPIToken(ctx, cmd.Int64("id")); err != nil {
+						return err
+					}
+
+					fmt.Printf("revoked API token %d\n", cmd.Int64("id"))
+					return nil
+				},
+			},
+			{
+				Name:  "list",
+				Usage: "List API tokens, revoked ones included",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					st, err := openTracker(cmd)
+					if err != nil {
+						return err
+					}
+					defer st.Close()
+
+					if err := st.Migrate(); err != nil {
+						return fmt.Errorf("failed to migrate database: %w", err)
+					}
+
+					tokens, err := st.ListAPITokens(ctx)
+					if err != nil {
+						return err
+					}
+
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tCREATED AT\tLAST USED AT\tREVOKED AT")
+					for _, t := range tokens {
+						fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+							t.ID, t.Name, t.Prefix, strings.Join(t.Scopes, ","),
+							t.CreatedAt.Format(time.RFC3339), formatOptionalTime(t.LastUsedAt, "never"), formatOptionalTime(t.RevokedAt, "-"))
+					}
+
+					return w.Flush()
+				},
+			},
+		},
+	}
+}
+
+func formatOptionalTime(t *time.Time, none string) string {
+	if t == nil {
+		return none
+	}
+	return t.Format(time.RFC3339)
+}
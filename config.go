@@ -2,6 +2,10 @@ package steamtracker
 
 import (
 	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
 )
@@ -9,32 +13,76 @@ import (
 type Config struct {
 	DatabaseDSN     string `json:"database_dsn"`
 	SnowflakeNodeID int64  `json:"snowflake_node_id"`
-	ResetDatabase   bool   `json:"reset_database"`
 	HTTPPort        string `json:"http_port"`
+	// GRPCPort serves SteamTrackerService next to the HTTP server, if set.
+	GRPCPort string `json:"grpc_port"`
 
-	SteamAPIKey string `json:"steam_api_key"`
-	SteamID     string `json:"steam_id"`
+	SteamAPIKey    string `json:"steam_api_key"`
+	SteamAPIKeyRef string `json:"steam_api_key_ref"` // e.g. file:///run/secrets/steam_api_key, used instead of SteamAPIKey
+	SteamID        string `json:"steam_id"`
+	// HTTPClient sends the Steam API requests, a client with a 10 second
+	// timeout if nil.
+	HTTPClient *http.Client `json:"-"`
+	// Now stamps new snapshots, time.Now if nil.
+	Now func() time.Time `json:"-"`
 
 	MaxTaskRetryCount int `json:"max_task_retry_count"`
 	TaskInterval      int `json:"task_interval"` // in seconds
 
 	DisableTask bool          `json:"disable_task"`
 	LogLevel    zerolog.Level `json:"log_level"`
+
+	RetentionPolicies  []RetentionPolicy `json:"retention_policies"`
+	RetentionInterval  int               `json:"retention_interval"` // in seconds
+	RetentionBatchSize int               `json:"retention_batch_size"`
+
+	BackupDir        string `json:"backup_dir"`
+	BackupInterval   int    `json:"backup_interval"` // in seconds, 0 disables scheduled backups
+	BackupKeepDaily  int    `json:"backup_keep_daily"`
+	BackupKeepWeekly int    `json:"backup_keep_weekly"`
+	BackupGzip       bool   `json:"backup_gzip"`
+
+	// The audit log settings fall back to a buffer of 1024 lines, batches of
+	// 100, a flush every second and the "block" policy when left zero.
+	AuditLogBufferSize    int    `json:"audit_log_buffer_size"`
+	AuditLogBatchSize     int    `json:"audit_log_batch_size"`
+	AuditLogFlushInterval int    `json:"audit_log_flush_interval"` // in milliseconds
+	AuditLogDropPolicy    string `json:"audit_log_drop_policy"`    // "block" or "drop"
+
+	// EventStreamHeartbeat is how often, in milliseconds, an idle event
+	// stream sends a comment. 0 uses the default of 15 seconds.
+	EventStreamHeartbeat int `json:"event_stream_heartbeat"`
+
+	// WebSocketOrigins lists the origin hosts, besides the server's own, that
+	// may open /api/v1/ws. Patterns are matched with path.Match, for example
+	// *.example.com.
+	WebSocketOrigins []string `json:"websocket_origins"`
+
+	// DisableAuth serves every endpoint without credentials, as before API
+	// tokens existed.
+	DisableAuth bool `json:"disable_auth"`
+	// DashboardUsername and DashboardPassword protect the HTML dashboard with
+	// Basic authentication, and let it read players through the API.
+	DashboardUsername string `json:"dashboard_username"`
+	DashboardPassword string `json:"dashboard_password"`
 }
 
 func (c *Config) Validate() error {
-	if c.DatabaseDSN == "" {
-		return fmt.Errorf("database DSN cannot be empty")
-	}
-	if c.SnowflakeNodeID < 0 || c.SnowflakeNodeID > 1023 {
-		return fmt.Errorf("invalid snowflake node ID: %d, must be between 0 and 1023", c.SnowflakeNodeID)
+	if err := c.ValidateDatabase(); err != nil {
+		return err
 	}
 	if c.HTTPPort == "" {
 		return fmt.Errorf("HTTP port cannot be empty")
 	}
-	if c.SteamAPIKey == "" {
+	if c.GRPCPort != "" && c.GRPCPort == c.HTTPPort {
+		return fmt.Errorf("gRPC port must differ from HTTP port")
+	}
+	if c.SteamAPIKey == "" && c.SteamAPIKeyRef == "" {
 		return fmt.Errorf("Steam API key cannot be empty")
 	}
+	if c.SteamAPIKey != "" && c.SteamAPIKeyRef != "" {
+		return fmt.Errorf("Steam API key and Steam API key reference are mutually exclusive")
+	}
 	if c.SteamID == "" {
 		return fmt.Errorf("Steam ID cannot be empty")
 	}
@@ -44,6 +92,92 @@ func (c *Config) Validate() error {
 	if c.TaskInterval < 1 {
 		return fmt.Errorf("task interval must be at least 1 second")
 	}
+	if err := c.ValidateRetention(); err != nil {
+		return err
+	}
+	if c.BackupInterval < 0 {
+		return fmt.Errorf("backup interval cannot be negative")
+	}
+	if c.BackupInterval > 0 && c.BackupDir == "" {
+		return fmt.Errorf("backup directory cannot be empty when scheduled backups are enabled")
+	}
+	if c.BackupInterval > 0 {
+		dialector, err := openDialector(c.DatabaseDSN)
+		if err != nil {
+			return err
+		}
+		if dialector.Name() != DialectSQLite {
+			return fmt.Errorf("scheduled backups are only supported for SQLite databases")
+		}
+	}
+	if c.BackupKeepDaily < 0 || c.BackupKeepWeekly < 0 {
+		return fmt.Errorf("backup retention counts cannot be negative")
+	}
+	if c.AuditLogBufferSize < 0 {
+		return fmt.Errorf("audit log buffer size cannot be negative")
+	}
+	if c.AuditLogBatchSize < 0 {
+		return fmt.Errorf("audit log batch size cannot be negative")
+	}
+	if c.AuditLogFlushInterval < 0 {
+		return fmt.Errorf("audit log flush interval cannot be negative")
+	}
+	switch c.AuditLogDropPolicy {
+	case "", AuditLogDropPolicyBlock, AuditLogDropPolicyDrop:
+	default:
+		return fmt.Errorf("invalid audit log drop policy: %q, must be %q or %q", c.AuditLogDropPolicy, AuditLogDropPolicyBlock, AuditLogDropPolicyDrop)
+	}
+	if c.EventStreamHeartbeat < 0 {
+		return fmt.Errorf("event stream heartbeat cannot be negative")
+	}
+	for _, pattern := range c.WebSocketOrigins {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid WebSocket origin pattern: %q", pattern)
+		}
+	}
+	if (c.DashboardUsername == "") != (c.DashboardPassword == "") {
+		return fmt.Errorf("dashboard username and password must be set together")
+	}
+	if strings.Contains(c.DashboardUsername, ":") {
+		return fmt.Errorf("dashboard username cannot contain a colon")
+	}
+
+	return nil
+}
+
+// ValidateDatabase checks only the settings needed to open the database.
+func (c *Config) ValidateDatabase() error {
+	if c.DatabaseDSN == "" {
+		return fmt.Errorf("database DSN cannot be empty")
+	}
+	if c.SnowflakeNodeID < 0 || c.SnowflakeNodeID > 1023 {
+		return fmt.Errorf("invalid snowflake node ID: %d, must be between 0 and 1023", c.SnowflakeNodeID)
+	}
+
+	return nil
+}
+
+func (c *Config) ValidateRetention() error {
+	if len(c.RetentionPolicies) == 0 {
+		return nil
+	}
+	if c.RetentionInterval < 1 {
+		return fmt.Errorf("retention interval must be at least 1 second")
+	}
+	if c.RetentionBatchSize < 1 {
+		return fmt.Errorf("retention batch size must be at least 1")
+	}
+
+	seen := make(map[string]bool, len(c.RetentionPolicies))
+	for _, policy := range c.RetentionPolicies {
+		if err := policy.Validate(); err != nil {
+			return err
+		}
+		if seen[policy.Table] {
+			return fmt.Errorf("duplicate retention policy for table %s", policy.Table)
+		}
+		seen[policy.Table] = true
+	}
 
 	return nil
 }
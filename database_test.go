@@ -0,0 +1,586 @@
+package steamtracker_test
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+func TestMain(m *testing.M) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	os.Exit(m.Run())
+}
+
+// testDSNs returns a fresh SQLite database plus the backend named by
+// STEAMTRACKER_TEST_DSN, if set (e.g. postgres://... or mysql://...).
+func testDSNs(t *testing.T) map[string]string {
+	t.Helper()
+
+	dsns := map[string]string{
+		"sqlite": "sqlite://" + filepath.Join(t.TempDir(), "steamtracker.db"),
+	}
+	if dsn := os.Getenv("STEAMTRACKER_TEST_DSN"); dsn != "" {
+		scheme, _, _ := strings.Cut(dsn, "://")
+		dsns[scheme] = dsn
+	}
+
+	return dsns
+}
+
+func openTestTracker(t *testing.T, dsn string) *steamtracker.SteamTracker {
+	t.Helper()
+
+	st, err := steamtracker.Open(&steamtracker.Config{DatabaseDSN: dsn, DisableAuth: true})
+	if err != nil {
+		t.Fatalf("Failed to open tracker: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	if err := st.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	return st
+}
+
+// uniqueSteamID keeps rows from different runs apart on shared databases.
+func uniqueSteamID() steamtracker.SteamID {
+	return steamtracker.SteamID(time.Now().UnixNano())
+}
+
+func TestOpenUnsupportedScheme(t *testing.T) {
+	if _, err := steamtracker.Open(&steamtracker.Config{DatabaseDSN: "redis://localhost"}); err == nil {
+		t.Fatal("Expected an error for an unsupported database scheme")
+	}
+}
+
+func TestSearchPlayersAcrossBackends(t *testing.T) {
+	for name, dsn := range testDSNs(t) {
+		t.Run(name, func(t *testing.T) {
+			st := openTestTracker(t, dsn)
+			steamID := uniqueSteamID()
+
+			for _, state := range []steamtracker.PersonaState{steamtracker.PersonaStateOnline, steamtracker.PersonaStateAway, steamtracker.PersonaStateOffline} {
+				if err := st.AddPlayer(&steamtracker.Player{SteamID: steamID, PersonaName: "Test Player", PersonaState: state}); err != nil {
+					t.Fatalf("Failed to add player: %v", err)
+				}
+			}
+
+			order := "desc"
+			query := steamtracker.SearchPlayersQuery{SteamID: &steamID, Limit: 2}
+			query.SortBy.CreatedAt = &order
+			if err := query.Validate(); err != nil {
+				t.Fatalf("Invalid query: %v", err)
+			}
+
+			result, err := st.SearchPlayers(t.Context(), &query)
+			if err != nil {
+				t.Fatalf("Failed to search players: %v", err)
+			}
+			if result.TotalCount == nil || *result.TotalCount != 3 {
+				t.Errorf("Expected total count 3, got %v", result.TotalCount)
+			}
+			if len(result.Players) != 2 {
+				t.Fatalf("Expected 2 players, got %d", len(result.Players))
+			}
+			if result.Players[0].PersonaState != steamtracker.PersonaStateOffline {
+				t.Errorf("Expected newest player to be Offline, got %s", result.Players[0].PersonaState)
+			}
+		})
+	}
+}
+
+func TestPlayerEventsAcrossBackends(t *testing.T) {
+	for name, dsn := range testDSNs(t) {
+		t.Run(name, func(t *testing.T) {
+			st := openTestTracker(t, dsn)
+			steamID := uniqueSteamID()
+
+			latest, err := st.GetLatestPlayerEvent(&steamtracker.GetLatestPlayerEventQuery{SteamID: steamID})
+			if err != nil {
+				t.Fatalf("Failed to get latest player event: %v", err)
+			}
+			if latest.PersonaState != steamtracker.PersonaStateUnknown {
+				t.Errorf("Expected Unknown state without events, got %s", latest.PersonaState)
+			}
+
+			for _, state := range []steamtracker.PersonaState{steamtracker.PersonaStateOnline, steamtracker.PersonaStateBusy} {
+				if _, err := st.CreatePlayerEvent(&steamtracker.CreatePlayerEventCommand{SteamID: steamID, PersonaName: "Test Player", PersonaState: state}); err != nil {
+					t.Fatalf("Failed to create player event: %v", err)
+				}
+			}
+
+			latest, err = st.GetLatestPlayerEvent(&steamtracker.GetLatestPlayerEventQuery{SteamID: steamID})
+			if err != nil {
+				t.Fatalf("Failed to get latest player event: %v", err)
+			}
+			if latest.PersonaState != steamtracker.PersonaStateBusy {
+				t.Errorf("Expected latest state Busy, got %s", latest.PersonaState)
+			}
+
+			query := steamtracker.SearchPlayerEventsQuery{SteamID: &steamID}
+			if err := query.Validate(); err != nil {
+				t.Fatalf("Invalid query: %v", err)
+			}
+
+			result, err := st.SearchPlayerEvents(&query)
+			if err != nil {
+				t.Fatalf("Failed to search player events: %v", err)
+			}
+			if result.TotalCount == nil || *result.TotalCount != 2 {
+				t.Errorf("Expected total count 2, got %v", result.TotalCount)
+			}
+		})
+	}
+}
+
+func TestSearchPlayersCursorAcrossBackends(t *testing.T) {
+	for name, dsn := range testDSNs(t) {
+		t.Run(name, func(t *testing.T) {
+			st := openTestTracker(t, dsn)
+			steamID := uniqueSteamID()
+
+			for range 5 {
+				if err := st.AddPlayer(&steamtracker.Player{SteamID: steamID, PersonaName: "Test Player"}); err != nil {
+					t.Fatalf("Failed to add player: %v", err)
+				}
+			}
+
+			seen := make(map[int64]bool)
+			cursor := ""
+			for pages := 1; ; pages++ {
+				query := steamtracker.SearchPlayersQuery{SteamID: &steamID, Limit: 2, Cursor: &cursor}
+				if err := query.Validate(); err != nil {
+					t.Fatalf("Invalid query: %v", err)
+				}
+
+				result, err := st.SearchPlayers(t.Context(), &query)
+				if err != nil {
+					t.Fatalf("Failed to search players: %v", err)
+				}
+				if result.TotalCount != nil {
+					t.Errorf("Expected no total count by default, got %d", *result.TotalCount)
+				}
+
+				for _, player := range result.Players {
+					if seen[player.ID] {
+						t.Fatalf("Player %d returned twice", player.ID)
+					}
+					seen[player.ID] = true
+				}
+
+				if result.NextCursor == "" {
+					if pages != 3 {
+						t.Errorf("Expected 3 pages, got %d", pages)
+					}
+					break
+				}
+				cursor = result.NextCursor
+			}
+
+			if len(seen) != 5 {
+				t.Errorf("Expected 5 players, got %d", len(seen))
+			}
+
+			invalid := "not-a-cursor"
+			query := steamtracker.SearchPlayersQuery{Cursor: &invalid}
+			if err := query.Validate(); err == nil {
+				t.Error("Expected an error for an invalid cursor")
+			}
+		})
+	}
+}
+
+// pageThrough follows next cursors from the first page to the last and
+// returns the IDs in the order they were returned and the number of pages.
+func pageThrough(t *testing.T, page func(cursor string) (ids []int64, next string)) ([]int64, int) {
+	t.Helper()
+
+	ids := make([]int64, 0)
+	seen := make(map[int64]bool)
+	cursor := ""
+	for pages := 1; ; pages++ {
+		pageIDs, next := page(cursor)
+		for _, id := range pageIDs {
+			if seen[id] {
+				t.Fatalf("Row %d returned twice", id)
+			}
+			seen[id] = true
+		}
+		ids = append(ids, pageIDs...)
+
+		if next == "" {
+			return ids, pages
+		}
+		cursor = next
+	}
+}
+
+func TestSearchPlayerEventsCursorAcrossBackends(t *testing.T) {
+	for name, dsn := range testDSNs(t) {
+		t.Run(name, func(t *testing.T) {
+			st := openTestTracker(t, dsn)
+			steamID := uniqueSteamID()
+
+			// Every event shares created_at, so only the ID orders them and
+			// every page boundary falls inside the tie.
+			at := time.Now().UTC().Truncate(time.Second)
+			rows := make([]map[string]any, 0, 5)
+			for state := range 5 {
+				rows = append(rows, eventRow(steamID, steamtracker.PlayerEventTypePersonaState, steamtracker.PersonaState(state), at))
+			}
+			importJSONL(t, st, "player_events", rows...)
+
+			ids, pages := pageThrough(t, func(cursor string) ([]int64, string) {
+				query := steamtracker.SearchPlayerEventsQuery{SteamID: &steamID, Limit: 2, Cursor: &cursor}
+				if err := query.Validate(); err != nil {
+					t.Fatalf("Invalid query: %v", err)
+				}
+				result, err := st.SearchPlayerEvents(&query)
+				if err != nil {
+					t.Fatalf("Failed to search player events: %v", err)
+				}
+
+				ids := make([]int64, 0, len(result.PlayerEvents))
+				for _, playerEvent := range result.PlayerEvents {
+					ids = append(ids, playerEvent.ID)
+				}
+				return ids, result.NextCursor
+			})
+			if len(ids) != 5 || pages != 3 {
+				t.Errorf("Expected 5 events on 3 pages, got %d on %d", len(ids), pages)
+			}
+			if !slices.IsSortedFunc(ids, func(a, b int64) int { return cmp.Compare(b, a) }) {
+				t.Errorf("Expected tied events newest ID first, got %v", ids)
+			}
+
+			invalid := "not-a-cursor"
+			query := steamtracker.SearchPlayerEventsQuery{Cursor: &invalid}
+			if err := query.Validate(); err == nil {
+				t.Error("Expected an error for an invalid cursor")
+			}
+		})
+	}
+}
+
+func TestSearchAuditLogsCursorAcrossBackends(t *testing.T) {
+	for name, dsn := range testDSNs(t) {
+		t.Run(name, func(t *testing.T) {
+			st := openTestTracker(t, dsn)
+			action := fmt.Sprintf("cursor_test_%d", uniqueSteamID())
+
+			// Every audit log shares created_at, so only the ID orders them.
+			at := time.Now().UTC().Truncate(time.Second)
+			cmds := make([]*steamtracker.CreateAuditLogCommand, 0, 5)
+			for i := range 5 {
+				cmds = append(cmds, &steamtracker.CreateAuditLogCommand{
+					Raw:       steamtracker.JSON(fmt.Sprintf(`{"level":"info","message":"line %d","action":%q}`, i, action)),
+					CreatedAt: at,
+				})
+			}
+			if err := st.CreateAuditLogs(cmds); err != nil {
+				t.Fatalf("Failed to create audit logs: %v", err)
+			}
+
+			ids, pages := pageThrough(t, func(cursor string) ([]int64, string) {
+				query := steamtracker.SearchAuditLogsQuery{Action: &action, Limit: 2, Cursor: &cursor}
+				if err := query.Validate(); err != nil {
+					t.Fatalf("Invalid query: %v", err)
+				}
+				result, err := st.SearchAuditLogs(&query)
+				if err != nil {
+					t.Fatalf("Failed to search audit logs: %v", err)
+				}
+
+				ids := make([]int64, 0, len(result.AuditLogs))
+				for _, auditLog := range result.AuditLogs {
+					ids = append(ids, auditLog.ID)
+				}
+				return ids, result.NextCursor
+			})
+			if len(ids) != 5 || pages != 3 {
+				t.Errorf("Expected 5 audit logs on 3 pages, got %d on %d", len(ids), pages)
+			}
+			if !slices.IsSortedFunc(ids, func(a, b int64) int { return cmp.Compare(b, a) }) {
+				t.Errorf("Expected tied audit logs newest ID first, got %v", ids)
+			}
+
+			invalid := "not-a-cursor"
+			query := steamtracker.SearchAuditLogsQuery{Cursor: &invalid}
+			if err := query.Validate(); err == nil {
+				t.Error("Expected an error for an invalid cursor")
+			}
+		})
+	}
+}
+
+func TestSearchAuditLogsCursorIncludesLateRows(t *testing.T) {
+	for name, dsn := range testDSNs(t) {
+		t.Run(name, func(t *testing.T) {
+			st := openTestTracker(t, dsn)
+			action := fmt.Sprintf("late_cursor_test_%d", uniqueSteamID())
+			createLines := func(at time.Time, n int) {
+				t.Helper()
+				cmds := make([]*steamtracker.CreateAuditLogCommand, 0, n)
+				for i := range n {
+					cmds = append(cmds, &steamtracker.CreateAuditLogCommand{
+						Raw:       steamtracker.JSON(fmt.Sprintf(`{"level":"info","message":"line %d","action":%q}`, i, action)),
+						CreatedAt: at,
+					})
+				}
+				if err := st.CreateAuditLogs(cmds); err != nil {
+					t.Fatalf("Failed to create audit logs: %v", err)
+				}
+			}
+
+			now := time.Now().UTC().Truncate(time.Second)
+			createLines(now, 4)
+
+			asc := "asc"
+			late := false
+			ids, _ := pageThrough(t, func(cursor string) ([]int64, string) {
+				query := steamtracker.SearchAuditLogsQuery{Action: &action, Limit: 2, Cursor: &cursor}
+				query.SortBy.ID = &asc
+				if err := query.Validate(); err != nil {
+					t.Fatalf("Invalid query: %v", err)
+				}
+				result, err := st.SearchAuditLogs(&query)
+				if err != nil {
+					t.Fatalf("Failed to search audit logs: %v", err)
+				}
+
+				// A line logged before the first page but flushed after it
+				// was read must still turn up on a later page.
+				if !late {
+					createLines(now.Add(-time.Hour), 1)
+					late = true
+				}
+
+				ids := make([]int64, 0, len(result.AuditLogs))
+				for _, auditLog := range result.AuditLogs {
+					ids = append(ids, auditLog.ID)
+				}
+				return ids, result.NextCursor
+			})
+			if len(ids) != 5 {
+				t.Errorf("Expected 5 audit logs including the late one, got %v", ids)
+			}
+			if !slices.IsSorted(ids) {
+				t.Errorf("Expected audit logs oldest ID first, got %v", ids)
+			}
+		})
+	}
+}
+
+func TestSearchPlayerEventsFiltersAcrossBackends(t *testing.T) {
+	for name, dsn := range testDSNs(t) {
+		t.Run(name, func(t *testing.T) {
+			st := openTestTracker(t, dsn)
+			alice, bob := uniqueSteamID(), uniqueSteamID()
+			start := time.Now().Add(-time.Second)
+
+			for _, player := range []*steamtracker.Player{
+				{SteamID: alice, PersonaName: "Alice Example", PersonaState: steamtracker.PersonaStateOnline},
+				{SteamID: alice, PersonaName: "Alice Example", PersonaState: steamtracker.PersonaStateOnline, GameID: "440"},
+				{SteamID: alice, PersonaName: "Alice Example", PersonaState: steamtracker.PersonaStateOnline, GameID: "440"},
+				{SteamID: alice, PersonaName: "Alice Example", PersonaState: steamtracker.PersonaStateBusy, GameID: "440"},
+				{SteamID: alice, PersonaName: "Alice Example", PersonaState: steamtracker.PersonaStateOffline},
+				{SteamID: bob, PersonaName: "Bob", PersonaState: steamtracker.PersonaStateOnline},
+			} {
+				if err := st.AddPlayer(player); err != nil {
+					t.Fatalf("Failed to add player: %v", err)
+				}
+			}
+
+			both := []steamtracker.SteamID{alice, bob}
+			ptr := func(s string) *string { return &s }
+			tests := []struct {
+				name  string
+				query steamtracker.SearchPlayerEventsQuery
+				count int64
+			}{
+				{"steam_ids", steamtracker.SearchPlayerEventsQuery{SteamIDs: both}, 5},
+				{"steam_id and steam_ids", steamtracker.SearchPlayerEventsQuery{SteamID: &bob, SteamIDs: both}, 1},
+				{"first_seen", steamtracker.SearchPlayerEventsQuery{SteamIDs: both, Types: []steamtracker.PlayerEventType{steamtracker.PlayerEventTypeFirstSeen}}, 2},
+				{"game", steamtracker.SearchPlayerEventsQuery{SteamIDs: both, Types: []steamtracker.PlayerEventType{steamtracker.PlayerEventTypeGame}}, 1},
+				{"persona_state", steamtracker.SearchPlayerEventsQuery{SteamIDs: both, PersonaStates: []steamtracker.PersonaState{steamtracker.PersonaStateBusy, steamtracker.PersonaStateOffline}}, 2},
+				{"persona_name", steamtracker.SearchPlayerEventsQuery{SteamIDs: both, PersonaName: ptr("ALICE")}, 4},
+				{"persona_name wildcard", steamtracker.SearchPlayerEventsQuery{SteamIDs: both, PersonaName: ptr("%")}, 0},
+				{"before start", steamtracker.SearchPlayerEventsQuery{SteamIDs: both, EndCreatedAt: &start}, 0},
+				{"after start", steamtracker.SearchPlayerEventsQuery{SteamIDs: both, StartCreatedAt: &start}, 5},
+			}
+
+			for _, tt := range tests {
+				if err := tt.query.Validate(); err != nil {
+					t.Fatalf("%s: invalid query: %v", tt.name, err)
+				}
+
+				result, err := st.SearchPlayerEvents(&tt.query)
+				if err != nil {
+					t.Fatalf("%s: failed to search player events: %v", tt.name, err)
+				}
+				if result.TotalCount == nil || *result.TotalCount != tt.count {
+					t.Errorf("%s: expected total count %d, got %v", tt.name, tt.count, result.TotalCount)
+				}
+			}
+
+			online := steamtracker.SearchPlayerEventsQuery{SteamID: &alice, PersonaStates: []steamtracker.PersonaState{steamtracker.PersonaStateOnline}}
+			online.SortBy.CreatedAt = ptr("asc")
+			if err := online.Validate(); err != nil {
+				t.Fatalf("Invalid query: %v", err)
+			}
+			result, err := st.SearchPlayerEvents(&online)
+			if err != nil {
+				t.Fatalf("Failed to search player events: %v", err)
+			}
+			if len(result.PlayerEvents) != 2 || result.PlayerEvents[0].Type != steamtracker.PlayerEventTypeFirstSeen || result.PlayerEvents[1].GameID != "440" {
+				t.Errorf("Expected the first event followed by a game event, got %d events", len(result.PlayerEvents))
+			}
+		})
+	}
+}
+
+// countAuditLogs returns the number of stored audit logs.
+func countAuditLogs(t *testing.T, st *steamtracker.SteamTracker) int64 {
+	t.Helper()
+
+	query := steamtracker.SearchAuditLogsQuery{}
+	if err := query.Validate(); err != nil {
+		t.Fatalf("Invalid query: %v", err)
+	}
+	result, err := st.SearchAuditLogs(&query)
+	if err != nil {
+		t.Fatalf("Failed to search audit logs: %v", err)
+	}
+
+	return *result.TotalCount
+}
+
+// countPlayers returns the number of stored snapshots of steamID.
+func countPlayers(t *testing.T, st *steamtracker.SteamTracker, steamID steamtracker.SteamID) int64 {
+	t.Helper()
+
+	query := steamtracker.SearchPlayersQuery{SteamID: &steamID}
+	if err := query.Validate(); err != nil {
+		t.Fatalf("Invalid query: %v", err)
+	}
+	result, err := st.SearchPlayers(t.Context(), &query)
+	if err != nil {
+		t.Fatalf("Failed to search players: %v", err)
+	}
+
+	return *result.TotalCount
+}
+
+func TestResetDatabase(t *testing.T) {
+	dir := t.TempDir()
+	st := openTestTracker(t, "sqlite://"+filepath.Join(dir, "tracker.db"))
+	steamID := uniqueSteamID()
+
+	if err := st.AddPlayer(&steamtracker.Player{SteamID: steamID, PersonaName: "Test Player", PersonaState: steamtracker.PersonaStateOnline}); err != nil {
+		t.Fatalf("Failed to add player: %v", err)
+	}
+	if _, err := st.CreateAuditLog(&steamtracker.CreateAuditLogCommand{Raw: steamtracker.JSON(`{"level":"info","message":"test"}`)}); err != nil {
+		t.Fatalf("Failed to create audit log: %v", err)
+	}
+
+	backupDir := filepath.Join(dir, "backups")
+
+	if _, err := st.ResetDatabase(&steamtracker.ResetDatabaseCommand{Confirm: "other.db", BackupDir: backupDir}); err == nil {
+		t.Fatal("Expected an error for a confirmation that does not match the database name")
+	}
+	if _, err := os.Stat(backupDir); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup before the confirmation is checked, got %v", err)
+	}
+	if countPlayers(t, st, steamID) != 1 || countAuditLogs(t, st) != 1 {
+		t.Fatal("Expected a refused reset to keep every row")
+	}
+
+	if _, err := st.ResetDatabase(&steamtracker.ResetDatabaseCommand{Confirm: "tracker.db", Tables: []string{"unknown"}, SkipBackup: true}); err == nil {
+		t.Error("Expected an error for an unknown table")
+	}
+
+	result, err := st.ResetDatabase(&steamtracker.ResetDatabaseCommand{Confirm: "tracker.db", Tables: []string{"audit_logs"}, BackupDir: backupDir})
+	if err != nil {
+		t.Fatalf("Failed to reset database: %v", err)
+	}
+	if result.Tables["audit_logs"] != 1 || len(result.Tables) != 1 {
+		t.Errorf("Expected only audit_logs to be reset, got %v", result.Tables)
+	}
+	if countAuditLogs(t, st) != 0 {
+		t.Error("Expected audit logs to be deleted")
+	}
+	if countPlayers(t, st, steamID) != 1 {
+		t.Error("Expected players outside the table filter to be kept")
+	}
+
+	// The backup was taken before the reset, so it still has the audit log.
+	if filepath.Dir(result.BackupPath) != backupDir {
+		t.Fatalf("Expected a backup in %s, got %q", backupDir, result.BackupPath)
+	}
+	backup := openTestTracker(t, "sqlite://"+result.BackupPath)
+	if countAuditLogs(t, backup) != 1 {
+		t.Error("Expected the backup to hold the audit log deleted by the reset")
+	}
+
+	result, err = st.ResetDatabase(&steamtracker.ResetDatabaseCommand{Confirm: "tracker.db", SkipBackup: true})
+	if err != nil {
+		t.Fatalf("Failed to reset database: %v", err)
+	}
+	if result.BackupPath != "" {
+		t.Errorf("Expected no backup with SkipBackup, got %s", result.BackupPath)
+	}
+	if countPlayers(t, st, steamID) != 0 {
+		t.Error("Expected every data table to be reset without a table filter")
+	}
+}
+
+func TestResetDatabaseDerivedTables(t *testing.T) {
+	st := openTestTracker(t, "sqlite://"+filepath.Join(t.TempDir(), "tracker.db"))
+	steamID := uniqueSteamID()
+
+	addPlayer(t, st, steamID, steamtracker.PersonaStateOnline, "")
+	player := addPlayer(t, st, steamID, steamtracker.PersonaStateOnline, "570")
+	if state := currentPlayer(t, st, steamID); state == nil || state.LastEventID == 0 || len(dailyOnlineSeconds(t, st, steamID)) == 0 {
+		t.Fatalf("Expected a current state with an event and rollups, got %+v", state)
+	}
+
+	// Without events the state is only known since the newest snapshot.
+	result, err := st.ResetDatabase(&steamtracker.ResetDatabaseCommand{Confirm: "tracker.db", Tables: []string{"player_events"}, SkipBackup: true})
+	if err != nil {
+		t.Fatalf("Failed to reset database: %v", err)
+	}
+	if len(result.Tables) != 1 {
+		t.Errorf("Expected only player_events to be reset, got %v", result.Tables)
+	}
+	if state := currentPlayer(t, st, steamID); state == nil || state.LastEventID != 0 || !state.StateSince.Equal(player.CreatedAt) {
+		t.Errorf("Expected the current state to be rebuilt without events, got %+v", state)
+	}
+	if len(dailyOnlineSeconds(t, st, steamID)) == 0 {
+		t.Error("Expected the rollups to be kept with the players")
+	}
+
+	result, err = st.ResetDatabase(&steamtracker.ResetDatabaseCommand{Confirm: "tracker.db", Tables: []string{"players"}, SkipBackup: true})
+	if err != nil {
+		t.Fatalf("Failed to reset database: %v", err)
+	}
+	for _, table := range []string{"players", "player_current_states", "player_hourly_rollups", "player_daily_rollups"} {
+		if _, ok := result.Tables[table]; !ok {
+			t.Errorf("Expected %s to be reset with players, got %v", table, result.Tables)
+		}
+	}
+	if state := currentPlayer(t, st, steamID); state != nil {
+		t.Errorf("Expected no current state after resetting players, got %+v", state)
+	}
+	if got := dailyOnlineSeconds(t, st, steamID); len(got) != 0 {
+		t.Errorf("Expected no rollups after resetting players, got %v", got)
+	}
+}
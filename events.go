@@ -0,0 +1,246 @@
+package steamtracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// eventStreamBufferSize is how many events a stream client may fall
+	// behind before it is disconnected.
+	eventStreamBufferSize = 64
+	// defaultEventStreamHeartbeat is how often an idle stream sends a
+	// comment, so proxies do not time it out, unless configured otherwise.
+	defaultEventStreamHeartbeat = 15 * time.Second
+	// eventStreamResumeBatchSize is how many missed events are read from the
+	// database at a time when a client resumes.
+	eventStreamResumeBatchSize = 500
+)
+
+// playerEventBroadcaster fans out newly created player events to the clients
+// of the event stream. Publish never blocks the caller: a subscriber whose
+// buffer is full is dropped, and its client reconnects with Last-Event-ID to
+// read what it missed from the database.
+type playerEventBroadcaster struct {
+	mu   sync.Mutex
+	subs map[*playerEventSubscription]struct{}
+}
+
+// playerEventSubscription receives published events on events until it is
+// unsubscribed or dropped, either of which closes the channel.
+type playerEventSubscription struct {
+	events chan *PlayerEvent
+}
+
+func newPlayerEventBroadcaster() *playerEventBroadcaster {
+	return &playerEventBroadcaster{subs: make(map[*playerEventSubscription]struct{})}
+}
+
+func (b *playerEventBroadcaster) subscribe() *playerEventSubscription {
+	sub := &playerEventSubscription{events: make(chan *PlayerEvent, eventStreamBufferSize)}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = struct{}{}
+
+	return sub
+}
+
+func (b *playerEventBroadcaster) unsubscribe(sub *playerEventSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.events)
+	}
+}
+
+func (b *playerEventBroadcaster) publish(e *PlayerEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subs {
+		select {
+		case sub.events <- e:
+		default:
+			delete(b.subs, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// StreamPlayerEventsQuery selects the events sent on the event stream.
+type StreamPlayerEventsQuery struct {
+	// SteamIDs limits the stream to these players, all players if empty.
+	SteamIDs []SteamID `json:"steam_ids"`
+	// LastEventID resumes after this event, sending the events created
+	// since first. The Last-Event-ID header takes precedence over it.
+	LastEventID *int64 `json:"last_event_id"`
+}
+
+func (query *StreamPlayerEventsQuery) Validate() error {
+	bindErr := &BindError{}
+	if len(query.SteamIDs) > 100 {
+		bindErr.add("steam_ids", fmt.Sprintf("must list at most 100 Steam IDs, got %d", len(query.SteamIDs)))
+	}
+	for _, steamID := range query.SteamIDs {
+		if steamID <= 0 {
+			bindErr.add("steam_ids", "must be positive")
+			break
+		}
+	}
+
+	if query.LastEventID != nil && *query.LastEventID < 0 {
+		bindErr.add("last_event_id", "must not be negative")
+	}
+
+	return bindErr.err()
+}
+
+func (query *StreamPlayerEventsQuery) matches(e *PlayerEvent) bool {
+	return len(query.SteamIDs) == 0 || slices.Contains(query.SteamIDs, e.SteamID)
+}
+
+// GetV1EventStream sends player events as Server-Sent Events while they are
+// created. Each event has its ID as the SSE id, so a reconnecting
+// EventSource resumes where it left off.
+func (st *SteamTracker) GetV1EventStream(w http.ResponseWriter, r *http.Request) {
+	query := StreamPlayerEventsQuery{}
+	if err := bindQuery(r, &query); err != nil {
+		writeAPIQueryError(w, err)
+		return
+	}
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			writeAPIQueryError(w, &BindError{Fields: []FieldError{{Field: "Last-Event-ID", Message: "must be a player event ID"}}})
+			return
+		}
+		query.LastEventID = &id
+	}
+
+	event := log.Debug().Str("action", "stream_player_events").Int("steam_ids", len(query.SteamIDs))
+	defer func() { event.Send() }()
+
+	// Subscribe before reading missed events, so nothing created in between
+	// is lost. Events read from the database are skipped when they arrive.
+	sub := st.playerEvents.subscribe()
+	defer st.playerEvents.unsubscribe(sub)
+
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	sent := 0
+	send := func(e *PlayerEvent) error {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: player_event\ndata: %s\n\n", e.ID, data); err != nil {
+			return err
+		}
+		sent++
+		return rc.Flush()
+	}
+
+	var resumedTo int64
+	if query.LastEventID != nil {
+		event.Int64("last_event_id", *query.LastEventID)
+
+		var err error
+		resumedTo, err = st.resumePlayerEvents(r.Context(), &query, send)
+		if err != nil {
+			event.Err(err)
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		event.Err(err)
+		return
+	}
+
+	interval := defaultEventStreamHeartbeat
+	if st.cfg.EventStreamHeartbeat > 0 {
+		interval = time.Duration(st.cfg.EventStreamHeartbeat) * time.Millisecond
+	}
+	heartbeat := time.NewTicker(interval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case e, ok := <-sub.events:
+			if !ok {
+				event.Bool("dropped", true)
+				return
+			}
+			if e.ID <= resumedTo || !query.matches(e) {
+				continue
+			}
+			if err := send(e); err != nil {
+				event.Int("sent", sent)
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			event.Int("sent", sent)
+			return
+		case <-st.ctx.Done():
+			event.Int("sent", sent)
+			return
+		}
+	}
+}
+
+// resumePlayerEvents sends the matching events created after
+// query.LastEventID, oldest first, and returns the ID of the last one.
+func (st *SteamTracker) resumePlayerEvents(ctx context.Context, query *StreamPlayerEventsQuery, send func(*PlayerEvent) error) (int64, error) {
+	lastID := *query.LastEventID
+	for {
+		events := make([]*PlayerEvent, 0, eventStreamResumeBatchSize)
+		ss := st.db.WithContext(ctx).Model(&PlayerEvent{}).Where("id > ?", lastID)
+		if len(query.SteamIDs) > 0 {
+			ss = ss.Where("steam_id IN ?", query.SteamIDs)
+		}
+		if err := ss.Order("id").Limit(eventStreamResumeBatchSize).Find(&events).Error; err != nil {
+			return lastID, fmt.Errorf("failed to get missed player events: %w", err)
+		}
+
+		for _, e := range events {
+			if err := send(e); err != nil {
+				return lastID, err
+			}
+			lastID = e.ID
+		}
+
+		if len(events) < eventStreamResumeBatchSize {
+			return lastID, nil
+		}
+	}
+}
+
+// publishPlayerEvent sends a committed event to the stream clients.
+func (st *SteamTracker) publishPlayerEvent(e *PlayerEvent) {
+	if e != nil {
+		st.playerEvents.publish(e)
+	}
+}
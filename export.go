@@ -0,0 +1,382 @@
+package steamtracker
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatJSONL   = "jsonl"
+	ExportFormatParquet = "parquet"
+)
+
+const exportBatchSize = 1000
+
+// The export row types define the stable on-disk format shared by export and
+// import. JSON Lines uses the same representation as the HTTP API, CSV uses
+// the json tag names as headers, and Parquet uses the parquet tags.
+
+type playerExportRow struct {
+	ID           int64        `json:"id" parquet:"id"`
+	SteamID      SteamID      `json:"steam_id" parquet:"steam_id"`
+	ProfileState int          `json:"profile_state" parquet:"profile_state"`
+	PersonaName  string       `json:"persona_name" parquet:"persona_name"`
+	AvatarHash   string       `json:"avatar_hash" parquet:"avatar_hash"`
+	LastLogoff   int          `json:"last_logoff" parquet:"last_logoff"`
+	PersonaState PersonaState `json:"persona_state" parquet:"persona_state"`
+	GameID       string       `json:"game_id" parquet:"game_id"`
+	CreatedAt    time.Time    `json:"created_at" parquet:"created_at,timestamp(nanosecond)"`
+}
+
+type playerEventExportRow struct {
+	ID           int64           `json:"id" parquet:"id"`
+	SteamID      SteamID         `json:"steam_id" parquet:"steam_id"`
+	Type         PlayerEventType `json:"type" parquet:"type"`
+	PersonaName  string          `json:"persona_name" parquet:"persona_name"`
+	PersonaState PersonaState    `json:"persona_state" parquet:"persona_state"`
+	GameID       string          `json:"game_id" parquet:"game_id"`
+	CreatedAt    time.Time       `json:"created_at" parquet:"created_at,timestamp(nanosecond)"`
+}
+
+type auditLogExportRow struct {
+	ID        int64     `json:"id" parquet:"id"`
+	Raw       RawString `json:"raw" parquet:"raw,json"`
+	CreatedAt time.Time `json:"created_at" parquet:"created_at,timestamp(nanosecond)"`
+}
+
+// RawString holds a JSON document as text. It is written verbatim in JSON
+// Lines and as a plain string column in CSV and Parquet.
+type RawString string
+
+func (r RawString) MarshalJSON() ([]byte, error) {
+	if r == "" {
+		return []byte("null"), nil
+	}
+	return []byte(r), nil
+}
+
+func (r *RawString) UnmarshalJSON(data []byte) error {
+	*r = RawString(data)
+	return nil
+}
+
+func (r RawString) String() string {
+	return string(r)
+}
+
+type exportTable struct {
+	name   string
+	sample any
+	// iterate streams the rows created in [since, until) in (created_at, id)
+	// order, one batch at a time.
+	iterate func(tx *gorm.DB, since, until *time.Time, fn func(rows []any) error) error
+}
+
+var exportTables = map[string]exportTable{
+	"players": newExportTable("players", func(p *Player) (any, time.Time, int64) {
+		return &playerExportRow{
+			ID:           p.ID,
+			SteamID:      p.SteamID,
+			ProfileState: p.ProfileState,
+			PersonaName:  p.PersonaName,
+			AvatarHash:   p.AvatarHash,
+			LastLogoff:   p.LastLogoff,
+			PersonaState: p.PersonaState,
+			GameID:       p.GameID,
+			CreatedAt:    p.CreatedAt,
+		}, p.CreatedAt, p.ID
+	}, &playerExportRow{}),
+	"player_events": newExportTable("player_events", func(e *PlayerEvent) (any, time.Time, int64) {
+		return &playerEventExportRow{
+			ID:           e.ID,
+			SteamID:      e.SteamID,
+			Type:         e.Type,
+			PersonaName:  e.PersonaName,
+			PersonaState: e.PersonaState,
+			GameID:       e.GameID,
+			CreatedAt:    e.CreatedAt,
+		}, e.CreatedAt, e.ID
+	}, &playerEventExportRow{}),
+	"audit_logs": newExportTable("audit_logs", func(al *AuditLog) (any, time.Time, int64) {
+		return &auditLogExportRow{
+			ID:        al.ID,
+			Raw:       RawString(al.Raw),
+			CreatedAt: al.CreatedAt,
+		}, al.CreatedAt, al.ID
+	}, &auditLogExportRow{}),
+}
+
+func newExportTable[M any](name string, convert func(*M) (any, time.Time, int64), sample any) exportTable {
+	return exportTable{
+		name:   name,
+		sample: sample,
+		iterate: func(tx *gorm.DB, since, until *time.Time, fn func(rows []any) error) error {
+			var lastCreatedAt time.Time
+			var lastID int64
+			first := true
+
+			for {
+				ss := tx.Model(new(M))
+				setOptional(since, func(v time.Time) { ss = ss.Where("created_at >= ?", v) })
+				setOptional(until, func(v time.Time) { ss = ss.Where("created_at < ?", v) })
+				if !first {
+					ss = ss.Where("(created_at > ? OR (created_at = ? AND id > ?))", lastCreatedAt, lastCreatedAt, lastID)
+				}
+
+				models := make([]*M, 0, exportBatchSize)
+				if err := ss.Order("created_at").Order("id").Limit(exportBatchSize).Find(&models).Error; err != nil {
+					return err
+				}
+
+				rows := make([]any, 0, len(models))
+				for _, m := range models {
+					row, createdAt, id := convert(m)
+					rows = append(rows, row)
+					lastCreatedAt, lastID = createdAt, id
+				}
+				first = false
+
+				if len(rows) > 0 {
+					if err := fn(rows); err != nil {
+						return err
+					}
+				}
+				if len(rows) < exportBatchSize {
+					return nil
+				}
+			}
+		},
+	}
+}
+
+// ExportTableNames lists the tables that can be exported.
+func ExportTableNames() []string {
+	return []string{"players", "player_events", "audit_logs"}
+}
+
+type ExportQuery struct {
+	// Table comes from the URL path, not the query string.
+	Table  string     `json:"table" query:"-"`
+	Format string     `json:"format"`
+	Since  *time.Time `json:"since"`
+	Until  *time.Time `json:"until"`
+}
+
+func (query *ExportQuery) Validate() error {
+	bindErr := &BindError{}
+	if _, ok := exportTables[query.Table]; !ok {
+		bindErr.add("table", fmt.Sprintf("must be one of %s, got %s", strings.Join(ExportTableNames(), ", "), query.Table))
+	}
+
+	if query.Format == "" {
+		query.Format = ExportFormatJSONL
+	}
+	switch query.Format {
+	case ExportFormatCSV, ExportFormatJSONL, ExportFormatParquet:
+	default:
+		bindErr.add("format", fmt.Sprintf("must be csv, jsonl or parquet, got %s", query.Format))
+	}
+
+	if query.Since != nil && query.Until != nil && query.Since.After(*query.Until) {
+		bindErr.add("since", "must not be after until")
+	}
+
+	return bindErr.err()
+}
+
+// ContentType returns the media type of the export format.
+func (query *ExportQuery) ContentType() string {
+	switch query.Format {
+	case ExportFormatCSV:
+		return "text/csv; charset=utf-8"
+	case ExportFormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "application/x-ndjson; charset=utf-8"
+	}
+}
+
+// Export streams the selected rows to w without loading the table into
+// memory. It returns the number of rows written.
+func (st *SteamTracker) Export(ctx context.Context, w io.Writer, query *ExportQuery) (int64, error) {
+	event := log.Debug().
+		Str("action", "export").
+		Str("table", query.Table).
+		Str("format", query.Format)
+	defer func() { event.Send() }()
+
+	setOptional(query.Since, func(v time.Time) { event.Time("since", v) })
+	setOptional(query.Until, func(v time.Time) { event.Time("until", v) })
+
+	table := exportTables[query.Table]
+	enc, err := newRowEncoder(w, query.Format, table.sample)
+	if err != nil {
+		event.Err(err)
+		return 0, err
+	}
+
+	var count int64
+	err = table.iterate(st.db.WithContext(ctx), query.Since, query.Until, func(rows []any) error {
+		for _, row := range rows {
+			if err := enc.Encode(row); err != nil {
+				return fmt.Errorf("failed to encode row: %w", err)
+			}
+		}
+		count += int64(len(rows))
+		return enc.Flush()
+	})
+	if closeErr := enc.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to finish export: %w", closeErr)
+	}
+	event.Int64("rows", count)
+	if err != nil {
+		event.Err(err)
+	}
+
+	return count, err
+}
+
+type rowEncoder interface {
+	Encode(row any) error
+	// Flush pushes buffered rows to the underlying writer, and on to the
+	// client when the writer is an HTTP response.
+	Flush() error
+	Close() error
+}
+
+func newRowEncoder(w io.Writer, format string, sample any) (rowEncoder, error) {
+	switch format {
+	case ExportFormatJSONL:
+		return &jsonlEncoder{w: w, enc: json.NewEncoder(w)}, nil
+	case ExportFormatCSV:
+		return &csvEncoder{w: w, cw: csv.NewWriter(w), header: exportColumns(sample)}, nil
+	case ExportFormatParquet:
+		return &parquetEncoder{pw: parquet.NewWriter(w, parquet.SchemaOf(sample), parquet.MaxRowsPerRowGroup(50000))}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+func flushHTTP(w io.Writer) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+type jsonlEncoder struct {
+	w   io.Writer
+	enc *json.Encoder
+}
+
+func (e *jsonlEncoder) Encode(row any) error { return e.enc.Encode(row) }
+func (e *jsonlEncoder) Flush() error         { flushHTTP(e.w); return nil }
+func (e *jsonlEncoder) Close() error         { return nil }
+
+type csvEncoder struct {
+	w           io.Writer
+	cw          *csv.Writer
+	header      []string
+	wroteHeader bool
+}
+
+func (e *csvEncoder) Encode(row any) error {
+	if !e.wroteHeader {
+		if err := e.cw.Write(e.header); err != nil {
+			return err
+		}
+		e.wroteHeader = true
+	}
+	return e.cw.Write(exportRecord(row))
+}
+
+func (e *csvEncoder) Flush() error {
+	e.cw.Flush()
+	flushHTTP(e.w)
+	return e.cw.Error()
+}
+
+func (e *csvEncoder) Close() error {
+	if !e.wroteHeader {
+		if err := e.cw.Write(e.header); err != nil {
+			return err
+		}
+	}
+	return e.Flush()
+}
+
+type parquetEncoder struct {
+	pw *parquet.Writer
+}
+
+func (e *parquetEncoder) Encode(row any) error { return e.pw.Write(row) }
+func (e *parquetEncoder) Flush() error         { return nil } // row groups are flushed by size
+func (e *parquetEncoder) Close() error         { return e.pw.Close() }
+
+// exportColumns returns the CSV header for an export row type.
+func exportColumns(sample any) []string {
+	t := reflect.TypeOf(sample).Elem()
+	columns := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		columns = append(columns, name)
+	}
+	return columns
+}
+
+// exportRecord formats an export row as CSV fields. Enumerations and IDs use
+// their String form so the file stays readable in spreadsheets.
+func exportRecord(row any) []string {
+	v := reflect.ValueOf(row).Elem()
+	record := make([]string, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		switch f := v.Field(i).Interface().(type) {
+		case time.Time:
+			record = append(record, f.Format(time.RFC3339Nano))
+		case fmt.Stringer:
+			record = append(record, f.String())
+		case int:
+			record = append(record, strconv.Itoa(f))
+		case int64:
+			record = append(record, strconv.FormatInt(f, 10))
+		case string:
+			record = append(record, f)
+		default:
+			record = append(record, fmt.Sprint(f))
+		}
+	}
+	return record
+}
+
+func (st *SteamTracker) GetExport(w http.ResponseWriter, r *http.Request) {
+	query := ExportQuery{Table: r.PathValue("table")}
+	if err := bindQuery(r, &query); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query parameters: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	st.writeExport(w, r, &query)
+}
+
+func (st *SteamTracker) writeExport(w http.ResponseWriter, r *http.Request, query *ExportQuery) {
+	w.Header().Set("Content-Type", query.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, query.Table, query.Format))
+
+	// Once rows have been streamed the status code cannot change, so a late
+	// failure can only be logged and the response cut short.
+	if _, err := st.Export(r.Context(), w, query); err != nil {
+		log.Error().Err(err).Str("table", query.Table).Msg("Failed to export")
+	}
+}
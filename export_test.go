@@ -0,0 +1,180 @@
+package steamtracker_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+func exportBytes(t *testing.T, st *steamtracker.SteamTracker, query steamtracker.ExportQuery) []byte {
+	t.Helper()
+
+	if err := query.Validate(); err != nil {
+		t.Fatalf("Invalid export query: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := st.Export(t.Context(), &buf, &query); err != nil {
+		t.Fatalf("Failed to export %s: %v", query.Table, err)
+	}
+	return buf.Bytes()
+}
+
+// exportedRows returns the rows of table as decoded from a JSON Lines export,
+// with created_at normalised to UTC so rows from different databases compare
+// equal.
+func exportedRows(t *testing.T, st *steamtracker.SteamTracker, table string) []map[string]any {
+	t.Helper()
+
+	rows := make([]map[string]any, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(exportBytes(t, st, steamtracker.ExportQuery{Table: table})))
+	for scanner.Scan() {
+		row := make(map[string]any)
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatalf("Failed to decode %q: %v", scanner.Text(), err)
+		}
+		createdAt, err := time.Parse(time.RFC3339Nano, row["created_at"].(string))
+		if err != nil {
+			t.Fatalf("Failed to parse created_at: %v", err)
+		}
+		row["created_at"] = createdAt.UTC()
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestExportFormats(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	steamID := uniqueSteamID()
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	importPlayers(t, st, steamID, steamtracker.PersonaStateOnline, minutes(start, 2)...)
+	columns := []string{"id", "steam_id", "profile_state", "persona_name", "avatar_hash", "last_logoff", "persona_state", "game_id", "created_at"}
+
+	t.Run("jsonl", func(t *testing.T) {
+		lines := bytes.Split(bytes.TrimSuffix(exportBytes(t, st, steamtracker.ExportQuery{Table: "players"}), []byte("\n")), []byte("\n"))
+		if len(lines) != 3 {
+			t.Fatalf("Expected 3 lines, got %d", len(lines))
+		}
+		for i, line := range lines {
+			row := make(map[string]json.RawMessage)
+			if err := json.Unmarshal(line, &row); err != nil {
+				t.Fatalf("Failed to decode line %d: %v", i, err)
+			}
+			keys := make([]string, 0, len(row))
+			for key := range row {
+				keys = append(keys, key)
+			}
+			if !slices.Equal(slices.Sorted(slices.Values(keys)), slices.Sorted(slices.Values(columns))) {
+				t.Errorf("Expected keys %v, got %v", columns, keys)
+			}
+			if want := `"` + start.Add(time.Duration(i)*time.Minute).Format(time.RFC3339) + `"`; string(row["created_at"]) != want {
+				t.Errorf("Expected line %d to be created at %s, got %s", i, want, row["created_at"])
+			}
+		}
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		records, err := csv.NewReader(bytes.NewReader(exportBytes(t, st, steamtracker.ExportQuery{Table: "players", Format: steamtracker.ExportFormatCSV}))).ReadAll()
+		if err != nil {
+			t.Fatalf("Failed to read CSV: %v", err)
+		}
+		if len(records) != 4 || !slices.Equal(records[0], columns) {
+			t.Fatalf("Expected a header and 3 records, got %v", records)
+		}
+		if records[1][1] != steamID.String() || records[1][6] != steamtracker.PersonaStateOnline.String() {
+			t.Errorf("Expected IDs and enumerations in their string form, got %v", records[1])
+		}
+	})
+
+	t.Run("csv empty", func(t *testing.T) {
+		since := start.Add(time.Hour)
+		records, err := csv.NewReader(bytes.NewReader(exportBytes(t, st, steamtracker.ExportQuery{Table: "players", Format: steamtracker.ExportFormatCSV, Since: &since}))).ReadAll()
+		if err != nil {
+			t.Fatalf("Failed to read CSV: %v", err)
+		}
+		if len(records) != 1 || !slices.Equal(records[0], columns) {
+			t.Errorf("Expected only the header, got %v", records)
+		}
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		data := exportBytes(t, st, steamtracker.ExportQuery{Table: "players", Format: steamtracker.ExportFormatParquet})
+		f, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("Failed to open Parquet file: %v", err)
+		}
+		if f.NumRows() != 3 {
+			t.Errorf("Expected 3 rows, got %d", f.NumRows())
+		}
+		for _, column := range columns {
+			if _, ok := f.Schema().Lookup(column); !ok {
+				t.Errorf("Expected a %s column", column)
+			}
+		}
+	})
+
+	t.Run("since and until", func(t *testing.T) {
+		since, until := start.Add(time.Minute), start.Add(2*time.Minute)
+		data := exportBytes(t, st, steamtracker.ExportQuery{Table: "players", Since: &since, Until: &until})
+		if n := bytes.Count(data, []byte("\n")); n != 1 {
+			t.Errorf("Expected 1 row created in [since, until), got %d", n)
+		}
+	})
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src := openTestTracker(t, testDSNs(t)["sqlite"])
+	steamID := uniqueSteamID()
+	for _, state := range []steamtracker.PersonaState{steamtracker.PersonaStateOnline, steamtracker.PersonaStateAway, steamtracker.PersonaStateOffline} {
+		if err := src.AddPlayer(&steamtracker.Player{SteamID: steamID, PersonaName: "Test Player", PersonaState: state}); err != nil {
+			t.Fatalf("Failed to add player: %v", err)
+		}
+	}
+	if _, err := src.CreateAuditLog(&steamtracker.CreateAuditLogCommand{Raw: steamtracker.JSON(`{"level":"info","message":"test"}`)}); err != nil {
+		t.Fatalf("Failed to create audit log: %v", err)
+	}
+
+	for _, format := range []string{steamtracker.ExportFormatJSONL, steamtracker.ExportFormatCSV, steamtracker.ExportFormatParquet} {
+		t.Run(format, func(t *testing.T) {
+			dst := openTestTracker(t, testDSNs(t)["sqlite"])
+
+			for _, table := range steamtracker.ExportTableNames() {
+				data := exportBytes(t, src, steamtracker.ExportQuery{Table: table, Format: format})
+				result, err := dst.Import(t.Context(), bytes.NewReader(data), &steamtracker.ImportCommand{Table: table, Format: format})
+				if err != nil {
+					t.Fatalf("Failed to import %s: %v", table, err)
+				}
+				if result.Read == 0 || result.Inserted != result.Read {
+					t.Errorf("Expected every %s row to be inserted, got %+v", table, result)
+				}
+			}
+
+			for _, table := range []string{"players", "player_events"} {
+				if want, got := exportedRows(t, src, table), exportedRows(t, dst, table); !reflect.DeepEqual(want, got) {
+					t.Errorf("Expected imported %s to match the export\nwant %v\ngot  %v", table, want, got)
+				}
+			}
+
+			// Audit logs are appended to the receiving tracker's hash chain
+			// under new IDs, so only their content carries over.
+			want, got := exportedRows(t, src, "audit_logs"), exportedRows(t, dst, "audit_logs")
+			if len(got) != len(want) {
+				t.Fatalf("Expected %d audit logs, got %d", len(want), len(got))
+			}
+			for i := range want {
+				if !reflect.DeepEqual(want[i]["raw"], got[i]["raw"]) || want[i]["created_at"] != got[i]["created_at"] {
+					t.Errorf("Expected audit log %v, got %v", want[i], got[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package steamtracker
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Game is the name Steam last gave a game ID while a player was playing it.
+type Game struct {
+	ID        string    `json:"id" gorm:"primaryKey;size:32"`
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// saveGame stores the name of the game player is playing, if Steam named it.
+func saveGame(tx *gorm.DB, player *Player) error {
+	if player.GameID == "" || player.GameName == "" {
+		return nil
+	}
+
+	game := Game{ID: player.GameID, Name: player.GameName, UpdatedAt: player.CreatedAt}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "updated_at"}),
+	}).Create(&game).Error
+}
+
+// gameNames returns the stored name of each game among gameIDs.
+func gameNames(tx *gorm.DB, gameIDs []string) (map[string]string, error) {
+	games := make([]*Game, 0, len(gameIDs))
+	if err := tx.Where("id IN ?", gameIDs).Find(&games).Error; err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(games))
+	for _, game := range games {
+		names[game.ID] = game.Name
+	}
+
+	return names, nil
+}
@@ -0,0 +1,949 @@
+package steamtracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/rs/zerolog/log"
+)
+
+// Limits of /graphql queries, checked before they run.
+const (
+	// graphQLMaxDepth is how deeply fields may be nested.
+	graphQLMaxDepth = 8
+	// graphQLMaxComplexity bounds the estimated cost of a query: each field
+	// costs 1, or its graphQLFieldCosts, and the fields selected below a list
+	// count once for every item it may return.
+	graphQLMaxComplexity = 10000
+	// graphQLDefaultListSize is the number of items assumed for lists
+	// without a limit argument.
+	graphQLDefaultListSize = 10
+	graphQLMaxLimit        = 100
+	graphQLMaxBodySize     = 1 << 20
+)
+
+// graphQLFieldCosts are the fields that run queries of their own, by
+// "Type.field".
+var graphQLFieldCosts = map[string]int{
+	"Query.playerEvents": 10,
+	"Query.auditLogs":    10,
+	"Player.sessions":    5,
+	"Player.rollups":     5,
+}
+
+// graphQLListSizes overrides graphQLDefaultListSize for list fields whose
+// size is already counted elsewhere, such as the nodes of a page, which
+// are counted by the limit of the page.
+var graphQLListSizes = map[string]int{
+	"PlayerEventPage.nodes": 1,
+	"AuditLogPage.nodes":    1,
+}
+
+// GraphQLRequest is the body of a POST to /graphql, or the query parameters
+// of a GET, with variables as a JSON object.
+type GraphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// graphQLContext is what the resolvers of one request share. Its loaders
+// batch the lookups that would otherwise run once per item of a list.
+type graphQLContext struct {
+	st  *SteamTracker
+	ctx context.Context
+	// now is the default end of time ranges, the same for every field of
+	// the request so their lookups can be batched.
+	now time.Time
+
+	players      *batchLoader[SteamID, *PlayerCurrentState]
+	gameNames    *batchLoader[string, string]
+	recentEvents *batchLoader[graphQLRecentEventsKey, []*PlayerEvent]
+	sessions     *batchLoader[graphQLSessionsKey, []*PlayerSession]
+	rollups      *batchLoader[graphQLRollupsKey, []*PlayerRollup]
+}
+
+type graphQLRecentEventsKey struct {
+	steamID SteamID
+	limit   int
+}
+
+type graphQLSessionsKey struct {
+	steamID      SteamID
+	since, until time.Time
+}
+
+type graphQLRollupsKey struct {
+	steamID     SteamID
+	granularity string
+	start, end  time.Time
+	limit       int
+}
+
+type graphQLContextKey struct{}
+
+func (st *SteamTracker) newGraphQLContext(ctx context.Context) *graphQLContext {
+	gc := &graphQLContext{st: st, ctx: ctx, now: time.Now()}
+	db := st.db.WithContext(ctx)
+
+	gc.players = newBatchLoader(func(steamIDs []SteamID) (map[SteamID]*PlayerCurrentState, error) {
+		states, err := getPlayerCurrentStates(db, steamIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get current players: %w", err)
+		}
+		return states, nil
+	})
+
+	gc.gameNames = newBatchLoader(func(gameIDs []string) (map[string]string, error) {
+		names, err := gameNames(db, gameIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get game names: %w", err)
+		}
+		return names, nil
+	})
+
+	gc.recentEvents = newBatchLoader(func(keys []graphQLRecentEventsKey) (map[graphQLRecentEventsKey][]*PlayerEvent, error) {
+		return batchByGroup(keys, func(k graphQLRecentEventsKey) (int, SteamID) { return k.limit, k.steamID },
+			func(limit int, steamIDs []SteamID) (map[SteamID][]*PlayerEvent, error) {
+				return recentPlayerEvents(db, steamIDs, limit)
+			})
+	})
+
+	gc.sessions = newBatchLoader(func(keys []graphQLSessionsKey) (map[graphQLSessionsKey][]*PlayerSession, error) {
+		type span struct{ since, until time.Time }
+		return batchByGroup(keys, func(k graphQLSessionsKey) (span, SteamID) { return span{k.since, k.until}, k.steamID },
+			func(s span, steamIDs []SteamID) (map[SteamID][]*PlayerSession, error) {
+				return playerSessions(db, steamIDs, s.since, s.until)
+			})
+	})
+
+	gc.rollups = newBatchLoader(func(keys []graphQLRollupsKey) (map[graphQLRollupsKey][]*PlayerRollup, error) {
+		type selection struct {
+			granularity string
+			start, end  time.Time
+			limit       int
+		}
+		return batchByGroup(keys, func(k graphQLRollupsKey) (selection, SteamID) {
+			return selection{k.granularity, k.start, k.end, k.limit}, k.steamID
+		}, func(s selection, steamIDs []SteamID) (map[SteamID][]*PlayerRollup, error) {
+			return playerRollups(db, s.granularity, steamIDs, s.start, s.end, s.limit)
+		})
+	})
+
+	return gc
+}
+
+// batchByGroup fetches keys that differ only by player with one call per
+// group, for loaders whose keys also carry the arguments of a field.
+func batchByGroup[K comparable, G comparable, V any](keys []K, split func(K) (G, SteamID), fetch func(G, []SteamID) (map[SteamID]V, error)) (map[K]V, error) {
+	groups := make(map[G][]SteamID)
+	order := make([]G, 0)
+	for _, k := range keys {
+		g, steamID := split(k)
+		if _, ok := groups[g]; !ok {
+			order = append(order, g)
+		}
+		groups[g] = append(groups[g], steamID)
+	}
+
+	fetched := make(map[G]map[SteamID]V, len(groups))
+	for _, g := range order {
+		values, err := fetch(g, groups[g])
+		if err != nil {
+			return nil, err
+		}
+		fetched[g] = values
+	}
+
+	values := make(map[K]V, len(keys))
+	for _, k := range keys {
+		g, steamID := split(k)
+		values[k] = fetched[g][steamID]
+	}
+
+	return values, nil
+}
+
+func graphQLContextFrom(p graphql.ResolveParams) *graphQLContext {
+	return p.Context.Value(graphQLContextKey{}).(*graphQLContext)
+}
+
+func graphQLSteamID(v any) (SteamID, error) {
+	s := fmt.Sprint(v)
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid Steam ID: %q", s)
+	}
+	return SteamID(id), nil
+}
+
+func graphQLSteamIDs(v any) ([]SteamID, error) {
+	values, _ := v.([]any)
+	steamIDs := make([]SteamID, 0, len(values))
+	for _, value := range values {
+		steamID, err := graphQLSteamID(value)
+		if err != nil {
+			return nil, err
+		}
+		steamIDs = append(steamIDs, steamID)
+	}
+	return steamIDs, nil
+}
+
+func graphQLLimit(args map[string]any) (int, error) {
+	limit, _ := args["limit"].(int)
+	if limit < 1 || limit > graphQLMaxLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d, got %d", graphQLMaxLimit, limit)
+	}
+	return limit, nil
+}
+
+func graphQLTime(args map[string]any, name string) *time.Time {
+	if t, ok := args[name].(time.Time); ok {
+		return &t
+	}
+	return nil
+}
+
+func graphQLString(args map[string]any, name string) *string {
+	if s, ok := args[name].(string); ok {
+		return &s
+	}
+	return nil
+}
+
+// optionalString resolves empty strings to null.
+func optionalString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+var graphQLPersonaState = graphql.NewEnum(graphql.EnumConfig{
+	Name: "PersonaState",
+	Values: func() graphql.EnumValueConfigMap {
+		values := graphql.EnumValueConfigMap{}
+		for state, name := range personaStateNames {
+			values[strings.ToUpper(strings.ReplaceAll(name, " ", "_"))] = &graphql.EnumValueConfig{Value: state}
+		}
+		return values
+	}(),
+})
+
+var graphQLPlayerEventType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "PlayerEventType",
+	Values: graphql.EnumValueConfigMap{
+		"FIRST_SEEN":    &graphql.EnumValueConfig{Value: PlayerEventTypeFirstSeen},
+		"PERSONA_STATE": &graphql.EnumValueConfig{Value: PlayerEventTypePersonaState},
+		"GAME":          &graphql.EnumValueConfig{Value: PlayerEventTypeGame},
+	},
+})
+
+var graphQLSortOrder = graphql.NewEnum(graphql.EnumConfig{
+	Name: "SortOrder",
+	Values: graphql.EnumValueConfigMap{
+		"ASC":  &graphql.EnumValueConfig{Value: "asc"},
+		"DESC": &graphql.EnumValueConfig{Value: "desc"},
+	},
+})
+
+var graphQLRollupGranularity = graphql.NewEnum(graphql.EnumConfig{
+	Name: "RollupGranularity",
+	Values: graphql.EnumValueConfigMap{
+		"HOUR": &graphql.EnumValueConfig{Value: "hour"},
+		"DAY":  &graphql.EnumValueConfig{Value: "day"},
+	},
+})
+
+// graphQLSchema is built once. Resolvers find the tracker and the loaders
+// of their request in its graphQLContext.
+var graphQLSchema = sync.OnceValues(newGraphQLSchema)
+
+func newGraphQLSchema() (graphql.Schema, error) {
+	var player, playerEvent *graphql.Object
+
+	limitArg := func(def int) *graphql.ArgumentConfig {
+		return &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: def, Description: fmt.Sprintf("At most %d.", graphQLMaxLimit)}
+	}
+	playerField := func(steamID func(source any) *SteamID) *graphql.Field {
+		return &graphql.Field{
+			Type:        player,
+			Description: "The current state of the player, null if they are no longer tracked.",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				id := steamID(p.Source)
+				if id == nil {
+					return nil, nil
+				}
+				return graphQLContextFrom(p).players.load(*id), nil
+			},
+		}
+	}
+
+	gameNameField := func(gameID func(source any) string) *graphql.Field {
+		return &graphql.Field{
+			Type:        graphql.String,
+			Description: "The name Steam gives gameId, null if there is no game or it was never named.",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				id := gameID(p.Source)
+				if id == "" {
+					return nil, nil
+				}
+				name := graphQLContextFrom(p).gameNames.load(id)
+				return func() (any, error) {
+					v, err := name()
+					if err != nil {
+						return nil, err
+					}
+					return optionalString(v.(string)), nil
+				}, nil
+			},
+		}
+	}
+
+	playerSession := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "PlayerSession",
+		Description: "A span of time in which a player was in any state but offline.",
+		Fields: graphql.Fields{
+			"steamId":         &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"startedAt":       &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"endedAt":         &graphql.Field{Type: graphql.DateTime, Description: "Null while the session is ongoing."},
+			"durationSeconds": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	playerRollup := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "PlayerRollup",
+		Description: "The time a player spent in each state during an hour or a day.",
+		Fields: graphql.Fields{
+			"steamId":               &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"bucketStart":           &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"offlineSeconds":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"onlineSeconds":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"busySeconds":           &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"awaySeconds":           &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"snoozeSeconds":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"lookingToTradeSeconds": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"lookingToPlaySeconds":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"inGameSeconds":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"sessions":              &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"games": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return []string(p.Source.(*PlayerRollup).Games), nil
+				},
+			},
+			"distinctGames": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"updatedAt":     &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	player = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Player",
+		Description: "The current state of a tracked player.",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"steamId":      &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+				"personaName":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"personaState": &graphql.Field{Type: graphql.NewNonNull(graphQLPersonaState)},
+				"gameId": &graphql.Field{
+					Type:        graphql.String,
+					Description: "The game being played, null if none.",
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return optionalString(p.Source.(*PlayerCurrentState).GameID), nil
+					},
+				},
+				"gameName": gameNameField(func(source any) string {
+					return source.(*PlayerCurrentState).GameID
+				}),
+				"avatarHash":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"profileState": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"lastLogoff":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"stateSince":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Description: "When personaState last changed."},
+				"lastPolledAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+				"recentEvents": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(playerEvent))),
+					Description: "The newest events of the player, newest first.",
+					Args:        graphql.FieldConfigArgument{"limit": limitArg(10)},
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						limit, err := graphQLLimit(p.Args)
+						if err != nil {
+							return nil, err
+						}
+						steamID := p.Source.(*PlayerCurrentState).SteamID
+						return graphQLContextFrom(p).recentEvents.load(graphQLRecentEventsKey{steamID: steamID, limit: limit}), nil
+					},
+				},
+				"sessions": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(playerSession))),
+					Description: "The sessions overlapping [since, until), by default the last 7 days.",
+					Args: graphql.FieldConfigArgument{
+						"since": &graphql.ArgumentConfig{Type: graphql.DateTime},
+						"until": &graphql.ArgumentConfig{Type: graphql.DateTime},
+					},
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						gc := graphQLContextFrom(p)
+						key := graphQLSessionsKey{steamID: p.Source.(*PlayerCurrentState).SteamID, until: gc.now}
+						if until := graphQLTime(p.Args, "until"); until != nil {
+							key.until = *until
+						}
+						key.since = key.until.Add(-7 * 24 * time.Hour)
+						if since := graphQLTime(p.Args, "since"); since != nil {
+							key.since = *since
+						}
+						if !key.since.Before(key.until) {
+							return nil, fmt.Errorf("since must be before until")
+						}
+						return gc.sessions.load(key), nil
+					},
+				},
+				"rollups": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(playerRollup))),
+					Description: "The newest rollups of the player with a bucket in [start, end], newest first.",
+					Args: graphql.FieldConfigArgument{
+						"granularity": &graphql.ArgumentConfig{Type: graphQLRollupGranularity, DefaultValue: "hour"},
+						"start":       &graphql.ArgumentConfig{Type: graphql.DateTime},
+						"end":         &graphql.ArgumentConfig{Type: graphql.DateTime},
+						"limit":       limitArg(24),
+					},
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						limit, err := graphQLLimit(p.Args)
+						if err != nil {
+							return nil, err
+						}
+						key := graphQLRollupsKey{steamID: p.Source.(*PlayerCurrentState).SteamID, limit: limit}
+						key.granularity, _ = p.Args["granularity"].(string)
+						if start := graphQLTime(p.Args, "start"); start != nil {
+							key.start = *start
+						}
+						if end := graphQLTime(p.Args, "end"); end != nil {
+							key.end = *end
+						}
+						if !key.start.IsZero() && !key.end.IsZero() && key.start.After(key.end) {
+							return nil, fmt.Errorf("start cannot be after end")
+						}
+						return graphQLContextFrom(p).rollups.load(key), nil
+					},
+				},
+			}
+		}),
+	})
+
+	playerEvent = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "PlayerEvent",
+		Description: "A change of a player's persona state or game.",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":           &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+				"steamId":      &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+				"type":         &graphql.Field{Type: graphql.NewNonNull(graphQLPlayerEventType)},
+				"personaName":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"personaState": &graphql.Field{Type: graphql.NewNonNull(graphQLPersonaState)},
+				"gameId": &graphql.Field{
+					Type: graphql.String,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return optionalString(p.Source.(*PlayerEvent).GameID), nil
+					},
+				},
+				"gameName": gameNameField(func(source any) string {
+					return source.(*PlayerEvent).GameID
+				}),
+				"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+				"player": playerField(func(source any) *SteamID {
+					return &source.(*PlayerEvent).SteamID
+				}),
+			}
+		}),
+	})
+
+	auditLog := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "AuditLog",
+		Description: "A log entry of the tracker, chained to the previous one by its hash.",
+		Fields: graphql.Fields{
+			"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"level":    &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"message":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"action":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"steamId":  &graphql.Field{Type: graphql.ID, Resolve: resolveAuditLogSteamID},
+			"error":    &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (any, error) { return optionalString(p.Source.(*AuditLog).Error), nil }},
+			"loggedAt": &graphql.Field{Type: graphql.DateTime},
+			"raw": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.String),
+				Description: "The JSON payload as it was logged.",
+				Resolve:     func(p graphql.ResolveParams) (any, error) { return string(p.Source.(*AuditLog).Raw), nil },
+			},
+			"snippet":   &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (any, error) { return optionalString(p.Source.(*AuditLog).Snippet), nil }},
+			"prevHash":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"hash":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"player": playerField(func(source any) *SteamID {
+				return source.(*AuditLog).SteamID
+			}),
+		},
+	})
+
+	pageOf := func(name string, node *graphql.Object, nodes func(source any) any, next func(source any) string) *graphql.Object {
+		return graphql.NewObject(graphql.ObjectConfig{
+			Name: name,
+			Fields: graphql.Fields{
+				"nodes": &graphql.Field{
+					Type:    graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(node))),
+					Resolve: func(p graphql.ResolveParams) (any, error) { return nodes(p.Source), nil },
+				},
+				"nextCursor": &graphql.Field{
+					Type:        graphql.String,
+					Description: "The cursor of the next page, null on the last page.",
+					Resolve:     func(p graphql.ResolveParams) (any, error) { return optionalString(next(p.Source)), nil },
+				},
+			},
+		})
+	}
+	playerEventPage := pageOf("PlayerEventPage", playerEvent,
+		func(source any) any { return source.(*SearchPlayerEventsQueryResult).PlayerEvents },
+		func(source any) string { return source.(*SearchPlayerEventsQueryResult).NextCursor })
+	auditLogPage := pageOf("AuditLogPage", auditLog,
+		func(source any) any { return source.(*SearchAuditLogsQueryResult).AuditLogs },
+		func(source any) string { return source.(*SearchAuditLogsQueryResult).NextCursor })
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"player": &graphql.Field{
+				Type: player,
+				Args: graphql.FieldConfigArgument{"steamId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					steamID, err := graphQLSteamID(p.Args["steamId"])
+					if err != nil {
+						return nil, err
+					}
+					return graphQLContextFrom(p).players.load(steamID), nil
+				},
+			},
+			"players": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(player))),
+				Description: "The tracked players, ordered by name.",
+				Args: graphql.FieldConfigArgument{
+					"steamIds": &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.ID))},
+					"online":   &graphql.ArgumentConfig{Type: graphql.Boolean, DefaultValue: false, Description: "Only players who are not offline."},
+					"limit":    limitArg(graphQLMaxLimit),
+				},
+				Resolve: resolvePlayers,
+			},
+			"playerEvents": &graphql.Field{
+				Type:        graphql.NewNonNull(playerEventPage),
+				Description: "Player events matching every given filter, newest first unless order is ASC.",
+				Args: graphql.FieldConfigArgument{
+					"steamIds":       &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.ID))},
+					"types":          &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphQLPlayerEventType))},
+					"personaStates":  &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphQLPersonaState))},
+					"personaName":    &graphql.ArgumentConfig{Type: graphql.String, Description: "Matches names containing it, ignoring case."},
+					"startCreatedAt": &graphql.ArgumentConfig{Type: graphql.DateTime},
+					"endCreatedAt":   &graphql.ArgumentConfig{Type: graphql.DateTime},
+					"order":          &graphql.ArgumentConfig{Type: graphQLSortOrder, DefaultValue: "desc"},
+					"limit":          limitArg(25),
+					"cursor":         &graphql.ArgumentConfig{Type: graphql.String, Description: "The nextCursor of the previous page."},
+				},
+				Resolve: resolvePlayerEvents,
+			},
+			"auditLogs": &graphql.Field{
+				Type:        graphql.NewNonNull(auditLogPage),
+				Description: "Audit logs matching every given filter, newest first unless order is ASC.",
+				Args: graphql.FieldConfigArgument{
+					"q":             &graphql.ArgumentConfig{Type: graphql.String, Description: "Searches the raw payloads."},
+					"level":         &graphql.ArgumentConfig{Type: graphql.String},
+					"action":        &graphql.ArgumentConfig{Type: graphql.String},
+					"steamId":       &graphql.ArgumentConfig{Type: graphql.ID},
+					"startLoggedAt": &graphql.ArgumentConfig{Type: graphql.DateTime},
+					"endLoggedAt":   &graphql.ArgumentConfig{Type: graphql.DateTime},
+					"hasError":      &graphql.ArgumentConfig{Type: graphql.Boolean},
+					"order":         &graphql.ArgumentConfig{Type: graphQLSortOrder, DefaultValue: "desc"},
+					"limit":         limitArg(25),
+					"cursor":        &graphql.ArgumentConfig{Type: graphql.String, Description: "The nextCursor of the previous page."},
+				},
+				Resolve: resolveAuditLogs,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+func resolveAuditLogSteamID(p graphql.ResolveParams) (any, error) {
+	if steamID := p.Source.(*AuditLog).SteamID; steamID != nil {
+		return steamID.String(), nil
+	}
+	return nil, nil
+}
+
+func resolvePlayers(p graphql.ResolveParams) (any, error) {
+	gc := graphQLContextFrom(p)
+	limit, err := graphQLLimit(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	steamIDs, err := graphQLSteamIDs(p.Args["steamIds"])
+	if err != nil {
+		return nil, err
+	}
+	online, _ := p.Args["online"].(bool)
+
+	result, err := gc.st.CurrentPlayers(gc.ctx, &GetCurrentPlayersQuery{Online: online})
+	if err != nil {
+		return nil, err
+	}
+
+	players := make([]*PlayerCurrentState, 0, len(result.Players))
+	for _, state := range result.Players {
+		if len(steamIDs) > 0 && !slices.Contains(steamIDs, state.SteamID) {
+			continue
+		}
+		gc.players.prime(state.SteamID, state)
+		players = append(players, state)
+	}
+
+	return players[:min(len(players), limit)], nil
+}
+
+func resolvePlayerEvents(p graphql.ResolveParams) (any, error) {
+	gc := graphQLContextFrom(p)
+	limit, err := graphQLLimit(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := ""
+	query := SearchPlayerEventsQuery{
+		Limit:          limit,
+		Cursor:         &cursor,
+		PersonaName:    graphQLString(p.Args, "personaName"),
+		StartCreatedAt: graphQLTime(p.Args, "startCreatedAt"),
+		EndCreatedAt:   graphQLTime(p.Args, "endCreatedAt"),
+	}
+	if c := graphQLString(p.Args, "cursor"); c != nil {
+		query.Cursor = c
+	}
+	if query.SteamIDs, err = graphQLSteamIDs(p.Args["steamIds"]); err != nil {
+		return nil, err
+	}
+	types, _ := p.Args["types"].([]any)
+	for _, t := range types {
+		query.Types = append(query.Types, t.(PlayerEventType))
+	}
+	states, _ := p.Args["personaStates"].([]any)
+	for _, s := range states {
+		query.PersonaStates = append(query.PersonaStates, s.(PersonaState))
+	}
+	query.SortBy.CreatedAt = graphQLString(p.Args, "order")
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	return gc.st.SearchPlayerEvents(&query)
+}
+
+func resolveAuditLogs(p graphql.ResolveParams) (any, error) {
+	gc := graphQLContextFrom(p)
+	if !hasScope(gc.ctx, ScopeReadAudit) {
+		return nil, fmt.Errorf("auditLogs requires the %s scope", ScopeReadAudit)
+	}
+	limit, err := graphQLLimit(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor := ""
+	query := SearchAuditLogsQuery{
+		Limit:         limit,
+		Cursor:        &cursor,
+		Q:             graphQLString(p.Args, "q"),
+		Level:         graphQLString(p.Args, "level"),
+		Action:        graphQLString(p.Args, "action"),
+		StartLoggedAt: graphQLTime(p.Args, "startLoggedAt"),
+		EndLoggedAt:   graphQLTime(p.Args, "endLoggedAt"),
+	}
+	if c := graphQLString(p.Args, "cursor"); c != nil {
+		query.Cursor = c
+	}
+	if v, ok := p.Args["steamId"]; ok {
+		steamID, err := graphQLSteamID(v)
+		if err != nil {
+			return nil, err
+		}
+		query.SteamID = &steamID
+	}
+	if hasError, ok := p.Args["hasError"].(bool); ok {
+		query.HasError = &hasError
+	}
+	query.SortBy.ID = graphQLString(p.Args, "order")
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	return gc.st.SearchAuditLogs(&query)
+}
+
+// graphQLCost walks the selections of an operation to measure its depth and
+// complexity, following fragments. Introspection fields count like any
+// other, so they cannot be used to get around the limits.
+type graphQLCost struct {
+	schema    *graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+}
+
+func (c *graphQLCost) selectionSet(parent *graphql.Object, set *ast.SelectionSet, depth int) (cost, maxDepth int) {
+	if set == nil {
+		return 0, depth
+	}
+
+	maxDepth = depth
+	add := func(childCost, childDepth int) {
+		cost += childCost
+		maxDepth = max(maxDepth, childDepth)
+	}
+
+	for _, selection := range set.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			add(c.field(parent, selection, depth+1))
+		case *ast.InlineFragment:
+			add(c.selectionSet(c.typeCondition(parent, selection.TypeCondition), selection.SelectionSet, depth))
+		case *ast.FragmentSpread:
+			if fragment, ok := c.fragments[selection.Name.Value]; ok {
+				add(c.selectionSet(c.typeCondition(parent, fragment.TypeCondition), fragment.SelectionSet, depth))
+			}
+		}
+	}
+
+	return cost, maxDepth
+}
+
+func (c *graphQLCost) typeCondition(parent *graphql.Object, condition *ast.Named) *graphql.Object {
+	if condition != nil {
+		if object, ok := c.schema.Type(condition.Name.Value).(*graphql.Object); ok {
+			return object
+		}
+	}
+	return parent
+}
+
+func (c *graphQLCost) field(parent *graphql.Object, field *ast.Field, depth int) (int, int) {
+	name := field.Name.Value
+	def, ok := c.fieldDef(parent, name)
+	if !ok {
+		return 1, depth
+	}
+	key := parent.Name() + "." + name
+
+	cost := 1
+	if fieldCost, ok := graphQLFieldCosts[key]; ok {
+		cost = fieldCost
+	}
+
+	fieldType := def.Type
+	isList := false
+	for {
+		if nonNull, ok := fieldType.(*graphql.NonNull); ok {
+			fieldType = nonNull.OfType
+			continue
+		}
+		if list, ok := fieldType.(*graphql.List); ok {
+			isList = true
+			fieldType = list.OfType
+			continue
+		}
+		break
+	}
+	object, ok := fieldType.(*graphql.Object)
+	if !ok {
+		return cost, depth
+	}
+
+	size := 1
+	if limit, ok := c.limit(def, field); ok {
+		size = limit
+	} else if listSize, ok := graphQLListSizes[key]; ok {
+		size = listSize
+	} else if isList {
+		size = graphQLDefaultListSize
+	}
+
+	childCost, maxDepth := c.selectionSet(object, field.SelectionSet, depth)
+	return cost + size*childCost, maxDepth
+}
+
+// fieldDef looks up a field of parent, including the introspection fields
+// that every type has or, like __schema, the query type has.
+func (c *graphQLCost) fieldDef(parent *graphql.Object, name string) (*graphql.FieldDefinition, bool) {
+	switch name {
+	case graphql.SchemaMetaFieldDef.Name:
+		return graphql.SchemaMetaFieldDef, true
+	case graphql.TypeMetaFieldDef.Name:
+		return graphql.TypeMetaFieldDef, true
+	case graphql.TypeNameMetaFieldDef.Name:
+		return graphql.TypeNameMetaFieldDef, true
+	}
+
+	def, ok := parent.Fields()[name]
+	return def, ok
+}
+
+// limit returns the value of the limit argument of a field, if it has one.
+// Values that are not valid limits count as the largest allowed, the
+// resolver rejects them anyway.
+func (c *graphQLCost) limit(def *graphql.FieldDefinition, field *ast.Field) (int, bool) {
+	i := slices.IndexFunc(def.Args, func(arg *graphql.Argument) bool { return arg.Name() == "limit" })
+	if i < 0 {
+		return 0, false
+	}
+
+	var value any = def.Args[i].DefaultValue
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			value = v.Value
+		case *ast.Variable:
+			if v, ok := c.variables[v.Name.Value]; ok {
+				value = v
+			}
+		}
+	}
+
+	limit, err := strconv.Atoi(fmt.Sprint(value))
+	if err != nil || limit < 1 || limit > graphQLMaxLimit {
+		return graphQLMaxLimit, true
+	}
+	return limit, true
+}
+
+// checkGraphQLLimits rejects operations nested deeper than graphQLMaxDepth
+// or more complex than graphQLMaxComplexity.
+func checkGraphQLLimits(schema *graphql.Schema, doc *ast.Document, req *GraphQLRequest) error {
+	c := &graphQLCost{schema: schema, fragments: make(map[string]*ast.FragmentDefinition), variables: req.Variables}
+
+	var operation *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			c.fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			if req.OperationName == "" || (def.Name != nil && def.Name.Value == req.OperationName) {
+				operation = def
+			}
+		}
+	}
+	if operation == nil {
+		return nil
+	}
+
+	cost, depth := c.selectionSet(schema.QueryType(), operation.SelectionSet, 0)
+	if depth > graphQLMaxDepth {
+		return fmt.Errorf("query is nested %d levels deep, at most %d", depth, graphQLMaxDepth)
+	}
+	if cost > graphQLMaxComplexity {
+		return fmt.Errorf("query has a complexity of %d, at most %d", cost, graphQLMaxComplexity)
+	}
+
+	return nil
+}
+
+// ServeGraphQL answers GraphQL queries sent as a POST with a JSON body or
+// as a GET with query, operationName and variables parameters.
+func (st *SteamTracker) ServeGraphQL(w http.ResponseWriter, r *http.Request) {
+	req := GraphQLRequest{}
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, graphQLMaxBodySize)).Decode(&req); err != nil {
+			writeGraphQLError(w, http.StatusBadRequest, APIErrorInvalidQuery, fmt.Sprintf("invalid request body: %v", err))
+			return
+		}
+	} else {
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if v := r.URL.Query().Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeGraphQLError(w, http.StatusBadRequest, APIErrorInvalidQuery, fmt.Sprintf("invalid variables: %v", err))
+				return
+			}
+		}
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		writeGraphQLError(w, http.StatusBadRequest, APIErrorInvalidQuery, "query cannot be empty")
+		return
+	}
+
+	event := log.Debug().Str("action", "graphql").Str("operation_name", req.OperationName)
+	defer func() { event.Send() }()
+
+	schema, err := graphQLSchema()
+	if err != nil {
+		event.Err(err)
+		writeGraphQLError(w, http.StatusInternalServerError, APIErrorInternal, fmt.Sprintf("failed to build schema: %v", err))
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		writeGraphQLResult(w, http.StatusBadRequest, &graphql.Result{Errors: withCode(gqlerrors.FormatErrors(err), APIErrorInvalidQuery)})
+		return
+	}
+	if validation := graphql.ValidateDocument(&schema, doc, nil); !validation.IsValid {
+		writeGraphQLResult(w, http.StatusBadRequest, &graphql.Result{Errors: withCode(validation.Errors, APIErrorInvalidQuery)})
+		return
+	}
+	if err := checkGraphQLLimits(&schema, doc, &req); err != nil {
+		event.Err(err)
+		writeGraphQLError(w, http.StatusBadRequest, APIErrorLimitExceeded, err.Error())
+		return
+	}
+
+	gc := st.newGraphQLContext(r.Context())
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       context.WithValue(r.Context(), graphQLContextKey{}, gc),
+	})
+	if len(result.Errors) > 0 {
+		event.Int("errors", len(result.Errors)).Str("error", result.Errors[0].Message)
+	}
+
+	writeGraphQLResult(w, http.StatusOK, result)
+}
+
+// withCode sets the error code extension the other errors of /graphql have.
+func withCode(errs []gqlerrors.FormattedError, code string) []gqlerrors.FormattedError {
+	for i := range errs {
+		errs[i].Extensions = map[string]any{"code": code}
+	}
+	return errs
+}
+
+func writeGraphQLError(w http.ResponseWriter, status int, code, message string) {
+	writeGraphQLResult(w, status, &graphql.Result{Errors: []gqlerrors.FormattedError{{Message: message, Extensions: map[string]any{"code": code}}}})
+}
+
+func writeGraphQLResult(w http.ResponseWriter, status int, result *graphql.Result) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Error().Err(err).Msg("Failed to encode GraphQL response")
+	}
+}
@@ -0,0 +1,355 @@
+package steamtracker
+
+//go:generate buf generate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	steamtrackerv1 "github.com/willywotz/steam-tracker/proto/steamtracker/v1"
+)
+
+// grpcServer implements SteamTrackerService on top of the same queries as
+// the HTTP API.
+type grpcServer struct {
+	steamtrackerv1.UnimplementedSteamTrackerServiceServer
+
+	st *SteamTracker
+}
+
+// GRPCServer returns a gRPC server with SteamTrackerService registered.
+func (st *SteamTracker) GRPCServer() *grpc.Server {
+	gs := grpc.NewServer(
+		grpc.UnaryInterceptor(st.grpcUnaryAuth),
+		grpc.StreamInterceptor(st.grpcStreamAuth),
+	)
+	steamtrackerv1.RegisterSteamTrackerServiceServer(gs, &grpcServer{st: st})
+	return gs
+}
+
+func (s *grpcServer) SearchPlayers(ctx context.Context, req *steamtrackerv1.SearchPlayersRequest) (*steamtrackerv1.SearchPlayersResponse, error) {
+	query := SearchPlayersQuery{
+		Page:           int(req.GetPage()),
+		Limit:          int(req.GetLimit()),
+		Cursor:         req.Cursor,
+		IncludeTotal:   req.IncludeTotal,
+		SteamID:        (*SteamID)(req.SteamId),
+		StartCreatedAt: timeFromProto(req.GetStartCreatedAt()),
+		EndCreatedAt:   timeFromProto(req.GetEndCreatedAt()),
+	}
+	query.SortBy.CreatedAt = sortOrderFromProto(req.GetSortByCreatedAt())
+	if err := query.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	result, err := s.st.SearchPlayers(ctx, &query)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to search players: %v", err)
+	}
+
+	resp := &steamtrackerv1.SearchPlayersResponse{
+		Players:    make([]*steamtrackerv1.Player, 0, len(result.Players)),
+		Pagination: paginationToProto(result.Page, result.PerPage, result.TotalCount, result.NextCursor),
+	}
+	for _, p := range result.Players {
+		resp.Players = append(resp.Players, &steamtrackerv1.Player{
+			Id:           p.ID,
+			SteamId:      int64(p.SteamID),
+			ProfileState: int32(p.ProfileState),
+			PersonaName:  p.PersonaName,
+			AvatarHash:   p.AvatarHash,
+			LastLogoff:   int64(p.LastLogoff),
+			PersonaState: personaStateToProto(p.PersonaState),
+			GameId:       p.GameID,
+			CreatedAt:    timestamppb.New(p.CreatedAt),
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *grpcServer) SearchPlayerEvents(ctx context.Context, req *steamtrackerv1.SearchPlayerEventsRequest) (*steamtrackerv1.SearchPlayerEventsResponse, error) {
+	query := SearchPlayerEventsQuery{
+		Page:           int(req.GetPage()),
+		Limit:          int(req.GetLimit()),
+		Cursor:         req.Cursor,
+		IncludeTotal:   req.IncludeTotal,
+		SteamID:        (*SteamID)(req.SteamId),
+		PersonaName:    req.PersonaName,
+		StartCreatedAt: timeFromProto(req.GetStartCreatedAt()),
+		EndCreatedAt:   timeFromProto(req.GetEndCreatedAt()),
+	}
+	for _, steamID := range req.GetSteamIds() {
+		query.SteamIDs = append(query.SteamIDs, SteamID(steamID))
+	}
+	for _, t := range req.GetTypes() {
+		eventType, ok := playerEventTypesFromProto[t]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid type: %s", t)
+		}
+		query.Types = append(query.Types, eventType)
+	}
+	for _, ps := range req.GetPersonaStates() {
+		state, ok := personaStatesFromProto[ps]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid persona state: %s", ps)
+		}
+		query.PersonaStates = append(query.PersonaStates, state)
+	}
+	query.SortBy.CreatedAt = sortOrderFromProto(req.GetSortByCreatedAt())
+	if err := query.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	result, err := s.st.SearchPlayerEvents(&query)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to search player events: %v", err)
+	}
+
+	resp := &steamtrackerv1.SearchPlayerEventsResponse{
+		PlayerEvents: make([]*steamtrackerv1.PlayerEvent, 0, len(result.PlayerEvents)),
+		Pagination:   paginationToProto(result.Page, result.PerPage, result.TotalCount, result.NextCursor),
+	}
+	for _, e := range result.PlayerEvents {
+		resp.PlayerEvents = append(resp.PlayerEvents, playerEventToProto(e))
+	}
+
+	return resp, nil
+}
+
+func (s *grpcServer) SearchAuditLogs(ctx context.Context, req *steamtrackerv1.SearchAuditLogsRequest) (*steamtrackerv1.SearchAuditLogsResponse, error) {
+	query := SearchAuditLogsQuery{
+		Page:          int(req.GetPage()),
+		Limit:         int(req.GetLimit()),
+		Cursor:        req.Cursor,
+		IncludeTotal:  req.IncludeTotal,
+		Q:             req.Q,
+		Level:         req.Level,
+		Action:        req.Action,
+		SteamID:       (*SteamID)(req.SteamId),
+		StartLoggedAt: timeFromProto(req.GetStartLoggedAt()),
+		EndLoggedAt:   timeFromProto(req.GetEndLoggedAt()),
+		HasError:      req.HasError,
+	}
+	query.SortBy.ID = sortOrderFromProto(req.GetSortById())
+	query.SortBy.LoggedAt = sortOrderFromProto(req.GetSortByLoggedAt())
+	if err := query.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	result, err := s.st.SearchAuditLogs(&query)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to search audit logs: %v", err)
+	}
+
+	resp := &steamtrackerv1.SearchAuditLogsResponse{
+		AuditLogs:  make([]*steamtrackerv1.AuditLog, 0, len(result.AuditLogs)),
+		Pagination: paginationToProto(result.Page, result.PerPage, result.TotalCount, result.NextCursor),
+	}
+	for _, l := range result.AuditLogs {
+		auditLog := &steamtrackerv1.AuditLog{
+			Id:        l.ID,
+			Raw:       string(l.Raw),
+			Level:     l.Level,
+			Message:   l.Message,
+			Action:    l.Action,
+			SteamId:   (*int64)(l.SteamID),
+			Error:     l.Error,
+			PrevHash:  l.PrevHash,
+			Hash:      l.Hash,
+			CreatedAt: timestamppb.New(l.CreatedAt),
+			Snippet:   l.Snippet,
+		}
+		if l.LoggedAt != nil {
+			auditLog.LoggedAt = timestamppb.New(*l.LoggedAt)
+		}
+		resp.AuditLogs = append(resp.AuditLogs, auditLog)
+	}
+
+	return resp, nil
+}
+
+// WatchPlayerEvents sends player events while they are created, like the
+// event stream of the HTTP API.
+func (s *grpcServer) WatchPlayerEvents(req *steamtrackerv1.WatchPlayerEventsRequest, stream grpc.ServerStreamingServer[steamtrackerv1.WatchPlayerEventsResponse]) error {
+	query := StreamPlayerEventsQuery{LastEventID: req.LastEventId}
+	for _, steamID := range req.GetSteamIds() {
+		query.SteamIDs = append(query.SteamIDs, SteamID(steamID))
+	}
+	if err := query.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ctx := stream.Context()
+	event := log.Debug().Str("action", "watch_player_events").Int("steam_ids", len(query.SteamIDs))
+	defer func() { event.Send() }()
+
+	// Subscribe before reading missed events, as the HTTP event stream does.
+	sub := s.st.playerEvents.subscribe()
+	defer s.st.playerEvents.unsubscribe(sub)
+
+	sent := 0
+	send := func(e *PlayerEvent) error {
+		if err := stream.Send(&steamtrackerv1.WatchPlayerEventsResponse{PlayerEvent: playerEventToProto(e)}); err != nil {
+			return err
+		}
+		sent++
+		return nil
+	}
+
+	var resumedTo int64
+	if query.LastEventID != nil {
+		event.Int64("last_event_id", *query.LastEventID)
+
+		var err error
+		resumedTo, err = s.st.resumePlayerEvents(ctx, &query, send)
+		if err != nil {
+			event.Err(err)
+			if _, ok := status.FromError(err); ok {
+				return err
+			}
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	for {
+		select {
+		case e, ok := <-sub.events:
+			if !ok {
+				event.Bool("dropped", true).Int("sent", sent)
+				return status.Error(codes.ResourceExhausted, "too slow to receive events, resume with last_event_id")
+			}
+			if e.ID <= resumedTo || !query.matches(e) {
+				continue
+			}
+			if err := send(e); err != nil {
+				event.Int("sent", sent)
+				return err
+			}
+		case <-ctx.Done():
+			event.Int("sent", sent)
+			return status.FromContextError(ctx.Err()).Err()
+		case <-s.st.ctx.Done():
+			event.Int("sent", sent)
+			return status.Error(codes.Unavailable, "server is shutting down")
+		}
+	}
+}
+
+var personaStatesToProto = map[PersonaState]steamtrackerv1.PersonaState{
+	PersonaStateUnknown:        steamtrackerv1.PersonaState_PERSONA_STATE_UNKNOWN,
+	PersonaStateOffline:        steamtrackerv1.PersonaState_PERSONA_STATE_OFFLINE,
+	PersonaStateOnline:         steamtrackerv1.PersonaState_PERSONA_STATE_ONLINE,
+	PersonaStateBusy:           steamtrackerv1.PersonaState_PERSONA_STATE_BUSY,
+	PersonaStateAway:           steamtrackerv1.PersonaState_PERSONA_STATE_AWAY,
+	PersonaStateSnooze:         steamtrackerv1.PersonaState_PERSONA_STATE_SNOOZE,
+	PersonaStateLookingToTrade: steamtrackerv1.PersonaState_PERSONA_STATE_LOOKING_TO_TRADE,
+	PersonaStateLookingToPlay:  steamtrackerv1.PersonaState_PERSONA_STATE_LOOKING_TO_PLAY,
+}
+
+var personaStatesFromProto = invertMap(personaStatesToProto)
+
+var playerEventTypesToProto = map[PlayerEventType]steamtrackerv1.PlayerEventType{
+	PlayerEventTypeFirstSeen:    steamtrackerv1.PlayerEventType_PLAYER_EVENT_TYPE_FIRST_SEEN,
+	PlayerEventTypePersonaState: steamtrackerv1.PlayerEventType_PLAYER_EVENT_TYPE_PERSONA_STATE,
+	PlayerEventTypeGame:         steamtrackerv1.PlayerEventType_PLAYER_EVENT_TYPE_GAME,
+}
+
+var playerEventTypesFromProto = invertMap(playerEventTypesToProto)
+
+func invertMap[K, V comparable](m map[K]V) map[V]K {
+	inverted := make(map[V]K, len(m))
+	for k, v := range m {
+		inverted[v] = k
+	}
+	return inverted
+}
+
+func personaStateToProto(ps PersonaState) steamtrackerv1.PersonaState {
+	if v, ok := personaStatesToProto[ps]; ok {
+		return v
+	}
+	return steamtrackerv1.PersonaState_PERSONA_STATE_UNKNOWN
+}
+
+func playerEventToProto(e *PlayerEvent) *steamtrackerv1.PlayerEvent {
+	return &steamtrackerv1.PlayerEvent{
+		Id:           e.ID,
+		SteamId:      int64(e.SteamID),
+		Type:         playerEventTypesToProto[e.Type],
+		PersonaName:  e.PersonaName,
+		PersonaState: personaStateToProto(e.PersonaState),
+		GameId:       e.GameID,
+		CreatedAt:    timestamppb.New(e.CreatedAt),
+	}
+}
+
+func paginationToProto(page, perPage int, totalCount *int64, nextCursor string) *steamtrackerv1.Pagination {
+	return &steamtrackerv1.Pagination{
+		Page:       int32(page),
+		PerPage:    int32(perPage),
+		TotalCount: totalCount,
+		NextCursor: nextCursor,
+	}
+}
+
+func timeFromProto(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+func sortOrderFromProto(order steamtrackerv1.SortOrder) *string {
+	var s string
+	switch order {
+	case steamtrackerv1.SortOrder_SORT_ORDER_ASC:
+		s = "asc"
+	case steamtrackerv1.SortOrder_SORT_ORDER_DESC:
+		s = "desc"
+	default:
+		return nil
+	}
+	return &s
+}
+
+// serveGRPC serves gRPC until Stop, if a gRPC port is configured.
+func (st *SteamTracker) serveGRPC() {
+	if st.gs == nil {
+		return
+	}
+
+	if err := st.gs.Serve(st.grpcLn); err != nil {
+		log.Error().Err(err).Msg("gRPC server stopped")
+	}
+}
+
+// stopGRPC waits for running calls to finish until ctx is done, then
+// cancels the rest. Streams end on their own once st.ctx is canceled.
+func (st *SteamTracker) stopGRPC(ctx context.Context) error {
+	if st.gs == nil {
+		return nil
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		st.gs.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		st.gs.Stop()
+		return fmt.Errorf("failed to stop gRPC server gracefully: %w", ctx.Err())
+	}
+}
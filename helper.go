@@ -1,7 +1,36 @@
 package steamtracker
 
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
 func setOptional[T any](value *T, add func(v T)) {
 	if value != nil {
 		add(*value)
 	}
 }
+
+// orderBy builds an ORDER BY column that GORM quotes for the active dialect,
+// so sort clauses stay portable across SQLite, PostgreSQL and MySQL.
+func orderBy(table, column, order string) clause.OrderByColumn {
+	return clause.OrderByColumn{
+		Column: clause.Column{Table: table, Name: column},
+		Desc:   order == "desc",
+	}
+}
+
+// validateSortOrder lowercases order, if set, and reports sort_by[column] in
+// bindErr unless it is asc or desc.
+func validateSortOrder(bindErr *BindError, column string, order *string) {
+	if order == nil {
+		return
+	}
+
+	*order = strings.ToLower(*order)
+	if *order != "asc" && *order != "desc" {
+		bindErr.add("sort_by["+column+"]", fmt.Sprintf("must be asc or desc, got %s", *order))
+	}
+}
@@ -36,7 +36,7 @@ func GetPlayerSummaries(client *http.Client, apiKey string, steamID string, maxR
 
 		resp, err := client.Do(req)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get player summaries: %w", err)
+			return nil, fmt.Errorf("failed to get player summaries: %w", redactURLError(err))
 		}
 		defer resp.Body.Close()
 
@@ -0,0 +1,532 @@
+package steamtracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/rs/zerolog/log"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const importBatchSize = 1000
+
+type ImportCommand struct {
+	Table  string `json:"table"`
+	Format string `json:"format"`
+}
+
+func (cmd *ImportCommand) Validate() error {
+	query := ExportQuery{Table: cmd.Table, Format: cmd.Format}
+	if err := query.Validate(); err != nil {
+		return err
+	}
+	cmd.Format = query.Format
+
+	return nil
+}
+
+type ImportResult struct {
+	Table    string `json:"table"`
+	Read     int64  `json:"read"`
+	Inserted int64  `json:"inserted"`
+	Skipped  int64  `json:"skipped"`  // already present
+	Remapped int64  `json:"remapped"` // inserted under a new ID because theirs was taken, or always for audit logs
+	// SteamIDs, Since and Until describe the players and player events that
+	// were inserted, and bound what RebuildDerivedData recomputes.
+	SteamIDs []SteamID  `json:"steam_ids,omitempty"`
+	Since    *time.Time `json:"since,omitempty"`
+	Until    *time.Time `json:"until,omitempty"`
+}
+
+// addInserted records an inserted player or player event.
+func (r *ImportResult) addInserted(steamID SteamID, createdAt time.Time) {
+	if i, found := slices.BinarySearch(r.SteamIDs, steamID); !found {
+		r.SteamIDs = slices.Insert(r.SteamIDs, i, steamID)
+	}
+	if r.Since == nil || createdAt.Before(*r.Since) {
+		r.Since = &createdAt
+	}
+	if r.Until == nil || createdAt.After(*r.Until) {
+		r.Until = &createdAt
+	}
+}
+
+// importTable knows how to turn an export row back into a model and how to
+// recognise a row that is already stored under a different ID.
+type importTable struct {
+	newRow  func() any
+	toModel func(row any) importModel
+}
+
+type importModel interface {
+	getID() int64
+	setID(id int64)
+	// sameAs reports whether other describes the same recorded fact.
+	sameAs(other importModel) bool
+	// duplicates narrows tx to rows recording the same fact.
+	duplicates(tx *gorm.DB) *gorm.DB
+}
+
+func (p *Player) getID() int64   { return p.ID }
+func (p *Player) setID(id int64) { p.ID = id }
+func (p *Player) sameAs(other importModel) bool {
+	o, ok := other.(*Player)
+	return ok && o.SteamID == p.SteamID && o.CreatedAt.Equal(p.CreatedAt)
+}
+func (p *Player) duplicates(tx *gorm.DB) *gorm.DB {
+	return tx.Model(&Player{}).Where("steam_id = ? AND created_at = ?", p.SteamID, p.CreatedAt)
+}
+
+func (e *PlayerEvent) getID() int64   { return e.ID }
+func (e *PlayerEvent) setID(id int64) { e.ID = id }
+func (e *PlayerEvent) sameAs(other importModel) bool {
+	o, ok := other.(*PlayerEvent)
+	return ok && o.SteamID == e.SteamID && o.PersonaState == e.PersonaState && o.CreatedAt.Equal(e.CreatedAt)
+}
+func (e *PlayerEvent) duplicates(tx *gorm.DB) *gorm.DB {
+	return tx.Model(&PlayerEvent{}).Where("steam_id = ? AND persona_state = ? AND created_at = ?", e.SteamID, e.PersonaState, e.CreatedAt)
+}
+
+func (al *AuditLog) getID() int64   { return al.ID }
+func (al *AuditLog) setID(id int64) { al.ID = id }
+func (al *AuditLog) sameAs(other importModel) bool {
+	o, ok := other.(*AuditLog)
+	return ok && bytes.Equal(o.Raw, al.Raw) && o.CreatedAt.Equal(al.CreatedAt)
+}
+func (al *AuditLog) duplicates(tx *gorm.DB) *gorm.DB {
+	return tx.Model(&AuditLog{}).Where("created_at = ? AND raw = ?", al.CreatedAt, al.Raw)
+}
+
+var importTables = map[string]importTable{
+	"players": {
+		newRow: func() any { return &playerExportRow{} },
+		toModel: func(row any) importModel {
+			r := row.(*playerExportRow)
+			return &Player{
+				ID:           r.ID,
+				SteamID:      r.SteamID,
+				ProfileState: r.ProfileState,
+				PersonaName:  r.PersonaName,
+				AvatarHash:   r.AvatarHash,
+				LastLogoff:   r.LastLogoff,
+				PersonaState: r.PersonaState,
+				GameID:       r.GameID,
+				CreatedAt:    r.CreatedAt,
+			}
+		},
+	},
+	"player_events": {
+		newRow: func() any { return &playerEventExportRow{} },
+		toModel: func(row any) importModel {
+			r := row.(*playerEventExportRow)
+			eventType := r.Type
+			if eventType == "" {
+				// Exports made before event types existed.
+				eventType = PlayerEventTypePersonaState
+			}
+			return &PlayerEvent{
+				ID:           r.ID,
+				SteamID:      r.SteamID,
+				Type:         eventType,
+				PersonaName:  r.PersonaName,
+				PersonaState: r.PersonaState,
+				GameID:       r.GameID,
+				CreatedAt:    r.CreatedAt,
+			}
+		},
+	},
+	"audit_logs": {
+		newRow: func() any { return &auditLogExportRow{} },
+		toModel: func(row any) importModel {
+			r := row.(*auditLogExportRow)
+			auditLog := &AuditLog{
+				ID:        r.ID,
+				Raw:       JSON(r.Raw),
+				CreatedAt: r.CreatedAt,
+			}
+			auditLog.setFields(parseAuditLogFields([]byte(r.Raw)))
+			return auditLog
+		},
+	},
+}
+
+// Import reads rows in one of the export formats and stores the ones that
+// are not present yet. Running the same import twice is a no-op.
+func (st *SteamTracker) Import(ctx context.Context, r io.Reader, cmd *ImportCommand) (*ImportResult, error) {
+	event := log.Info().
+		Str("action", "import").
+		Str("table", cmd.Table).
+		Str("format", cmd.Format)
+	defer func() { event.Send() }()
+
+	result := ImportResult{Table: cmd.Table}
+	table := importTables[cmd.Table]
+
+	batch := make([]any, 0, importBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := st.importRows(ctx, table, batch, &result)
+		batch = batch[:0]
+		return err
+	}
+
+	err := decodeRows(r, cmd.Format, table.newRow, func(row any) error {
+		result.Read++
+		batch = append(batch, row)
+		if len(batch) < importBatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		err = flush()
+	}
+
+	event.Int64("read", result.Read).
+		Int64("inserted", result.Inserted).
+		Int64("skipped", result.Skipped).
+		Int64("remapped", result.Remapped)
+	if err != nil {
+		event.Err(err)
+	}
+
+	return &result, err
+}
+
+// ImportDatabase copies every exportable table from another steamtracker
+// SQLite file into this database.
+func (st *SteamTracker) ImportDatabase(ctx context.Context, path string) ([]*ImportResult, error) {
+	event := log.Info().
+		Str("action", "import_database").
+		Str("path", path)
+	defer func() { event.Send() }()
+
+	src, err := gorm.Open(sqlite.Open(path), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to open source database: %w", err)
+		event.Err(err)
+		return nil, err
+	}
+	if sqlDB, err := src.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	results := make([]*ImportResult, 0, len(exportTables))
+	for _, name := range ExportTableNames() {
+		result := ImportResult{Table: name}
+		results = append(results, &result)
+
+		if !src.Migrator().HasTable(name) {
+			continue
+		}
+
+		err := exportTables[name].iterate(src.WithContext(ctx), nil, nil, func(rows []any) error {
+			result.Read += int64(len(rows))
+			return st.importRows(ctx, importTables[name], rows, &result)
+		})
+		if err != nil {
+			err = fmt.Errorf("failed to import %s: %w", name, err)
+			event.Err(err)
+			return results, err
+		}
+	}
+
+	return results, nil
+}
+
+func (st *SteamTracker) importRows(ctx context.Context, table importTable, rows []any, result *ImportResult) error {
+	if _, ok := table.newRow().(*auditLogExportRow); ok {
+		st.auditChainMu.Lock()
+		defer st.auditChainMu.Unlock()
+	}
+
+	return st.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, row := range rows {
+			model := table.toModel(row)
+
+			if model.getID() != 0 {
+				existing := reflect.New(reflect.TypeOf(model).Elem()).Interface().(importModel)
+				err := tx.Where("id = ?", model.getID()).First(existing).Error
+				switch {
+				case err == nil && existing.sameAs(model):
+					result.Skipped++
+					continue
+				case err == nil:
+					// Another tracker generated the same snowflake ID.
+					model.setID(0)
+				case !errors.Is(err, gorm.ErrRecordNotFound):
+					return fmt.Errorf("failed to look up row %d: %w", model.getID(), err)
+				}
+			}
+
+			var count int64
+			if err := model.duplicates(tx).Count(&count).Error; err != nil {
+				return fmt.Errorf("failed to check for duplicates: %w", err)
+			}
+			if count > 0 {
+				result.Skipped++
+				continue
+			}
+
+			// Imported audit logs are appended to the hash chain, which
+			// needs IDs newer than every stored row.
+			auditLog, chained := model.(*AuditLog)
+			if chained {
+				auditLog.ID = 0
+			}
+
+			if model.getID() == 0 {
+				model.setID(st.GenerateID())
+				result.Remapped++
+			}
+
+			if chained {
+				if err := chainAuditLogs(tx, []*AuditLog{auditLog}); err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Create(model).Error; err != nil {
+				return fmt.Errorf("failed to insert row: %w", err)
+			}
+			result.Inserted++
+
+			switch m := model.(type) {
+			case *Player:
+				result.addInserted(m.SteamID, m.CreatedAt)
+			case *PlayerEvent:
+				result.addInserted(m.SteamID, m.CreatedAt)
+			}
+		}
+
+		return nil
+	})
+}
+
+// RebuildDerivedData recomputes what is derived from the rows the imports
+// inserted: consecutive duplicate player events left behind by merging two
+// histories are collapsed, and the rollups and current states of the imported
+// players are rebuilt over the imported time range. Other players, and the
+// rollups of days that were not imported, are left alone.
+func (st *SteamTracker) RebuildDerivedData(ctx context.Context, results []*ImportResult) error {
+	imported := ImportResult{}
+	for _, r := range results {
+		for _, steamID := range r.SteamIDs {
+			imported.addInserted(steamID, *r.Since)
+			imported.addInserted(steamID, *r.Until)
+		}
+	}
+	if len(imported.SteamIDs) == 0 {
+		return nil
+	}
+
+	if _, err := st.collapsePlayerEvents(ctx, imported.SteamIDs); err != nil {
+		return fmt.Errorf("failed to collapse player events: %w", err)
+	}
+
+	// Intervals between an imported snapshot and a stored one up to a max
+	// gap away change the buckets just outside the imported range too.
+	maxGap := st.rollupMaxGap()
+	since, until := imported.Since.Add(-maxGap), imported.Until.Add(maxGap)
+	cmd := RebuildPlayerRollupsCommand{SteamIDs: imported.SteamIDs, Since: &since, Until: &until}
+	if _, err := st.RebuildPlayerRollups(ctx, &cmd); err != nil {
+		return fmt.Errorf("failed to rebuild player rollups: %w", err)
+	}
+
+	if _, err := st.RebuildPlayerCurrentStates(ctx, imported.SteamIDs); err != nil {
+		return fmt.Errorf("failed to rebuild player current states: %w", err)
+	}
+
+	return nil
+}
+
+// collapsePlayerEvents deletes events that repeat the previous event's
+// persona state and game for the same player, so each event is a real
+// transition. Only the events of steamIDs are checked.
+func (st *SteamTracker) collapsePlayerEvents(ctx context.Context, steamIDs []SteamID) (int64, error) {
+	event := log.Info().Str("action", "collapse_player_events").Int("steam_ids", len(steamIDs))
+	defer func() { event.Send() }()
+
+	type playerEventState struct {
+		personaState PersonaState
+		gameID       string
+	}
+	lastState := make(map[SteamID]playerEventState)
+	redundant := make([]int64, 0)
+
+	events := st.db.WithContext(ctx).Where("steam_id IN ?", steamIDs).Session(&gorm.Session{})
+	err := exportTables["player_events"].iterate(events, nil, nil, func(rows []any) error {
+		for _, row := range rows {
+			e := row.(*playerEventExportRow)
+			current := playerEventState{personaState: e.PersonaState, gameID: e.GameID}
+			if state, ok := lastState[e.SteamID]; ok && state == current {
+				redundant = append(redundant, e.ID)
+				continue
+			}
+			lastState[e.SteamID] = current
+		}
+		return nil
+	})
+	if err != nil {
+		event.Err(err)
+		return 0, err
+	}
+
+	var deleted int64
+	for start := 0; start < len(redundant); start += importBatchSize {
+		n, err := st.deleteIDs(ctx, &PlayerEvent{}, redundant[start:min(start+importBatchSize, len(redundant))])
+		deleted += n
+		if err != nil {
+			event.Err(err)
+			return deleted, err
+		}
+	}
+
+	event.Int64("deleted", deleted)
+	return deleted, nil
+}
+
+func decodeRows(r io.Reader, format string, newRow func() any, fn func(row any) error) error {
+	switch format {
+	case ExportFormatJSONL:
+		dec := json.NewDecoder(r)
+		for line := 1; ; line++ {
+			row := newRow()
+			if err := dec.Decode(row); errors.Is(err, io.EOF) {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("invalid JSON on record %d: %w", line, err)
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+	case ExportFormatCSV:
+		cr := csv.NewReader(r)
+		header, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read CSV header: %w", err)
+		}
+		for line := 2; ; line++ {
+			record, err := cr.Read()
+			if errors.Is(err, io.EOF) {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("failed to read CSV line %d: %w", line, err)
+			}
+			row := newRow()
+			if err := parseRecord(header, record, row); err != nil {
+				return fmt.Errorf("invalid CSV line %d: %w", line, err)
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+	case ExportFormatParquet:
+		ra, ok := r.(io.ReaderAt)
+		if !ok {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return fmt.Errorf("failed to read parquet input: %w", err)
+			}
+			ra = bytes.NewReader(data)
+		}
+		pr := parquet.NewReader(ra, parquet.SchemaOf(newRow()))
+		defer pr.Close()
+		for {
+			row := newRow()
+			if err := pr.Read(row); errors.Is(err, io.EOF) {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("failed to read parquet row: %w", err)
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported import format: %s", format)
+	}
+}
+
+// parseRecord is the inverse of exportRecord.
+func parseRecord(header, record []string, row any) error {
+	v := reflect.ValueOf(row).Elem()
+	fields := make(map[string]int, v.NumField())
+	for i, column := range exportColumns(row) {
+		fields[column] = i
+	}
+
+	for i, column := range header {
+		idx, ok := fields[column]
+		if !ok || i >= len(record) {
+			continue
+		}
+		value := record[i]
+
+		switch f := v.Field(idx).Addr().Interface().(type) {
+		case *time.Time:
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", column, err)
+			}
+			*f = t
+		case *PersonaState:
+			if n, err := strconv.Atoi(value); err == nil {
+				*f = PersonaState(n)
+			} else if err := f.fromString(value); err != nil {
+				return fmt.Errorf("invalid %s: %w", column, err)
+			}
+		case *PlayerEventType:
+			if t := PlayerEventType(value); t.valid() {
+				*f = t
+			} else {
+				return fmt.Errorf("invalid %s: %s", column, value)
+			}
+		case *SteamID:
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", column, err)
+			}
+			*f = SteamID(n)
+		case *int64:
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", column, err)
+			}
+			*f = n
+		case *int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", column, err)
+			}
+			*f = n
+		case *RawString:
+			*f = RawString(value)
+		case *string:
+			*f = value
+		default:
+			return fmt.Errorf("unsupported column type for %s", column)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package steamtracker_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+func importJSONL(t *testing.T, st *steamtracker.SteamTracker, table string, rows ...map[string]any) *steamtracker.ImportResult {
+	t.Helper()
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			t.Fatalf("Failed to encode row: %v", err)
+		}
+	}
+
+	result, err := st.Import(t.Context(), &buf, &steamtracker.ImportCommand{Table: table, Format: steamtracker.ExportFormatJSONL})
+	if err != nil {
+		t.Fatalf("Failed to import %s: %v", table, err)
+	}
+	return result
+}
+
+func playerRows(steamID steamtracker.SteamID, state steamtracker.PersonaState, times ...time.Time) []map[string]any {
+	rows := make([]map[string]any, 0, len(times))
+	for _, at := range times {
+		rows = append(rows, map[string]any{"steam_id": steamID, "persona_name": "Test Player", "persona_state": state, "created_at": at})
+	}
+	return rows
+}
+
+func eventRow(steamID steamtracker.SteamID, eventType steamtracker.PlayerEventType, state steamtracker.PersonaState, at time.Time) map[string]any {
+	return map[string]any{"steam_id": steamID, "type": eventType, "persona_name": "Test Player", "persona_state": state, "created_at": at}
+}
+
+func countPlayerEvents(t *testing.T, st *steamtracker.SteamTracker, steamID steamtracker.SteamID) int {
+	t.Helper()
+
+	query := steamtracker.SearchPlayerEventsQuery{SteamID: &steamID, Limit: 100}
+	if err := query.Validate(); err != nil {
+		t.Fatalf("Invalid query: %v", err)
+	}
+	result, err := st.SearchPlayerEvents(&query)
+	if err != nil {
+		t.Fatalf("Failed to search player events: %v", err)
+	}
+	return len(result.PlayerEvents)
+}
+
+func currentPlayer(t *testing.T, st *steamtracker.SteamTracker, steamID steamtracker.SteamID) *steamtracker.PlayerCurrentState {
+	t.Helper()
+
+	state, err := st.CurrentPlayer(t.Context(), &steamtracker.GetCurrentPlayerQuery{SteamID: steamID})
+	if err != nil {
+		t.Fatalf("Failed to get current player: %v", err)
+	}
+	return state // nil if the player has no current state
+}
+
+func rebuildDerivedData(t *testing.T, st *steamtracker.SteamTracker, results ...*steamtracker.ImportResult) {
+	t.Helper()
+
+	if err := st.RebuildDerivedData(t.Context(), results); err != nil {
+		t.Fatalf("Failed to rebuild derived data: %v", err)
+	}
+}
+
+func TestImportExportedHistoryTwice(t *testing.T) {
+	src := openTestTracker(t, testDSNs(t)["sqlite"])
+	steamID := uniqueSteamID()
+
+	start := utcDay(time.Now()).Add(-12 * time.Hour)
+	rebuildDerivedData(t, src,
+		importJSONL(t, src, "players", append(playerRows(steamID, steamtracker.PersonaStateOnline, minutes(start, 3)...),
+			playerRows(steamID, steamtracker.PersonaStateOffline, start.Add(4*time.Minute))...)...),
+		importJSONL(t, src, "player_events",
+			eventRow(steamID, steamtracker.PlayerEventTypeFirstSeen, steamtracker.PersonaStateOnline, start),
+			eventRow(steamID, steamtracker.PlayerEventTypePersonaState, steamtracker.PersonaStateOffline, start.Add(4*time.Minute))),
+	)
+
+	dst := openTestTracker(t, testDSNs(t)["sqlite"])
+	importAll := func() []*steamtracker.ImportResult {
+		results := make([]*steamtracker.ImportResult, 0, 2)
+		for _, table := range []string{"players", "player_events"} {
+			data := exportBytes(t, src, steamtracker.ExportQuery{Table: table})
+			result, err := dst.Import(t.Context(), bytes.NewReader(data), &steamtracker.ImportCommand{Table: table, Format: steamtracker.ExportFormatJSONL})
+			if err != nil {
+				t.Fatalf("Failed to import %s: %v", table, err)
+			}
+			results = append(results, result)
+		}
+		rebuildDerivedData(t, dst, results...)
+		return results
+	}
+
+	importAll()
+	rollups, state := dailyOnlineSeconds(t, dst, steamID), currentPlayer(t, dst, steamID)
+	if state == nil {
+		t.Fatal("Expected a current state for the imported player")
+	}
+	if want := dailyOnlineSeconds(t, src, steamID); !reflect.DeepEqual(rollups, want) || rollups[utcDay(start)] != 240 {
+		t.Errorf("Expected the rollups of the exporting tracker, 240 online seconds, got %v, want %v", rollups, want)
+	}
+	if want := currentPlayer(t, src, steamID); state.PersonaState != steamtracker.PersonaStateOffline || !state.StateSince.Equal(want.StateSince) || state.LastEventID != want.LastEventID {
+		t.Errorf("Expected the current state of the exporting tracker, got %+v, want %+v", state, want)
+	}
+	players, events := exportedRows(t, dst, "players"), exportedRows(t, dst, "player_events")
+
+	for _, result := range importAll() {
+		if result.Inserted != 0 || result.Skipped != result.Read || len(result.SteamIDs) != 0 {
+			t.Errorf("Expected every %s row to be skipped, got %+v", result.Table, result)
+		}
+	}
+	if got := dailyOnlineSeconds(t, dst, steamID); !reflect.DeepEqual(got, rollups) {
+		t.Errorf("Expected the rollups to stay %v, got %v", rollups, got)
+	}
+	if got := currentPlayer(t, dst, steamID); !reflect.DeepEqual(got, state) {
+		t.Errorf("Expected the current state to stay %+v, got %+v", state, got)
+	}
+	if !reflect.DeepEqual(exportedRows(t, dst, "players"), players) || !reflect.DeepEqual(exportedRows(t, dst, "player_events"), events) {
+		t.Error("Expected importing the same rows again to change nothing")
+	}
+}
+
+func TestRebuildDerivedDataOnlyForImportedPlayers(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	imported, other := uniqueSteamID(), uniqueSteamID()+1
+
+	start := utcDay(time.Now()).Add(-12 * time.Hour)
+	// The second event repeats the first, as merging two histories can.
+	otherResults := []*steamtracker.ImportResult{
+		importJSONL(t, st, "players", playerRows(other, steamtracker.PersonaStateOnline, minutes(start, 3)...)...),
+		importJSONL(t, st, "player_events",
+			eventRow(other, steamtracker.PlayerEventTypeFirstSeen, steamtracker.PersonaStateOnline, start),
+			eventRow(other, steamtracker.PlayerEventTypePersonaState, steamtracker.PersonaStateOnline, start.Add(time.Minute))),
+	}
+	result := importJSONL(t, st, "players", playerRows(imported, steamtracker.PersonaStateOnline, minutes(start, 2)...)...)
+	if !reflect.DeepEqual(result.SteamIDs, []steamtracker.SteamID{imported}) || !result.Since.Equal(start) || !result.Until.Equal(start.Add(2*time.Minute)) {
+		t.Fatalf("Expected the import to record the player and its time range, got %+v", result)
+	}
+
+	rebuildDerivedData(t, st, result)
+	if got := dailyOnlineSeconds(t, st, imported)[utcDay(start)]; got != 120 {
+		t.Errorf("Expected 120 online seconds for the imported player, got %d", got)
+	}
+	if got := dailyOnlineSeconds(t, st, other); len(got) != 0 {
+		t.Errorf("Expected no rollups for the other player, got %v", got)
+	}
+	if got := countPlayerEvents(t, st, other); got != 2 {
+		t.Errorf("Expected the events of the other player to be left alone, got %d", got)
+	}
+	if state := currentPlayer(t, st, other); state != nil {
+		t.Errorf("Expected no current state for the other player, got %+v", state)
+	}
+
+	rebuildDerivedData(t, st, otherResults...)
+	if got := countPlayerEvents(t, st, other); got != 1 {
+		t.Errorf("Expected the repeated event to be collapsed, got %d events", got)
+	}
+	if got := dailyOnlineSeconds(t, st, other)[utcDay(start)]; got != 180 {
+		t.Errorf("Expected 180 online seconds for the other player, got %d", got)
+	}
+	if state := currentPlayer(t, st, other); state == nil || !state.StateSince.Equal(start) {
+		t.Errorf("Expected the other player to be online since %s, got %+v", start, state)
+	}
+}
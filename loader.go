@@ -0,0 +1,79 @@
+package steamtracker
+
+import (
+	"sync"
+)
+
+// batchLoader loads values by key for the resolvers of one GraphQL request.
+//
+// load only queues its key and returns a thunk. The executor calls thunks
+// after it has visited every field at the same level of the query, so the
+// first thunk called fetches all queued keys at once and the rest find their
+// values cached. Resolving a field on each item of a list then costs one
+// query instead of one per item.
+type batchLoader[K comparable, V any] struct {
+	// fetch returns the values of keys. Keys without a value get the zero
+	// value of V.
+	fetch func(keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	queued  map[K]bool
+	values  map[K]V
+	errs    map[K]error
+}
+
+func newBatchLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *batchLoader[K, V] {
+	return &batchLoader[K, V]{
+		fetch:  fetch,
+		queued: make(map[K]bool),
+		values: make(map[K]V),
+		errs:   make(map[K]error),
+	}
+}
+
+// load queues key and returns a thunk resolving to its value.
+func (l *batchLoader[K, V]) load(key K) func() (any, error) {
+	l.mu.Lock()
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (any, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 {
+			keys := l.pending
+			l.pending = nil
+
+			values, err := l.fetch(keys)
+			for _, k := range keys {
+				if err != nil {
+					l.errs[k] = err
+					continue
+				}
+				l.values[k] = values[k]
+			}
+		}
+
+		if err := l.errs[key]; err != nil {
+			return nil, err
+		}
+		return l.values[key], nil
+	}
+}
+
+// prime caches a value loaded by other means, so loading key later does
+// not fetch it again.
+func (l *batchLoader[K, V]) prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.values[key] = value
+	}
+}
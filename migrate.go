@@ -0,0 +1,217 @@
+package steamtracker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Migration is a single, ordered schema change. Up and Down run inside a
+// transaction together with the schema_version bookkeeping, so a failed
+// migration leaves the recorded version untouched.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+type SchemaVersion struct {
+	Version   int       `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+func (SchemaVersion) TableName() string {
+	return "schema_version"
+}
+
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+// LatestSchemaVersion is the newest schema version this binary knows about.
+func LatestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+func (st *SteamTracker) ensureSchemaVersionTable() error {
+	if err := st.db.WithContext(st.ctx).AutoMigrate(&SchemaVersion{}); err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the highest migration version applied to the database.
+func (st *SteamTracker) SchemaVersion() (int, error) {
+	if err := st.ensureSchemaVersionTable(); err != nil {
+		return 0, err
+	}
+
+	var version int
+	if err := st.db.WithContext(st.ctx).Model(&SchemaVersion{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error; err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	return version, nil
+}
+
+func (st *SteamTracker) checkSchemaNotNewer() (int, error) {
+	current, err := st.SchemaVersion()
+	if err != nil {
+		return 0, err
+	}
+
+	if latest := LatestSchemaVersion(); current > latest {
+		return current, fmt.Errorf("database schema version %d is newer than this binary supports (%d), refusing to continue", current, latest)
+	}
+
+	return current, nil
+}
+
+// Migrate applies every pending migration. It is run on startup.
+func (st *SteamTracker) Migrate() error {
+	_, err := st.MigrateUp(0)
+	return err
+}
+
+// MigrateUp applies pending migrations up to and including target. A target of
+// zero means the latest version.
+func (st *SteamTracker) MigrateUp(target int) ([]Migration, error) {
+	current, err := st.checkSchemaNotNewer()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syncAuditLogSearch(st.db.WithContext(st.ctx)); err != nil {
+		return nil, fmt.Errorf("failed to sync audit log search index: %w", err)
+	}
+
+	if target <= 0 {
+		target = LatestSchemaVersion()
+	}
+
+	applied := make([]Migration, 0)
+	for _, m := range migrations {
+		if m.Version <= current || m.Version > target {
+			continue
+		}
+
+		event := log.Debug().
+			Str("action", "migrate_up").
+			Int("version", m.Version).
+			Str("name", m.Name)
+
+		err := st.db.WithContext(st.ctx).Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+
+			return tx.Create(&SchemaVersion{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			event.Err(err).Send()
+			return applied, fmt.Errorf("failed to apply migration %d (%s): %w", m.Version, m.Name, err)
+		}
+
+		event.Send()
+		applied = append(applied, m)
+	}
+
+	return applied, nil
+}
+
+// MigrateDown reverts the given number of most recently applied migrations.
+func (st *SteamTracker) MigrateDown(steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("steps must be at least 1")
+	}
+
+	current, err := st.checkSchemaNotNewer()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syncAuditLogSearch(st.db.WithContext(st.ctx)); err != nil {
+		return nil, fmt.Errorf("failed to sync audit log search index: %w", err)
+	}
+
+	reverted := make([]Migration, 0)
+	for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := migrations[i]
+		if m.Version > current {
+			continue
+		}
+
+		event := log.Debug().
+			Str("action", "migrate_down").
+			Int("version", m.Version).
+			Str("name", m.Name)
+
+		err := st.db.WithContext(st.ctx).Transaction(func(tx *gorm.DB) error {
+			if m.Down == nil {
+				return errors.New("migration is irreversible")
+			}
+
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+
+			return tx.Delete(&SchemaVersion{}, m.Version).Error
+		})
+		if err != nil {
+			event.Err(err).Send()
+			return reverted, fmt.Errorf("failed to revert migration %d (%s): %w", m.Version, m.Name, err)
+		}
+
+		event.Send()
+		reverted = append(reverted, m)
+	}
+
+	return reverted, nil
+}
+
+// MigrationStatus lists every known migration and when it was applied. Versions
+// recorded in the database but unknown to this binary are included as well.
+func (st *SteamTracker) MigrationStatus() ([]MigrationStatus, error) {
+	if err := st.ensureSchemaVersionTable(); err != nil {
+		return nil, err
+	}
+
+	rows := make([]SchemaVersion, 0)
+	if err := st.db.WithContext(st.ctx).Order("version").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema versions: %w", err)
+	}
+
+	applied := make(map[int]SchemaVersion, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	result := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if row, ok := applied[m.Version]; ok {
+			status.AppliedAt = &row.AppliedAt
+			delete(applied, m.Version)
+		}
+		result = append(result, status)
+	}
+
+	for _, row := range rows {
+		if _, unknown := applied[row.Version]; unknown {
+			result = append(result, MigrationStatus{Version: row.Version, Name: row.Name, AppliedAt: &row.AppliedAt})
+		}
+	}
+
+	return result, nil
+}
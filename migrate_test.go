@@ -0,0 +1,177 @@
+package steamtracker_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+func openUnmigratedTracker(t *testing.T, dsn string) *steamtracker.SteamTracker {
+	t.Helper()
+
+	st, err := steamtracker.Open(&steamtracker.Config{DatabaseDSN: dsn, DisableAuth: true})
+	if err != nil {
+		t.Fatalf("Failed to open tracker: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	return st
+}
+
+// appliedVersions returns the versions MigrationStatus reports as applied,
+// and fails if it reports any as unknown to the binary.
+func appliedVersions(t *testing.T, st *steamtracker.SteamTracker) []int {
+	t.Helper()
+
+	statuses, err := st.MigrationStatus()
+	if err != nil {
+		t.Fatalf("Failed to get migration status: %v", err)
+	}
+	if len(statuses) != steamtracker.LatestSchemaVersion() {
+		t.Fatalf("Expected %d migrations, got %d", steamtracker.LatestSchemaVersion(), len(statuses))
+	}
+
+	applied := make([]int, 0, len(statuses))
+	for i, status := range statuses {
+		if status.Version != i+1 || status.Name == "" {
+			t.Errorf("Expected migration %d in order and named, got %+v", i+1, status)
+		}
+		if status.AppliedAt != nil {
+			applied = append(applied, status.Version)
+		}
+	}
+	return applied
+}
+
+func schemaVersion(t *testing.T, st *steamtracker.SteamTracker) int {
+	t.Helper()
+
+	version, err := st.SchemaVersion()
+	if err != nil {
+		t.Fatalf("Failed to get schema version: %v", err)
+	}
+	return version
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	st := openUnmigratedTracker(t, testDSNs(t)["sqlite"])
+	latest := steamtracker.LatestSchemaVersion()
+
+	if got := appliedVersions(t, st); len(got) != 0 {
+		t.Fatalf("Expected no applied migrations on a new database, got %v", got)
+	}
+
+	applied, err := st.MigrateUp(latest - 1)
+	if err != nil {
+		t.Fatalf("Failed to migrate up: %v", err)
+	}
+	if len(applied) != latest-1 || schemaVersion(t, st) != latest-1 {
+		t.Fatalf("Expected to stop at version %d, applied %d to version %d", latest-1, len(applied), schemaVersion(t, st))
+	}
+
+	applied, err = st.MigrateUp(0)
+	if err != nil {
+		t.Fatalf("Failed to migrate up: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != latest {
+		t.Fatalf("Expected only version %d to be applied, got %+v", latest, applied)
+	}
+	if got := appliedVersions(t, st); len(got) != latest {
+		t.Errorf("Expected every migration to be applied, got %v", got)
+	}
+
+	if applied, err := st.MigrateUp(0); err != nil || len(applied) != 0 {
+		t.Errorf("Expected nothing to apply at the latest version, got %+v, %v", applied, err)
+	}
+
+	reverted, err := st.MigrateDown(1)
+	if err != nil {
+		t.Fatalf("Failed to migrate down: %v", err)
+	}
+	if len(reverted) != 1 || reverted[0].Version != latest || schemaVersion(t, st) != latest-1 {
+		t.Fatalf("Expected version %d to be reverted, got %+v at version %d", latest, reverted, schemaVersion(t, st))
+	}
+	if got := appliedVersions(t, st); len(got) != latest-1 || got[len(got)-1] != latest-1 {
+		t.Errorf("Expected version %d to be pending again, got applied %v", latest, got)
+	}
+
+	if _, err := st.MigrateUp(0); err != nil {
+		t.Fatalf("Failed to migrate back up: %v", err)
+	}
+	if schemaVersion(t, st) != latest {
+		t.Errorf("Expected version %d, got %d", latest, schemaVersion(t, st))
+	}
+
+	// Every migration can be reverted and applied again.
+	if reverted, err := st.MigrateDown(latest); err != nil || len(reverted) != latest {
+		t.Fatalf("Expected to revert all %d migrations, got %d: %v", latest, len(reverted), err)
+	}
+	if schemaVersion(t, st) != 0 {
+		t.Errorf("Expected version 0, got %d", schemaVersion(t, st))
+	}
+	if err := st.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate back up: %v", err)
+	}
+	addPlayer(t, st, uniqueSteamID(), steamtracker.PersonaStateOnline, "")
+
+	if _, err := st.MigrateDown(0); err == nil {
+		t.Error("Expected an error for zero steps")
+	}
+}
+
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	dsn := testDSNs(t)["sqlite"]
+	st := openTestTracker(t, dsn)
+	latest := steamtracker.LatestSchemaVersion()
+
+	future := steamtracker.SchemaVersion{Version: latest + 1, Name: "from_the_future", AppliedAt: time.Now()}
+	if err := st.DB().Create(&future).Error; err != nil {
+		t.Fatalf("Failed to record a newer schema version: %v", err)
+	}
+
+	statuses, err := st.MigrationStatus()
+	if err != nil {
+		t.Fatalf("Failed to get migration status: %v", err)
+	}
+	if last := statuses[len(statuses)-1]; last.Version != future.Version || last.Name != future.Name || last.AppliedAt == nil {
+		t.Errorf("Expected the status to list the unknown version %d, got %+v", future.Version, last)
+	}
+
+	for name, migrate := range map[string]func() error{
+		"up":   func() error { _, err := st.MigrateUp(0); return err },
+		"down": func() error { _, err := st.MigrateDown(1); return err },
+	} {
+		if err := migrate(); err == nil || !strings.Contains(err.Error(), "newer than this binary") {
+			t.Errorf("%s: expected a newer schema error, got %v", name, err)
+		}
+	}
+	if schemaVersion(t, st) != future.Version {
+		t.Errorf("Expected the schema to stay at version %d, got %d", future.Version, schemaVersion(t, st))
+	}
+
+	if err := openUnmigratedTracker(t, dsn).Migrate(); err == nil {
+		t.Error("Open: expected migrating a newer schema to fail")
+	}
+
+	_, err = steamtracker.New(&steamtracker.Config{
+		DatabaseDSN:           dsn,
+		HTTPPort:              "0",
+		SteamAPIKey:           testSteamAPIKey,
+		SteamID:               "76561197960287930",
+		MaxTaskRetryCount:     1,
+		TaskInterval:          60,
+		LogLevel:              zerolog.Disabled,
+		DisableAuth:           true,
+		AuditLogBufferSize:    16,
+		AuditLogBatchSize:     4,
+		AuditLogFlushInterval: 10,
+		AuditLogDropPolicy:    steamtracker.AuditLogDropPolicyBlock,
+	})
+	if err == nil || !strings.Contains(err.Error(), "newer than this binary") {
+		t.Errorf("New: expected a newer schema error, got %v", err)
+	}
+}
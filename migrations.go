@@ -0,0 +1,543 @@
+package steamtracker
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// migrations is the ordered list of schema changes. Never edit or reorder an
+// entry once it has been released; add a new one instead. Each migration uses
+// its own frozen copy of the tables it touches so that later changes to the
+// models do not alter what an old migration does.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_initial_tables",
+		Up: func(tx *gorm.DB) error {
+			// AutoMigrate is idempotent, which lets databases created before
+			// versioned migrations adopt version 1 as-is.
+			return tx.Migrator().AutoMigrate(&player0001{}, &playerEvent0001{}, &auditLog0001{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&player0001{}, &playerEvent0001{}, &auditLog0001{})
+		},
+	},
+	{
+		Version: 2,
+		Name:    "index_audit_logs_created_at",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateIndex(&auditLog0002{}, "CreatedAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropIndex(&auditLog0002{}, "CreatedAt")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create_player_rollups",
+		Up: func(tx *gorm.DB) error {
+			for _, table := range []string{"player_hourly_rollups", "player_daily_rollups"} {
+				if err := tx.Table(table).Migrator().AutoMigrate(&playerRollup0003{}); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable("player_hourly_rollups", "player_daily_rollups")
+		},
+	},
+	{
+		Version: 4,
+		Name:    "add_audit_log_columns",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AutoMigrate(&auditLog0004{}); err != nil {
+				return err
+			}
+			return backfillAuditLogFields(tx)
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, field := range []string{"Level", "Action", "SteamID", "LoggedAt"} {
+				if err := tx.Migrator().DropIndex(&auditLog0004{}, field); err != nil {
+					return err
+				}
+			}
+			for _, field := range []string{"Level", "Message", "Action", "SteamID", "Error", "LoggedAt"} {
+				if err := tx.Migrator().DropColumn(&auditLog0004{}, field); err != nil {
+					return err
+				}
+			}
+			// SQLite drops columns by rebuilding the table, which loses the
+			// index added by version 2.
+			if !tx.Migrator().HasIndex(&auditLog0002{}, "CreatedAt") {
+				return tx.Migrator().CreateIndex(&auditLog0002{}, "CreatedAt")
+			}
+			return nil
+		},
+	},
+	{
+		Version: 5,
+		Name:    "create_audit_logs_fts",
+		Up: func(tx *gorm.DB) error {
+			// Without FTS5 this is a no-op and searches fall back to LIKE;
+			// "audit reindex" creates the index later.
+			ok, err := createAuditLogSearch(tx)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				if tx.Dialector.Name() == DialectSQLite {
+					log.Warn().Msg("SQLite was built without FTS5, so the audit log search index was not created and searches fall back to LIKE; build with -tags sqlite_fts5 and run \"steamtracker audit reindex\" to enable it")
+				}
+				return nil
+			}
+			return tx.Exec("INSERT INTO audit_logs_fts(audit_logs_fts) VALUES ('rebuild')").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() != "sqlite" {
+				return nil
+			}
+			return dropAuditLogSearch(tx)
+		},
+	},
+	{
+		Version: 6,
+		Name:    "redact_audit_log_urls",
+		Up: func(tx *gorm.DB) error {
+			return redactStoredAuditLogs(tx)
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil // redacted values cannot be restored
+		},
+	},
+	{
+		Version: 7,
+		Name:    "add_audit_log_hash_chain",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AutoMigrate(&auditLog0007{}); err != nil {
+				return err
+			}
+			return backfillAuditChain(tx)
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, field := range []string{"PrevHash", "Hash"} {
+				if err := tx.Migrator().DropColumn(&auditLog0007{}, field); err != nil {
+					return err
+				}
+			}
+			// SQLite drops columns by rebuilding the table, which loses the
+			// indexes added by versions 2 and 4.
+			for _, field := range []string{"Level", "Action", "SteamID", "LoggedAt", "CreatedAt"} {
+				if tx.Migrator().HasIndex(&auditLog0004{}, field) {
+					continue
+				}
+				if err := tx.Migrator().CreateIndex(&auditLog0004{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create_player_current_states",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateIndex(&playerEvent0008{}, "idx_player_events_steam_id_created_at"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().AutoMigrate(&playerCurrentState0008{}); err != nil {
+				return err
+			}
+			return rebuildPlayerCurrentStates0008(tx)
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&playerCurrentState0008{}); err != nil {
+				return err
+			}
+			return tx.Migrator().DropIndex(&playerEvent0008{}, "idx_player_events_steam_id_created_at")
+		},
+	},
+	{
+		Version: 9,
+		Name:    "add_player_event_type_and_indexes",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AutoMigrate(&playerEvent0009{}); err != nil {
+				return err
+			}
+			return backfillPlayerEventTypes(tx)
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, name := range []string{
+				"idx_player_events_type_created_at",
+				"idx_player_events_persona_state_created_at",
+				"idx_player_events_created_at_id",
+			} {
+				if err := tx.Migrator().DropIndex(&playerEvent0009{}, name); err != nil {
+					return err
+				}
+			}
+			for _, field := range []string{"Type", "GameID"} {
+				if err := tx.Migrator().DropColumn(&playerEvent0009{}, field); err != nil {
+					return err
+				}
+			}
+			// SQLite drops columns by rebuilding the table, which loses the
+			// index added by version 8.
+			if !tx.Migrator().HasIndex(&playerEvent0008{}, "idx_player_events_steam_id_created_at") {
+				return tx.Migrator().CreateIndex(&playerEvent0008{}, "idx_player_events_steam_id_created_at")
+			}
+			return nil
+		},
+	},
+	{
+		Version: 10,
+		Name:    "create_api_tokens",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AutoMigrate(&apiToken0010{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&apiToken0010{})
+		},
+	},
+	{
+		Version: 11,
+		Name:    "create_games",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AutoMigrate(&game0011{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&game0011{})
+		},
+	},
+}
+
+// redactStoredAuditLogs removes API keys that earlier versions logged as part
+// of request URLs.
+func redactStoredAuditLogs(tx *gorm.DB) error {
+	var lastID int64
+	for {
+		rows := make([]struct {
+			ID    int64
+			Raw   string
+			Error string
+		}, 0, 500)
+		if err := tx.Table("audit_logs").Select("id", "raw", "error").
+			Where("id > ?", lastID).Where("raw LIKE ?", "%key=%").
+			Order("id").Limit(500).Find(&rows).Error; err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			raw := sensitiveQueryParamPattern.ReplaceAllString(row.Raw, "${1}"+redacted)
+			if raw == row.Raw {
+				continue
+			}
+			if err := tx.Table("audit_logs").Where("id = ?", row.ID).Updates(map[string]any{
+				"raw":   raw,
+				"error": sensitiveQueryParamPattern.ReplaceAllString(row.Error, "${1}"+redacted),
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(rows) < 500 {
+			return nil
+		}
+		lastID = rows[len(rows)-1].ID
+	}
+}
+
+// backfillAuditChain chains the audit logs stored before version 7.
+func backfillAuditChain(tx *gorm.DB) error {
+	prevHash := ""
+	var lastID int64
+	for {
+		rows := make([]struct {
+			ID  int64
+			Raw string
+		}, 0, 500)
+		if err := tx.Table("audit_logs").Select("id", "raw").Where("id > ?", lastID).Order("id").Limit(500).Find(&rows).Error; err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			hash := auditLogHash(prevHash, row.ID, []byte(row.Raw))
+			if err := tx.Table("audit_logs").Where("id = ?", row.ID).Updates(map[string]any{
+				"prev_hash": prevHash,
+				"hash":      hash,
+			}).Error; err != nil {
+				return err
+			}
+			prevHash = hash
+		}
+
+		if len(rows) < 500 {
+			return nil
+		}
+		lastID = rows[len(rows)-1].ID
+	}
+}
+
+// backfillAuditLogFields fills the columns added in version 4 for audit logs
+// written before it.
+func backfillAuditLogFields(tx *gorm.DB) error {
+	var lastID int64
+	for {
+		rows := make([]struct {
+			ID  int64
+			Raw string
+		}, 0, 500)
+		if err := tx.Table("audit_logs").Select("id", "raw").Where("id > ?", lastID).Order("id").Limit(500).Find(&rows).Error; err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			fields := parseAuditLogFields([]byte(row.Raw))
+			if err := tx.Table("audit_logs").Where("id = ?", row.ID).Updates(map[string]any{
+				"level":     fields.Level,
+				"message":   fields.Message,
+				"action":    fields.Action,
+				"steam_id":  fields.SteamID,
+				"error":     fields.Error,
+				"logged_at": fields.LoggedAt,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(rows) < 500 {
+			return nil
+		}
+		lastID = rows[len(rows)-1].ID
+	}
+}
+
+// backfillPlayerEventTypes types the events recorded before event types
+// existed. Back then an event was only written when the persona state
+// changed, except for the first event of each player.
+func backfillPlayerEventTypes(tx *gorm.DB) error {
+	if err := tx.Table("player_events").Where("type IS NULL OR type = ''").
+		Update("type", "persona_state").Error; err != nil {
+		return err
+	}
+
+	steamIDs := make([]int64, 0)
+	if err := tx.Table("player_events").Distinct("steam_id").Pluck("steam_id", &steamIDs).Error; err != nil {
+		return err
+	}
+
+	for _, steamID := range steamIDs {
+		var firstID int64
+		if err := tx.Table("player_events").Select("id").Where("steam_id = ?", steamID).
+			Order("created_at").Order("id").Limit(1).Scan(&firstID).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("player_events").Where("id = ?", firstID).
+			Update("type", "first_seen").Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rebuildPlayerCurrentStates0008 fills player_current_states from the newest
+// snapshot and the newest event of every player. Events only recorded persona
+// state changes back then, so a newest event in the current state is when the
+// player entered it.
+func rebuildPlayerCurrentStates0008(tx *gorm.DB) error {
+	steamIDs := make([]int64, 0)
+	if err := tx.Model(&player0001{}).Distinct("steam_id").Pluck("steam_id", &steamIDs).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&playerCurrentState0008{}).Error; err != nil {
+		return err
+	}
+
+	for _, steamID := range steamIDs {
+		player := player0001{}
+		if err := tx.Where("steam_id = ?", steamID).Order("created_at DESC").Order("id DESC").Take(&player).Error; err != nil {
+			return err
+		}
+
+		state := playerCurrentState0008{
+			SteamID:      player.SteamID,
+			ProfileState: player.ProfileState,
+			PersonaName:  player.PersonaName,
+			AvatarHash:   player.AvatarHash,
+			LastLogoff:   player.LastLogoff,
+			PersonaState: player.PersonaState,
+			GameID:       player.GameID,
+			StateSince:   player.CreatedAt,
+			LastPolledAt: player.CreatedAt,
+			LastPlayerID: player.ID,
+		}
+
+		playerEvent := playerEvent0008{}
+		err := tx.Where("steam_id = ?", steamID).Order("created_at DESC").Order("id DESC").Take(&playerEvent).Error
+		if err == nil {
+			state.LastEventID = playerEvent.ID
+			if playerEvent.PersonaState == player.PersonaState {
+				state.StateSince = playerEvent.CreatedAt
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if err := tx.Create(&state).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type player0001 struct {
+	ID           int64 `gorm:"primaryKey"`
+	SteamID      int64 `gorm:"index"`
+	ProfileState int
+	PersonaName  string
+	AvatarHash   string
+	LastLogoff   int
+	PersonaState int
+	GameID       string
+	CreatedAt    time.Time `gorm:"index"`
+}
+
+func (player0001) TableName() string { return "players" }
+
+type playerEvent0001 struct {
+	ID           int64 `gorm:"primaryKey"`
+	SteamID      int64
+	PersonaName  string
+	PersonaState int
+	CreatedAt    time.Time
+}
+
+func (playerEvent0001) TableName() string { return "player_events" }
+
+type auditLog0001 struct {
+	ID        int64  `gorm:"primaryKey"`
+	Raw       string `gorm:"type:text"`
+	CreatedAt time.Time
+}
+
+func (auditLog0001) TableName() string { return "audit_logs" }
+
+type auditLog0002 struct {
+	ID        int64     `gorm:"primaryKey"`
+	Raw       string    `gorm:"type:text"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (auditLog0002) TableName() string { return "audit_logs" }
+
+type playerRollup0003 struct {
+	SteamID               int64     `gorm:"primaryKey;autoIncrement:false"`
+	BucketStart           time.Time `gorm:"primaryKey"`
+	OfflineSeconds        int64
+	OnlineSeconds         int64
+	BusySeconds           int64
+	AwaySeconds           int64
+	SnoozeSeconds         int64
+	LookingToTradeSeconds int64
+	LookingToPlaySeconds  int64
+	InGameSeconds         int64
+	Sessions              int
+	Games                 string `gorm:"type:text"`
+	DistinctGames         int
+	UpdatedAt             time.Time
+}
+
+type auditLog0004 struct {
+	ID        int64      `gorm:"primaryKey"`
+	Raw       string     `gorm:"type:text"`
+	Level     string     `gorm:"size:16;index"`
+	Message   string     `gorm:"type:text"`
+	Action    string     `gorm:"size:64;index"`
+	SteamID   *int64     `gorm:"index"`
+	Error     string     `gorm:"type:text"`
+	LoggedAt  *time.Time `gorm:"index"`
+	CreatedAt time.Time  `gorm:"index"`
+}
+
+func (auditLog0004) TableName() string { return "audit_logs" }
+
+type auditLog0007 struct {
+	ID        int64      `gorm:"primaryKey"`
+	Raw       string     `gorm:"type:text"`
+	Level     string     `gorm:"size:16;index"`
+	Message   string     `gorm:"type:text"`
+	Action    string     `gorm:"size:64;index"`
+	SteamID   *int64     `gorm:"index"`
+	Error     string     `gorm:"type:text"`
+	LoggedAt  *time.Time `gorm:"index"`
+	PrevHash  string     `gorm:"size:64"`
+	Hash      string     `gorm:"size:64"`
+	CreatedAt time.Time  `gorm:"index"`
+}
+
+func (auditLog0007) TableName() string { return "audit_logs" }
+
+type playerEvent0008 struct {
+	ID           int64 `gorm:"primaryKey"`
+	SteamID      int64 `gorm:"index:idx_player_events_steam_id_created_at,priority:1"`
+	PersonaName  string
+	PersonaState int
+	CreatedAt    time.Time `gorm:"index:idx_player_events_steam_id_created_at,priority:2"`
+}
+
+func (playerEvent0008) TableName() string { return "player_events" }
+
+type playerCurrentState0008 struct {
+	SteamID      int64 `gorm:"primaryKey;autoIncrement:false"`
+	ProfileState int
+	PersonaName  string
+	AvatarHash   string
+	LastLogoff   int
+	PersonaState int
+	GameID       string
+	StateSince   time.Time
+	LastPolledAt time.Time
+	LastPlayerID int64
+	LastEventID  int64
+}
+
+func (playerCurrentState0008) TableName() string { return "player_current_states" }
+
+type playerEvent0009 struct {
+	ID           int64  `gorm:"primaryKey;index:idx_player_events_created_at_id,priority:2"`
+	SteamID      int64  `gorm:"index:idx_player_events_steam_id_created_at,priority:1"`
+	Type         string `gorm:"size:32;index:idx_player_events_type_created_at,priority:1"`
+	PersonaName  string
+	PersonaState int `gorm:"index:idx_player_events_persona_state_created_at,priority:1"`
+	GameID       string
+	CreatedAt    time.Time `gorm:"index:idx_player_events_steam_id_created_at,priority:2;index:idx_player_events_type_created_at,priority:2;index:idx_player_events_persona_state_created_at,priority:2;index:idx_player_events_created_at_id,priority:1"`
+}
+
+func (playerEvent0009) TableName() string { return "player_events" }
+
+type apiToken0010 struct {
+	ID         int64  `gorm:"primaryKey"`
+	Name       string `gorm:"size:128"`
+	Prefix     string `gorm:"size:16"`
+	TokenHash  string `gorm:"size:64;uniqueIndex"`
+	Scopes     string `gorm:"type:text"`
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func (apiToken0010) TableName() string { return "api_tokens" }
+
+type game0011 struct {
+	ID        string `gorm:"primaryKey;size:32"`
+	Name      string
+	UpdatedAt time.Time
+}
+
+func (game0011) TableName() string { return "games" }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -23,7 +24,9 @@ type Player struct {
 	// PrimaryClanID     string       `json:"primary_clan_id"`
 	// TimeCreated int `json:"time_created"`
 	// PersonaStateFlags int       `json:"persona_state_flags"`
-	// GameExtraInfo     string    `json:"game_extra_info"`
+	// GameName is the name Steam gives GameID. It is stored once per game,
+	// in the games table, rather than with every snapshot.
+	GameName  string    `json:"-" gorm:"-"`
 	GameID    string    `json:"game_id"`
 	CreatedAt time.Time `json:"created_at" gorm:"index"`
 }
@@ -130,6 +133,10 @@ func (ps *PersonaState) fromString(stateName string) error {
 type SearchPlayersQuery struct {
 	Page  int `query:"page"`
 	Limit int `query:"limit"`
+	// Cursor switches to keyset pagination on (created_at, id) and takes
+	// precedence over Page. An empty cursor starts at the first row.
+	Cursor       *string `json:"cursor" query:"cursor,allowempty"`
+	IncludeTotal *bool   `json:"include_total"`
 
 	SteamID        *SteamID   `json:"steam_id"`
 	StartCreatedAt *time.Time `json:"start_created_at"`
@@ -148,50 +155,104 @@ func (query *SearchPlayersQuery) Validate() error {
 		query.Limit = 25
 	}
 
+	bindErr := &BindError{}
+
+	if query.Cursor != nil {
+		if _, err := DecodeCursor(*query.Cursor); err != nil {
+			bindErr.add("cursor", "must be a next_cursor returned by a previous page")
+		}
+	}
+
 	if query.SteamID != nil && *query.SteamID < 0 {
-		return fmt.Errorf("invalid SteamID: %d", *query.SteamID)
+		bindErr.add("steam_id", "must not be negative")
 	}
 
 	if query.StartCreatedAt != nil && query.EndCreatedAt != nil && query.StartCreatedAt.After(*query.EndCreatedAt) {
-		return fmt.Errorf("start_created_at cannot be after end_created_at")
+		bindErr.add("start_created_at", "must not be after end_created_at")
 	}
 
-	if query.SortBy.CreatedAt != nil {
-		if *query.SortBy.CreatedAt != "asc" && *query.SortBy.CreatedAt != "desc" {
-			return fmt.Errorf("invalid sort order for created_at: %s, must be 'asc' or 'desc'", *query.SortBy.CreatedAt)
-		}
-	}
+	validateSortOrder(bindErr, "created_at", query.SortBy.CreatedAt)
 
-	return nil
+	return bindErr.err()
 }
 
 type SearchPlayersQueryResult struct {
-	TotalCount int64 `json:"totalCount"`
-	Page       int   `json:"page"`
-	PerPage    int   `json:"perPage"`
+	// TotalCount is only set when the query asked for it, see IncludeTotal.
+	TotalCount *int64 `json:"totalCount,omitempty"`
+	Page       int    `json:"page,omitempty"`
+	PerPage    int    `json:"perPage"`
+	NextCursor string `json:"next_cursor,omitempty"`
 
 	Players []*Player `json:"players"`
 }
 
+// parsePersonaState accepts a persona state number or name, ignoring case,
+// spaces and underscores, so "Looking to Play", "looking_to_play" and 6 are
+// the same state.
+func parsePersonaState(s string) (PersonaState, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 || n >= len(personaStateNames) {
+			return 0, fmt.Errorf("invalid persona state value: %d", n)
+		}
+		return PersonaState(n), nil
+	}
+
+	normalize := strings.NewReplacer(" ", "", "_", "")
+	for state, name := range personaStateNames {
+		if strings.EqualFold(normalize.Replace(name), normalize.Replace(s)) {
+			return state, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown persona state: %s", s)
+}
+
+// PlayerEventType says why a player event was recorded.
+type PlayerEventType string
+
+const (
+	// PlayerEventTypeFirstSeen is the first event of a player.
+	PlayerEventTypeFirstSeen PlayerEventType = "first_seen"
+	// PlayerEventTypePersonaState records a change of persona state.
+	PlayerEventTypePersonaState PlayerEventType = "persona_state"
+	// PlayerEventTypeGame records a player starting, stopping or switching
+	// games while the persona state stays the same.
+	PlayerEventTypeGame PlayerEventType = "game"
+)
+
+func (t PlayerEventType) valid() bool {
+	return t == PlayerEventTypeFirstSeen || t == PlayerEventTypePersonaState || t == PlayerEventTypeGame
+}
+
 type PlayerEvent struct {
-	ID           int64        `json:"id" gorm:"primaryKey"`
-	SteamID      SteamID      `json:"steam_id"`
-	PersonaName  string       `json:"persona_name"`
-	PersonaState PersonaState `json:"persona_state"`
-	CreatedAt    time.Time    `json:"created_at"`
+	ID           int64           `json:"id" gorm:"primaryKey;index:idx_player_events_created_at_id,priority:2"`
+	SteamID      SteamID         `json:"steam_id" gorm:"index:idx_player_events_steam_id_created_at,priority:1"`
+	Type         PlayerEventType `json:"type" gorm:"size:32;index:idx_player_events_type_created_at,priority:1"`
+	PersonaName  string          `json:"persona_name"`
+	PersonaState PersonaState    `json:"persona_state" gorm:"index:idx_player_events_persona_state_created_at,priority:1"`
+	GameID       string          `json:"game_id"`
+	CreatedAt    time.Time       `json:"created_at" gorm:"index:idx_player_events_steam_id_created_at,priority:2;index:idx_player_events_type_created_at,priority:2;index:idx_player_events_persona_state_created_at,priority:2;index:idx_player_events_created_at_id,priority:1"`
 }
 
 type CreatePlayerEventCommand struct {
-	SteamID      SteamID      `json:"steam_id"`
-	PersonaName  string       `json:"persona_name"`
-	PersonaState PersonaState `json:"persona_state"`
+	SteamID      SteamID         `json:"steam_id"`
+	Type         PlayerEventType `json:"type"`
+	PersonaName  string          `json:"persona_name"`
+	PersonaState PersonaState    `json:"persona_state"`
+	GameID       string          `json:"game_id"`
 }
 
 func (cmd *CreatePlayerEventCommand) PlayerEvent() PlayerEvent {
+	eventType := cmd.Type
+	if eventType == "" {
+		eventType = PlayerEventTypePersonaState
+	}
+
 	return PlayerEvent{
 		SteamID:      cmd.SteamID,
+		Type:         eventType,
 		PersonaName:  cmd.PersonaName,
 		PersonaState: cmd.PersonaState,
+		GameID:       cmd.GameID,
 	}
 }
 
@@ -202,8 +263,21 @@ type GetLatestPlayerEventQuery struct {
 type SearchPlayerEventsQuery struct {
 	Page  int `query:"page"`
 	Limit int `query:"limit"`
+	// Cursor switches to keyset pagination on (created_at, id) and takes
+	// precedence over Page. An empty cursor starts at the first row.
+	Cursor       *string `json:"cursor" query:"cursor,allowempty"`
+	IncludeTotal *bool   `json:"include_total"`
 
 	SteamID *SteamID `json:"steam_id"`
+	// SteamIDs matches events of any of the given players. Like all filters
+	// it is combined with SteamID using AND.
+	SteamIDs      []SteamID         `json:"steam_ids"`
+	Types         []PlayerEventType `json:"type"`
+	PersonaStates []PersonaState    `json:"persona_state"`
+	// PersonaName matches names containing it, ignoring case.
+	PersonaName    *string    `json:"persona_name"`
+	StartCreatedAt *time.Time `json:"start_created_at"`
+	EndCreatedAt   *time.Time `json:"end_created_at"`
 
 	SortBy struct {
 		CreatedAt *string `json:"created_at"`
@@ -218,23 +292,54 @@ func (query *SearchPlayerEventsQuery) Validate() error {
 		query.Limit = 25
 	}
 
+	bindErr := &BindError{}
+
+	if query.Cursor != nil {
+		if _, err := DecodeCursor(*query.Cursor); err != nil {
+			bindErr.add("cursor", "must be a next_cursor returned by a previous page")
+		}
+	}
+
 	if query.SteamID != nil && *query.SteamID < 0 {
-		return fmt.Errorf("invalid SteamID: %d", *query.SteamID)
+		bindErr.add("steam_id", "must not be negative")
 	}
 
-	if query.SortBy.CreatedAt != nil {
-		if *query.SortBy.CreatedAt != "asc" && *query.SortBy.CreatedAt != "desc" {
-			return fmt.Errorf("invalid sort order for created_at: %s, must be 'asc' or 'desc'", *query.SortBy.CreatedAt)
+	if len(query.SteamIDs) > 100 {
+		bindErr.add("steam_ids", fmt.Sprintf("must list at most 100 Steam IDs, got %d", len(query.SteamIDs)))
+	}
+	for _, steamID := range query.SteamIDs {
+		if steamID < 0 {
+			bindErr.add("steam_ids", "must not be negative")
+			break
 		}
 	}
 
-	return nil
+	for _, t := range query.Types {
+		if !t.valid() {
+			bindErr.add("type", fmt.Sprintf("must be %s, %s or %s, got %s", PlayerEventTypeFirstSeen, PlayerEventTypePersonaState, PlayerEventTypeGame, t))
+			break
+		}
+	}
+
+	if query.PersonaName != nil && *query.PersonaName == "" {
+		query.PersonaName = nil
+	}
+
+	if query.StartCreatedAt != nil && query.EndCreatedAt != nil && query.StartCreatedAt.After(*query.EndCreatedAt) {
+		bindErr.add("start_created_at", "must not be after end_created_at")
+	}
+
+	validateSortOrder(bindErr, "created_at", query.SortBy.CreatedAt)
+
+	return bindErr.err()
 }
 
 type SearchPlayerEventsQueryResult struct {
-	TotalCount int64 `json:"total_count"`
-	Page       int   `json:"page"`
-	PerPage    int   `json:"per_page"`
+	// TotalCount is only set when the query asked for it, see IncludeTotal.
+	TotalCount *int64 `json:"total_count,omitempty"`
+	Page       int    `json:"page,omitempty"`
+	PerPage    int    `json:"per_page"`
+	NextCursor string `json:"next_cursor,omitempty"`
 
 	PlayerEvents []*PlayerEvent `json:"player_events"`
 }
@@ -0,0 +1,318 @@
+package steamtracker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// PlayerCurrentState is the latest known snapshot of a tracked player. It is
+// kept up to date by AddPlayer so the polling task and the dashboard never
+// have to scan players or player_events for the newest row.
+type PlayerCurrentState struct {
+	SteamID      SteamID      `json:"steam_id" gorm:"primaryKey;autoIncrement:false"`
+	ProfileState int          `json:"profile_state"`
+	PersonaName  string       `json:"persona_name"`
+	AvatarHash   string       `json:"avatar_hash"`
+	LastLogoff   int          `json:"last_logoff"`
+	PersonaState PersonaState `json:"persona_state"`
+	GameID       string       `json:"game_id"`
+	// StateSince is when PersonaState last changed.
+	StateSince   time.Time `json:"state_since"`
+	LastPolledAt time.Time `json:"last_polled_at"`
+	LastPlayerID int64     `json:"last_player_id"`
+	LastEventID  int64     `json:"last_event_id"`
+}
+
+// snapshot returns the state as the player row it was taken from.
+func (s *PlayerCurrentState) snapshot() *Player {
+	return &Player{
+		ID:           s.LastPlayerID,
+		SteamID:      s.SteamID,
+		ProfileState: s.ProfileState,
+		PersonaName:  s.PersonaName,
+		AvatarHash:   s.AvatarHash,
+		LastLogoff:   s.LastLogoff,
+		PersonaState: s.PersonaState,
+		GameID:       s.GameID,
+		CreatedAt:    s.LastPolledAt,
+	}
+}
+
+func (s *PlayerCurrentState) setSnapshot(player *Player) {
+	s.SteamID = player.SteamID
+	s.ProfileState = player.ProfileState
+	s.PersonaName = player.PersonaName
+	s.AvatarHash = player.AvatarHash
+	s.LastLogoff = player.LastLogoff
+	s.PersonaState = player.PersonaState
+	s.GameID = player.GameID
+	s.LastPolledAt = player.CreatedAt
+	s.LastPlayerID = player.ID
+}
+
+// recordPlayerState stores a new snapshot in the current state table and,
+// if the persona state or the game changed, a player event. It returns the
+// event, or nil if there was none.
+func (st *SteamTracker) recordPlayerState(tx *gorm.DB, prev *PlayerCurrentState, player *Player) (*PlayerEvent, error) {
+	state := PlayerCurrentState{}
+	if prev != nil {
+		state = *prev
+	}
+
+	var eventType PlayerEventType
+	switch {
+	case prev == nil:
+		eventType = PlayerEventTypeFirstSeen
+	case prev.PersonaState != player.PersonaState:
+		eventType = PlayerEventTypePersonaState
+	case prev.GameID != player.GameID:
+		eventType = PlayerEventTypeGame
+	}
+
+	var playerEvent *PlayerEvent
+	if eventType != "" {
+		e := PlayerEvent{
+			ID:           st.GenerateID(),
+			SteamID:      player.SteamID,
+			Type:         eventType,
+			PersonaName:  player.PersonaName,
+			PersonaState: player.PersonaState,
+			GameID:       player.GameID,
+			CreatedAt:    player.CreatedAt,
+		}
+		if err := tx.Create(&e).Error; err != nil {
+			return nil, fmt.Errorf("failed to create player event: %w", err)
+		}
+		playerEvent = &e
+
+		if eventType != PlayerEventTypeGame {
+			state.StateSince = player.CreatedAt
+		}
+		state.LastEventID = e.ID
+	}
+
+	state.setSnapshot(player)
+	if err := tx.Save(&state).Error; err != nil {
+		return nil, fmt.Errorf("failed to save player current state: %w", err)
+	}
+
+	return playerEvent, nil
+}
+
+func getPlayerCurrentState(tx *gorm.DB, steamID SteamID) (*PlayerCurrentState, error) {
+	state := PlayerCurrentState{}
+	err := tx.Where("steam_id = ?", steamID).Take(&state).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// getPlayerCurrentStates returns the current state of each tracked player
+// among steamIDs.
+func getPlayerCurrentStates(tx *gorm.DB, steamIDs []SteamID) (map[SteamID]*PlayerCurrentState, error) {
+	found := make([]*PlayerCurrentState, 0, len(steamIDs))
+	if err := tx.Where("steam_id IN ?", steamIDs).Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	states := make(map[SteamID]*PlayerCurrentState, len(found))
+	for _, state := range found {
+		states[state.SteamID] = state
+	}
+
+	return states, nil
+}
+
+// rebuildPlayerCurrentStates recomputes the current state of the players in
+// steamIDs, or of every player when it is empty, from the newest snapshot and
+// the newest event stored for it.
+func rebuildPlayerCurrentStates(tx *gorm.DB, steamIDs []SteamID) (int64, error) {
+	players := tx.Table("players")
+	states := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Table("player_current_states")
+	if len(steamIDs) > 0 {
+		players = players.Where("steam_id IN ?", steamIDs)
+		states = states.Where("steam_id IN ?", steamIDs)
+	}
+
+	steamIDs = make([]SteamID, 0, len(steamIDs))
+	if err := players.Distinct("steam_id").Pluck("steam_id", &steamIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to list players: %w", err)
+	}
+
+	if err := states.Delete(&PlayerCurrentState{}).Error; err != nil {
+		return 0, fmt.Errorf("failed to clear player current states: %w", err)
+	}
+
+	for _, steamID := range steamIDs {
+		player := Player{}
+		if err := tx.Table("players").Where("steam_id = ?", steamID).Order("created_at DESC").Order("id DESC").Take(&player).Error; err != nil {
+			return 0, fmt.Errorf("failed to get latest player %d: %w", steamID, err)
+		}
+
+		state := PlayerCurrentState{StateSince: player.CreatedAt}
+		state.setSnapshot(&player)
+
+		playerEvent := PlayerEvent{}
+		err := tx.Table("player_events").Select("id", "persona_state").Where("steam_id = ?", steamID).Order("created_at DESC").Order("id DESC").Take(&playerEvent).Error
+		if err == nil {
+			state.LastEventID = playerEvent.ID
+			if playerEvent.PersonaState == player.PersonaState {
+				since, err := personaStateSince(tx, steamID, player.PersonaState)
+				if err != nil {
+					return 0, fmt.Errorf("failed to get player state change %d: %w", steamID, err)
+				}
+				state.StateSince = since
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fmt.Errorf("failed to get latest player event %d: %w", steamID, err)
+		}
+
+		if err := tx.Table("player_current_states").Create(&state).Error; err != nil {
+			return 0, fmt.Errorf("failed to create player current state %d: %w", steamID, err)
+		}
+	}
+
+	return int64(len(steamIDs)), nil
+}
+
+// personaStateSince returns when a player entered its current persona state:
+// the first event after the last one in a different state. Game events do not
+// change the persona state, so they are skipped over.
+func personaStateSince(tx *gorm.DB, steamID SteamID, current PersonaState) (time.Time, error) {
+	ss := tx.Table("player_events").Select("created_at").Where("steam_id = ?", steamID)
+
+	changed := PlayerEvent{}
+	err := tx.Table("player_events").Select("id", "created_at").
+		Where("steam_id = ? AND persona_state <> ?", steamID, current).
+		Order("created_at DESC").Order("id DESC").Take(&changed).Error
+	if err == nil {
+		ss = ss.Where("created_at > ? OR (created_at = ? AND id > ?)", changed.CreatedAt, changed.CreatedAt, changed.ID)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, err
+	}
+
+	since := PlayerEvent{}
+	if err := ss.Order("created_at").Order("id").Take(&since).Error; err != nil {
+		return time.Time{}, err
+	}
+
+	return since.CreatedAt, nil
+}
+
+// RebuildPlayerCurrentStates recomputes the current state of the players in
+// steamIDs, or of every player when it is empty.
+func (st *SteamTracker) RebuildPlayerCurrentStates(ctx context.Context, steamIDs []SteamID) (int64, error) {
+	event := log.Info().Str("action", "rebuild_player_current_states").Int("steam_ids", len(steamIDs))
+	defer func() { event.Send() }()
+
+	var count int64
+	err := st.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		count, err = rebuildPlayerCurrentStates(tx, steamIDs)
+		return err
+	})
+	if err != nil {
+		event.Err(err)
+		return 0, err
+	}
+
+	event.Int64("players", count)
+	return count, nil
+}
+
+type GetCurrentPlayerQuery struct {
+	SteamID SteamID `json:"steam_id"`
+}
+
+func (query *GetCurrentPlayerQuery) Validate() error {
+	bindErr := &BindError{}
+	if query.SteamID <= 0 {
+		bindErr.add("steam_id", "must be positive")
+	}
+
+	return bindErr.err()
+}
+
+// CurrentPlayer returns the current state of one player, or nil if the
+// player has never been polled.
+func (st *SteamTracker) CurrentPlayer(ctx context.Context, query *GetCurrentPlayerQuery) (*PlayerCurrentState, error) {
+	event := log.Debug().Str("action", "get_current_player").Str("steam_id", query.SteamID.String())
+	defer func() { event.Send() }()
+
+	state, err := getPlayerCurrentState(st.db.WithContext(ctx), query.SteamID)
+	if err != nil {
+		err = fmt.Errorf("failed to get current player: %w", err)
+		event.Err(err)
+		return nil, err
+	}
+
+	return state, nil
+}
+
+type GetCurrentPlayersQuery struct {
+	// Online limits the result to players who are not offline.
+	Online bool `json:"online"`
+}
+
+func (query *GetCurrentPlayersQuery) Validate() error {
+	return nil
+}
+
+type GetCurrentPlayersQueryResult struct {
+	Players []*PlayerCurrentState `json:"players"`
+}
+
+func (st *SteamTracker) CurrentPlayers(ctx context.Context, query *GetCurrentPlayersQuery) (*GetCurrentPlayersQueryResult, error) {
+	event := log.Debug().Str("action", "get_current_players")
+	defer func() { event.Send() }()
+
+	result := GetCurrentPlayersQueryResult{
+		Players: make([]*PlayerCurrentState, 0),
+	}
+
+	ss := st.db.WithContext(ctx).Model(&PlayerCurrentState{})
+	if query.Online {
+		ss = ss.Where("persona_state <> ?", PersonaStateOffline)
+		event.Bool("online", true)
+	}
+
+	if err := ss.Order("persona_name").Order("steam_id").Find(&result.Players).Error; err != nil {
+		err = fmt.Errorf("failed to get current players: %w", err)
+		event.Err(err)
+		return &result, err
+	}
+
+	return &result, nil
+}
+
+func (st *SteamTracker) GetCurrentPlayers(w http.ResponseWriter, r *http.Request) {
+	query := GetCurrentPlayersQuery{}
+	if err := bindQuery(r, &query); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query parameters: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	result, err := st.CurrentPlayers(r.Context(), &query)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get current players: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,172 @@
+package steamtracker_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+func addPlayer(t *testing.T, st *steamtracker.SteamTracker, steamID steamtracker.SteamID, state steamtracker.PersonaState, gameID string) *steamtracker.Player {
+	t.Helper()
+
+	player := &steamtracker.Player{SteamID: steamID, PersonaName: "Test Player", PersonaState: state, GameID: gameID}
+	if err := st.AddPlayer(player); err != nil {
+		t.Fatalf("Failed to add player: %v", err)
+	}
+	return player
+}
+
+func playerEventTypes(t *testing.T, st *steamtracker.SteamTracker, steamID steamtracker.SteamID) []steamtracker.PlayerEventType {
+	t.Helper()
+
+	query := steamtracker.SearchPlayerEventsQuery{SteamID: &steamID, Limit: 100}
+	if err := query.Validate(); err != nil {
+		t.Fatalf("Invalid query: %v", err)
+	}
+	result, err := st.SearchPlayerEvents(&query)
+	if err != nil {
+		t.Fatalf("Failed to search player events: %v", err)
+	}
+
+	types := make([]steamtracker.PlayerEventType, 0, len(result.PlayerEvents))
+	for _, playerEvent := range result.PlayerEvents {
+		types = append(types, playerEvent.Type)
+	}
+	slices.Sort(types)
+	return types
+}
+
+func TestRecordPlayerState(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	steamID := uniqueSteamID()
+
+	tests := []struct {
+		name      string
+		state     steamtracker.PersonaState
+		gameID    string
+		event     steamtracker.PlayerEventType // empty if no event is recorded
+		sinceIsAt bool                         // whether the state changed with this poll
+	}{
+		{"first seen", steamtracker.PersonaStateOnline, "", steamtracker.PlayerEventTypeFirstSeen, true},
+		{"unchanged", steamtracker.PersonaStateOnline, "", "", false},
+		{"game started", steamtracker.PersonaStateOnline, "570", steamtracker.PlayerEventTypeGame, false},
+		{"persona state", steamtracker.PersonaStateAway, "570", steamtracker.PlayerEventTypePersonaState, true},
+	}
+
+	var since time.Time
+	var lastEventID int64
+	wantTypes := make([]steamtracker.PlayerEventType, 0, len(tests))
+	for _, tt := range tests {
+		player := addPlayer(t, st, steamID, tt.state, tt.gameID)
+		if tt.sinceIsAt {
+			since = player.CreatedAt
+		}
+		if tt.event != "" {
+			wantTypes = append(wantTypes, tt.event)
+		}
+
+		state := currentPlayer(t, st, steamID)
+		if state == nil {
+			t.Fatalf("%s: expected a current state", tt.name)
+		}
+		if state.PersonaState != tt.state || state.GameID != tt.gameID || state.LastPlayerID != player.ID || !state.LastPolledAt.Equal(player.CreatedAt) {
+			t.Errorf("%s: expected the state of snapshot %d, got %+v", tt.name, player.ID, state)
+		}
+		if !state.StateSince.Equal(since) {
+			t.Errorf("%s: expected the state to be unchanged since %s, got %s", tt.name, since, state.StateSince)
+		}
+		if tt.event == "" && state.LastEventID != lastEventID {
+			t.Errorf("%s: expected the last event to stay %d, got %d", tt.name, lastEventID, state.LastEventID)
+		}
+		if tt.event != "" && state.LastEventID == lastEventID {
+			t.Errorf("%s: expected a new last event, got %d", tt.name, state.LastEventID)
+		}
+		lastEventID = state.LastEventID
+	}
+
+	slices.Sort(wantTypes)
+	if got := playerEventTypes(t, st, steamID); !slices.Equal(got, wantTypes) {
+		t.Errorf("Expected events %v, got %v", wantTypes, got)
+	}
+}
+
+func TestRebuildPlayerCurrentStates(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	online, offline := uniqueSteamID(), uniqueSteamID()+1
+
+	// A game event after the state change must not move StateSince.
+	addPlayer(t, st, online, steamtracker.PersonaStateOffline, "")
+	addPlayer(t, st, online, steamtracker.PersonaStateOnline, "")
+	addPlayer(t, st, online, steamtracker.PersonaStateOnline, "570")
+	addPlayer(t, st, online, steamtracker.PersonaStateOnline, "570")
+	addPlayer(t, st, offline, steamtracker.PersonaStateOffline, "")
+	recorded := map[steamtracker.SteamID]*steamtracker.PlayerCurrentState{
+		online:  currentPlayer(t, st, online),
+		offline: currentPlayer(t, st, offline),
+	}
+
+	count, err := st.RebuildPlayerCurrentStates(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("Failed to rebuild player current states: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 rebuilt players, got %d", count)
+	}
+	for steamID, want := range recorded {
+		if got := currentPlayer(t, st, steamID); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected the rebuilt state of %d to match the recorded one, got %+v, want %+v", steamID, got, want)
+		}
+	}
+}
+
+func TestGetCurrentPlayersOnline(t *testing.T) {
+	st := openTestTracker(t, testDSNs(t)["sqlite"])
+	online, away, offline := uniqueSteamID(), uniqueSteamID()+1, uniqueSteamID()+2
+
+	addPlayer(t, st, online, steamtracker.PersonaStateOnline, "")
+	addPlayer(t, st, away, steamtracker.PersonaStateAway, "")
+	addPlayer(t, st, offline, steamtracker.PersonaStateOffline, "")
+
+	tests := []struct {
+		query string
+		want  []steamtracker.SteamID
+	}{
+		{"", []steamtracker.SteamID{online, away, offline}},
+		{"?online=false", []steamtracker.SteamID{online, away, offline}},
+		{"?online=true", []steamtracker.SteamID{online, away}},
+	}
+
+	handler := st.Handler()
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/players/current"+tt.query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%q: expected 200, got %d: %s", tt.query, w.Code, w.Body)
+		}
+
+		var body steamtracker.GetCurrentPlayersQueryResult
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%q: failed to decode response: %v", tt.query, err)
+		}
+		got := make([]steamtracker.SteamID, 0, len(body.Players))
+		for _, state := range body.Players {
+			got = append(got, state.SteamID)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%q: expected players %v, got %v", tt.query, tt.want, got)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/players/current?online=maybe", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid online value, got %d", w.Code)
+	}
+}
@@ -0,0 +1,128 @@
+package steamtracker_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+// blockingSteamAPI answers GetPlayerSummaries for one player, holding every
+// request until release is closed.
+type blockingSteamAPI struct {
+	steamID  steamtracker.SteamID
+	requests atomic.Int64
+	started  chan struct{} // receives once per request
+	release  chan struct{}
+}
+
+func (api *blockingSteamAPI) RoundTrip(*http.Request) (*http.Response, error) {
+	api.requests.Add(1)
+	api.started <- struct{}{}
+	<-api.release
+
+	body := fmt.Sprintf(`{"response":{"players":[{"steamid":"%s","personaname":"Test Player","personastate":1}]}}`, api.steamID)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}, nil
+}
+
+func TestPollNowDuringScheduledPoll(t *testing.T) {
+	api := &blockingSteamAPI{steamID: uniqueSteamID(), started: make(chan struct{}, 10), release: make(chan struct{})}
+
+	origLogger := log.Logger
+	t.Cleanup(func() { log.Logger = origLogger })
+
+	st, err := steamtracker.New(&steamtracker.Config{
+		DatabaseDSN:           "sqlite://" + filepath.Join(t.TempDir(), "steamtracker.db"),
+		HTTPPort:              "0",
+		SteamAPIKey:           testSteamAPIKey,
+		SteamID:               api.steamID.String(),
+		HTTPClient:            &http.Client{Transport: api},
+		MaxTaskRetryCount:     1,
+		TaskInterval:          3600,
+		LogLevel:              zerolog.Disabled,
+		DisableAuth:           true,
+		AuditLogBufferSize:    16,
+		AuditLogBatchSize:     4,
+		AuditLogFlushInterval: 10,
+		AuditLogDropPolicy:    steamtracker.AuditLogDropPolicyBlock,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tracker: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- st.Run() }()
+
+	// Run polls once on start; hold that poll at the Steam API.
+	select {
+	case <-api.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the scheduled poll")
+	}
+
+	if err := st.PollNow(); !errors.Is(err, steamtracker.ErrPollInProgress) {
+		t.Fatalf("Expected ErrPollInProgress while the scheduled poll runs, got %v", err)
+	}
+	select {
+	case <-api.started:
+		t.Error("Expected the manual poll not to call the Steam API while the scheduled one runs")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(api.release)
+	for deadline := time.Now().Add(5 * time.Second); countPlayers(t, st, api.steamID) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the scheduled poll to store the player")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The refused request did not start the cooldown.
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		err := st.PollNow()
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, steamtracker.ErrPollInProgress) || time.Now().After(deadline) {
+			t.Fatalf("Failed to poll now once the scheduled poll finished: %v", err)
+		}
+	}
+	if err := st.PollNow(); !errors.Is(err, steamtracker.ErrPollTooSoon) {
+		t.Errorf("Expected ErrPollTooSoon right after a manual poll, got %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); countPlayers(t, st, api.steamID) < 2; {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the manual poll to store the player")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := st.Stop(); err != nil {
+		t.Fatalf("Failed to stop tracker: %v", err)
+	}
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("Expected Run to return cleanly, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Run to return once the tracker is stopped")
+	}
+
+	if got, want := countPlayers(t, st, api.steamID), api.requests.Load(); got != want {
+		t.Errorf("Expected one snapshot per Steam API request, got %d for %d", got, want)
+	}
+}
@@ -0,0 +1,1485 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: steamtracker/v1/steamtracker.proto
+
+package steamtrackerv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type PersonaState int32
+
+const (
+	PersonaState_PERSONA_STATE_UNSPECIFIED      PersonaState = 0
+	PersonaState_PERSONA_STATE_UNKNOWN          PersonaState = 1
+	PersonaState_PERSONA_STATE_OFFLINE          PersonaState = 2
+	PersonaState_PERSONA_STATE_ONLINE           PersonaState = 3
+	PersonaState_PERSONA_STATE_BUSY             PersonaState = 4
+	PersonaState_PERSONA_STATE_AWAY             PersonaState = 5
+	PersonaState_PERSONA_STATE_SNOOZE           PersonaState = 6
+	PersonaState_PERSONA_STATE_LOOKING_TO_TRADE PersonaState = 7
+	PersonaState_PERSONA_STATE_LOOKING_TO_PLAY  PersonaState = 8
+)
+
+// Enum value maps for PersonaState.
+var (
+	PersonaState_name = map[int32]string{
+		0: "PERSONA_STATE_UNSPECIFIED",
+		1: "PERSONA_STATE_UNKNOWN",
+		2: "PERSONA_STATE_OFFLINE",
+		3: "PERSONA_STATE_ONLINE",
+		4: "PERSONA_STATE_BUSY",
+		5: "PERSONA_STATE_AWAY",
+		6: "PERSONA_STATE_SNOOZE",
+		7: "PERSONA_STATE_LOOKING_TO_TRADE",
+		8: "PERSONA_STATE_LOOKING_TO_PLAY",
+	}
+	PersonaState_value = map[string]int32{
+		"PERSONA_STATE_UNSPECIFIED":      0,
+		"PERSONA_STATE_UNKNOWN":          1,
+		"PERSONA_STATE_OFFLINE":          2,
+		"PERSONA_STATE_ONLINE":           3,
+		"PERSONA_STATE_BUSY":             4,
+		"PERSONA_STATE_AWAY":             5,
+		"PERSONA_STATE_SNOOZE":           6,
+		"PERSONA_STATE_LOOKING_TO_TRADE": 7,
+		"PERSONA_STATE_LOOKING_TO_PLAY":  8,
+	}
+)
+
+func (x PersonaState) Enum() *PersonaState {
+	p := new(PersonaState)
+	*p = x
+	return p
+}
+
+func (x PersonaState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PersonaState) Descriptor() protoreflect.EnumDescriptor {
+	return file_steamtracker_v1_steamtracker_proto_enumTypes[0].Descriptor()
+}
+
+func (PersonaState) Type() protoreflect.EnumType {
+	return &file_steamtracker_v1_steamtracker_proto_enumTypes[0]
+}
+
+func (x PersonaState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PersonaState.Descriptor instead.
+func (PersonaState) EnumDescriptor() ([]byte, []int) {
+	return file_steamtracker_v1_steamtracker_proto_rawDescGZIP(), []int{0}
+}
+
+type PlayerEventType int32
+
+const (
+	PlayerEventType_PLAYER_EVENT_TYPE_UNSPECIFIED   PlayerEventType = 0
+	PlayerEventType_PLAYER_EVENT_TYPE_FIRST_SEEN    PlayerEventType = 1
+	PlayerEventType_PLAYER_EVENT_TYPE_PERSONA_STATE PlayerEventType = 2
+	PlayerEventType_PLAYER_EVENT_TYPE_GAME          PlayerEventType = 3
+)
+
+// Enum value maps for PlayerEventType.
+var (
+	PlayerEventType_name = map[int32]string{
+		0: "PLAYER_EVENT_TYPE_UNSPECIFIED",
+		1: "PLAYER_EVENT_TYPE_FIRST_SEEN",
+		2: "PLAYER_EVENT_TYPE_PERSONA_STATE",
+		3: "PLAYER_EVENT_TYPE_GAME",
+	}
+	PlayerEventType_value = map[string]int32{
+		"PLAYER_EVENT_TYPE_UNSPECIFIED":   0,
+		"PLAYER_EVENT_TYPE_FIRST_SEEN":    1,
+		"PLAYER_EVENT_TYPE_PERSONA_STATE": 2,
+		"PLAYER_EVENT_TYPE_GAME":          3,
+	}
+)
+
+func (x PlayerEventType) Enum() *PlayerEventType {
+	p := new(PlayerEventType)
+	*p = x
+	return p
+}
+
+func (x PlayerEventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PlayerEventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_steamtracker_v1_steamtracker_proto_enumTypes[1].Descriptor()
+}
+
+func (PlayerEventType) Type() protoreflect.EnumType {
+	return &file_steamtracker_v1_steamtracker_proto_enumTypes[1]
+}
+
+func (x PlayerEventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PlayerEventType.Descriptor instead.
+func (PlayerEventType) EnumDescriptor() ([]byte, []int) {
+	return file_steamtracker_v1_steamtracker_proto_rawDescGZIP(), []int{1}
+}
+
+type SortOrder int32
+
+const (
+	SortOrder_SORT_ORDER_UNSPECIFIED SortOrder = 0
+	SortOrder_SORT_ORDER_ASC         SortOrder = 1
+	SortOrder_SORT_ORDER_DESC        SortOrder = 2
+)
+
+// Enum value maps for SortOrder.
+var (
+	SortOrder_name = map[int32]string{
+		0: "SORT_ORDER_UNSPECIFIED",
+		1: "SORT_ORDER_ASC",
+		2: "SORT_ORDER_DESC",
+	}
+	SortOrder_value = map[string]int32{
+		"SORT_ORDER_UNSPECIFIED": 0,
+		"SORT_ORDER_ASC":         1,
+		"SORT_ORDER_DESC":        2,
+	}
+)
+
+func (x SortOrder) Enum() *SortOrder {
+	p := new(SortOrder)
+	*p = x
+	return p
+}
+
+func (x SortOrder) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SortOrder) Descriptor() protoreflect.EnumDescriptor {
+	return file_steamtracker_v1_steamtracker_proto_enumTypes[2].Descriptor()
+}
+
+func (SortOrder) Type() protoreflect.EnumType {
+	return &file_steamtracker_v1_steamtracker_proto_enumTypes[2]
+}
+
+func (x SortOrder) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SortOrder.Descriptor instead.
+func (SortOrder) EnumDescriptor() ([]byte, []int) {
+	return file_steamtracker_v1_steamtracker_proto_rawDescGZIP(), []int{2}
+}
+
+type Player struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	SteamId       int64                  `protobuf:"varint,2,opt,name=steam_id,json=steamId,proto3" json:"steam_id,omitempty"`
+	ProfileState  int32                  `protobuf:"varint,3,opt,name=profile_state,json=profileState,proto3" json:"profile_state,omitempty"`
+	PersonaName   string                 `protobuf:"bytes,4,opt,name=persona_name,json=personaName,proto3" json:"persona_name,omitempty"`
+	AvatarHash    string                 `protobuf:"bytes,5,opt,name=avatar_hash,json=avatarHash,proto3" json:"avatar_hash,omitempty"`
+	LastLogoff    int64                  `protobuf:"varint,6,opt,name=last_logoff,json=lastLogoff,proto3" json:"last_logoff,omitempty"`
+	PersonaState  PersonaState           `protobuf:"varint,7,opt,name=persona_state,json=personaState,proto3,enum=steamtracker.v1.PersonaState" json:"persona_state,omitempty"`
+	GameId        string                 `protobuf:"bytes,8,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Player) Reset() {
+	*x = Player{}
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Player) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Player) ProtoMessage() {}
+
+func (x *Player) ProtoReflect() protoreflect.Message {
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Player.ProtoReflect.Descriptor instead.
+func (*Player) Descriptor() ([]byte, []int) {
+	return file_steamtracker_v1_steamtracker_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Player) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Player) GetSteamId() int64 {
+	if x != nil {
+		return x.SteamId
+	}
+	return 0
+}
+
+func (x *Player) GetProfileState() int32 {
+	if x != nil {
+		return x.ProfileState
+	}
+	return 0
+}
+
+func (x *Player) GetPersonaName() string {
+	if x != nil {
+		return x.PersonaName
+	}
+	return ""
+}
+
+func (x *Player) GetAvatarHash() string {
+	if x != nil {
+		return x.AvatarHash
+	}
+	return ""
+}
+
+func (x *Player) GetLastLogoff() int64 {
+	if x != nil {
+		return x.LastLogoff
+	}
+	return 0
+}
+
+func (x *Player) GetPersonaState() PersonaState {
+	if x != nil {
+		return x.PersonaState
+	}
+	return PersonaState_PERSONA_STATE_UNSPECIFIED
+}
+
+func (x *Player) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+func (x *Player) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type PlayerEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	SteamId       int64                  `protobuf:"varint,2,opt,name=steam_id,json=steamId,proto3" json:"steam_id,omitempty"`
+	Type          PlayerEventType        `protobuf:"varint,3,opt,name=type,proto3,enum=steamtracker.v1.PlayerEventType" json:"type,omitempty"`
+	PersonaName   string                 `protobuf:"bytes,4,opt,name=persona_name,json=personaName,proto3" json:"persona_name,omitempty"`
+	PersonaState  PersonaState           `protobuf:"varint,5,opt,name=persona_state,json=personaState,proto3,enum=steamtracker.v1.PersonaState" json:"persona_state,omitempty"`
+	GameId        string                 `protobuf:"bytes,6,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PlayerEvent) Reset() {
+	*x = PlayerEvent{}
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PlayerEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PlayerEvent) ProtoMessage() {}
+
+func (x *PlayerEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PlayerEvent.ProtoReflect.Descriptor instead.
+func (*PlayerEvent) Descriptor() ([]byte, []int) {
+	return file_steamtracker_v1_steamtracker_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PlayerEvent) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *PlayerEvent) GetSteamId() int64 {
+	if x != nil {
+		return x.SteamId
+	}
+	return 0
+}
+
+func (x *PlayerEvent) GetType() PlayerEventType {
+	if x != nil {
+		return x.Type
+	}
+	return PlayerEventType_PLAYER_EVENT_TYPE_UNSPECIFIED
+}
+
+func (x *PlayerEvent) GetPersonaName() string {
+	if x != nil {
+		return x.PersonaName
+	}
+	return ""
+}
+
+func (x *PlayerEvent) GetPersonaState() PersonaState {
+	if x != nil {
+		return x.PersonaState
+	}
+	return PersonaState_PERSONA_STATE_UNSPECIFIED
+}
+
+func (x *PlayerEvent) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+func (x *PlayerEvent) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type AuditLog struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// raw is the JSON payload as it was logged.
+	Raw       string                 `protobuf:"bytes,2,opt,name=raw,proto3" json:"raw,omitempty"`
+	Level     string                 `protobuf:"bytes,3,opt,name=level,proto3" json:"level,omitempty"`
+	Message   string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	Action    string                 `protobuf:"bytes,5,opt,name=action,proto3" json:"action,omitempty"`
+	SteamId   *int64                 `protobuf:"varint,6,opt,name=steam_id,json=steamId,proto3,oneof" json:"steam_id,omitempty"`
+	Error     string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	LoggedAt  *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=logged_at,json=loggedAt,proto3" json:"logged_at,omitempty"`
+	PrevHash  string                 `protobuf:"bytes,9,opt,name=prev_hash,json=prevHash,proto3" json:"prev_hash,omitempty"`
+	Hash      string                 `protobuf:"bytes,10,opt,name=hash,proto3" json:"hash,omitempty"`
+	CreatedAt *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// snippet is the highlighted match of a full-text search.
+	Snippet       string `protobuf:"bytes,12,opt,name=snippet,proto3" json:"snippet,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuditLog) Reset() {
+	*x = AuditLog{}
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditLog) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditLog) ProtoMessage() {}
+
+func (x *AuditLog) ProtoReflect() protoreflect.Message {
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditLog.ProtoReflect.Descriptor instead.
+func (*AuditLog) Descriptor() ([]byte, []int) {
+	return file_steamtracker_v1_steamtracker_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AuditLog) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *AuditLog) GetRaw() string {
+	if x != nil {
+		return x.Raw
+	}
+	return ""
+}
+
+func (x *AuditLog) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *AuditLog) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *AuditLog) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *AuditLog) GetSteamId() int64 {
+	if x != nil && x.SteamId != nil {
+		return *x.SteamId
+	}
+	return 0
+}
+
+func (x *AuditLog) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *AuditLog) GetLoggedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LoggedAt
+	}
+	return nil
+}
+
+func (x *AuditLog) GetPrevHash() string {
+	if x != nil {
+		return x.PrevHash
+	}
+	return ""
+}
+
+func (x *AuditLog) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *AuditLog) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *AuditLog) GetSnippet() string {
+	if x != nil {
+		return x.Snippet
+	}
+	return ""
+}
+
+// Pagination mirrors the pagination of the HTTP API: page and limit skip
+// rows, a cursor continues after the last row of the previous page.
+type Pagination struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Page    int32                  `protobuf:"varint,1,opt,name=page,proto3" json:"page,omitempty"`
+	PerPage int32                  `protobuf:"varint,2,opt,name=per_page,json=perPage,proto3" json:"per_page,omitempty"`
+	// total_count is only set when the request asked for it.
+	TotalCount *int64 `protobuf:"varint,3,opt,name=total_count,json=totalCount,proto3,oneof" json:"total_count,omitempty"`
+	// next_cursor is empty on the last page.
+	NextCursor    string `protobuf:"bytes,4,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Pagination) Reset() {
+	*x = Pagination{}
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Pagination) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pagination) ProtoMessage() {}
+
+func (x *Pagination) ProtoReflect() protoreflect.Message {
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pagination.ProtoReflect.Descriptor instead.
+func (*Pagination) Descriptor() ([]byte, []int) {
+	return file_steamtracker_v1_steamtracker_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Pagination) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *Pagination) GetPerPage() int32 {
+	if x != nil {
+		return x.PerPage
+	}
+	return 0
+}
+
+func (x *Pagination) GetTotalCount() int64 {
+	if x != nil && x.TotalCount != nil {
+		return *x.TotalCount
+	}
+	return 0
+}
+
+func (x *Pagination) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type SearchPlayersRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Page  int32                  `protobuf:"varint,1,opt,name=page,proto3" json:"page,omitempty"`
+	Limit int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	// cursor switches to keyset pagination and takes precedence over page.
+	// An empty cursor starts at the first row.
+	Cursor          *string                `protobuf:"bytes,3,opt,name=cursor,proto3,oneof" json:"cursor,omitempty"`
+	IncludeTotal    *bool                  `protobuf:"varint,4,opt,name=include_total,json=includeTotal,proto3,oneof" json:"include_total,omitempty"`
+	SteamId         *int64                 `protobuf:"varint,5,opt,name=steam_id,json=steamId,proto3,oneof" json:"steam_id,omitempty"`
+	StartCreatedAt  *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=start_created_at,json=startCreatedAt,proto3" json:"start_created_at,omitempty"`
+	EndCreatedAt    *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=end_created_at,json=endCreatedAt,proto3" json:"end_created_at,omitempty"`
+	SortByCreatedAt SortOrder              `protobuf:"varint,8,opt,name=sort_by_created_at,json=sortByCreatedAt,proto3,enum=steamtracker.v1.SortOrder" json:"sort_by_created_at,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *SearchPlayersRequest) Reset() {
+	*x = SearchPlayersRequest{}
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchPlayersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchPlayersRequest) ProtoMessage() {}
+
+func (x *SearchPlayersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchPlayersRequest.ProtoReflect.Descriptor instead.
+func (*SearchPlayersRequest) Descriptor() ([]byte, []int) {
+	return file_steamtracker_v1_steamtracker_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SearchPlayersRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *SearchPlayersRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *SearchPlayersRequest) GetCursor() string {
+	if x != nil && x.Cursor != nil {
+		return *x.Cursor
+	}
+	return ""
+}
+
+func (x *SearchPlayersRequest) GetIncludeTotal() bool {
+	if x != nil && x.IncludeTotal != nil {
+		return *x.IncludeTotal
+	}
+	return false
+}
+
+func (x *SearchPlayersRequest) GetSteamId() int64 {
+	if x != nil && x.SteamId != nil {
+		return *x.SteamId
+	}
+	return 0
+}
+
+func (x *SearchPlayersRequest) GetStartCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartCreatedAt
+	}
+	return nil
+}
+
+func (x *SearchPlayersRequest) GetEndCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndCreatedAt
+	}
+	return nil
+}
+
+func (x *SearchPlayersRequest) GetSortByCreatedAt() SortOrder {
+	if x != nil {
+		return x.SortByCreatedAt
+	}
+	return SortOrder_SORT_ORDER_UNSPECIFIED
+}
+
+type SearchPlayersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Players       []*Player              `protobuf:"bytes,1,rep,name=players,proto3" json:"players,omitempty"`
+	Pagination    *Pagination            `protobuf:"bytes,2,opt,name=pagination,proto3" json:"pagination,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchPlayersResponse) Reset() {
+	*x = SearchPlayersResponse{}
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchPlayersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchPlayersResponse) ProtoMessage() {}
+
+func (x *SearchPlayersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchPlayersResponse.ProtoReflect.Descriptor instead.
+func (*SearchPlayersResponse) Descriptor() ([]byte, []int) {
+	return file_steamtracker_v1_steamtracker_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SearchPlayersResponse) GetPlayers() []*Player {
+	if x != nil {
+		return x.Players
+	}
+	return nil
+}
+
+func (x *SearchPlayersResponse) GetPagination() *Pagination {
+	if x != nil {
+		return x.Pagination
+	}
+	return nil
+}
+
+type SearchPlayerEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Page          int32                  `protobuf:"varint,1,opt,name=page,proto3" json:"page,omitempty"`
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Cursor        *string                `protobuf:"bytes,3,opt,name=cursor,proto3,oneof" json:"cursor,omitempty"`
+	IncludeTotal  *bool                  `protobuf:"varint,4,opt,name=include_total,json=includeTotal,proto3,oneof" json:"include_total,omitempty"`
+	SteamId       *int64                 `protobuf:"varint,5,opt,name=steam_id,json=steamId,proto3,oneof" json:"steam_id,omitempty"`
+	SteamIds      []int64                `protobuf:"varint,6,rep,packed,name=steam_ids,json=steamIds,proto3" json:"steam_ids,omitempty"`
+	Types         []PlayerEventType      `protobuf:"varint,7,rep,packed,name=types,proto3,enum=steamtracker.v1.PlayerEventType" json:"types,omitempty"`
+	PersonaStates []PersonaState         `protobuf:"varint,8,rep,packed,name=persona_states,json=personaStates,proto3,enum=steamtracker.v1.PersonaState" json:"persona_states,omitempty"`
+	// persona_name matches names containing it, ignoring case.
+	PersonaName     *string                `protobuf:"bytes,9,opt,name=persona_name,json=personaName,proto3,oneof" json:"persona_name,omitempty"`
+	StartCreatedAt  *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=start_created_at,json=startCreatedAt,proto3" json:"start_created_at,omitempty"`
+	EndCreatedAt    *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=end_created_at,json=endCreatedAt,proto3" json:"end_created_at,omitempty"`
+	SortByCreatedAt SortOrder              `protobuf:"varint,12,opt,name=sort_by_created_at,json=sortByCreatedAt,proto3,enum=steamtracker.v1.SortOrder" json:"sort_by_created_at,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *SearchPlayerEventsRequest) Reset() {
+	*x = SearchPlayerEventsRequest{}
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchPlayerEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchPlayerEventsRequest) ProtoMessage() {}
+
+func (x *SearchPlayerEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchPlayerEventsRequest.ProtoReflect.Descriptor instead.
+func (*SearchPlayerEventsRequest) Descriptor() ([]byte, []int) {
+	return file_steamtracker_v1_steamtracker_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SearchPlayerEventsRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *SearchPlayerEventsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *SearchPlayerEventsRequest) GetCursor() string {
+	if x != nil && x.Cursor != nil {
+		return *x.Cursor
+	}
+	return ""
+}
+
+func (x *SearchPlayerEventsRequest) GetIncludeTotal() bool {
+	if x != nil && x.IncludeTotal != nil {
+		return *x.IncludeTotal
+	}
+	return false
+}
+
+func (x *SearchPlayerEventsRequest) GetSteamId() int64 {
+	if x != nil && x.SteamId != nil {
+		return *x.SteamId
+	}
+	return 0
+}
+
+func (x *SearchPlayerEventsRequest) GetSteamIds() []int64 {
+	if x != nil {
+		return x.SteamIds
+	}
+	return nil
+}
+
+func (x *SearchPlayerEventsRequest) GetTypes() []PlayerEventType {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+func (x *SearchPlayerEventsRequest) GetPersonaStates() []PersonaState {
+	if x != nil {
+		return x.PersonaStates
+	}
+	return nil
+}
+
+func (x *SearchPlayerEventsRequest) GetPersonaName() string {
+	if x != nil && x.PersonaName != nil {
+		return *x.PersonaName
+	}
+	return ""
+}
+
+func (x *SearchPlayerEventsRequest) GetStartCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartCreatedAt
+	}
+	return nil
+}
+
+func (x *SearchPlayerEventsRequest) GetEndCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndCreatedAt
+	}
+	return nil
+}
+
+func (x *SearchPlayerEventsRequest) GetSortByCreatedAt() SortOrder {
+	if x != nil {
+		return x.SortByCreatedAt
+	}
+	return SortOrder_SORT_ORDER_UNSPECIFIED
+}
+
+type SearchPlayerEventsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PlayerEvents  []*PlayerEvent         `protobuf:"bytes,1,rep,name=player_events,json=playerEvents,proto3" json:"player_events,omitempty"`
+	Pagination    *Pagination            `protobuf:"bytes,2,opt,name=pagination,proto3" json:"pagination,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchPlayerEventsResponse) Reset() {
+	*x = SearchPlayerEventsResponse{}
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchPlayerEventsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchPlayerEventsResponse) ProtoMessage() {}
+
+func (x *SearchPlayerEventsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchPlayerEventsResponse.ProtoReflect.Descriptor instead.
+func (*SearchPlayerEventsResponse) Descriptor() ([]byte, []int) {
+	return file_steamtracker_v1_steamtracker_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SearchPlayerEventsResponse) GetPlayerEvents() []*PlayerEvent {
+	if x != nil {
+		return x.PlayerEvents
+	}
+	return nil
+}
+
+func (x *SearchPlayerEventsResponse) GetPagination() *Pagination {
+	if x != nil {
+		return x.Pagination
+	}
+	return nil
+}
+
+type SearchAuditLogsRequest struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Page         int32                  `protobuf:"varint,1,opt,name=page,proto3" json:"page,omitempty"`
+	Limit        int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Cursor       *string                `protobuf:"bytes,3,opt,name=cursor,proto3,oneof" json:"cursor,omitempty"`
+	IncludeTotal *bool                  `protobuf:"varint,4,opt,name=include_total,json=includeTotal,proto3,oneof" json:"include_total,omitempty"`
+	// q searches the raw payloads.
+	Q              *string                `protobuf:"bytes,5,opt,name=q,proto3,oneof" json:"q,omitempty"`
+	Level          *string                `protobuf:"bytes,6,opt,name=level,proto3,oneof" json:"level,omitempty"`
+	Action         *string                `protobuf:"bytes,7,opt,name=action,proto3,oneof" json:"action,omitempty"`
+	SteamId        *int64                 `protobuf:"varint,8,opt,name=steam_id,json=steamId,proto3,oneof" json:"steam_id,omitempty"`
+	StartLoggedAt  *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=start_logged_at,json=startLoggedAt,proto3" json:"start_logged_at,omitempty"`
+	EndLoggedAt    *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=end_logged_at,json=endLoggedAt,proto3" json:"end_logged_at,omitempty"`
+	HasError       *bool                  `protobuf:"varint,11,opt,name=has_error,json=hasError,proto3,oneof" json:"has_error,omitempty"`
+	SortById       SortOrder              `protobuf:"varint,12,opt,name=sort_by_id,json=sortById,proto3,enum=steamtracker.v1.SortOrder" json:"sort_by_id,omitempty"`
+	SortByLoggedAt SortOrder              `protobuf:"varint,13,opt,name=sort_by_logged_at,json=sortByLoggedAt,proto3,enum=steamtracker.v1.SortOrder" json:"sort_by_logged_at,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SearchAuditLogsRequest) Reset() {
+	*x = SearchAuditLogsRequest{}
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchAuditLogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchAuditLogsRequest) ProtoMessage() {}
+
+func (x *SearchAuditLogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchAuditLogsRequest.ProtoReflect.Descriptor instead.
+func (*SearchAuditLogsRequest) Descriptor() ([]byte, []int) {
+	return file_steamtracker_v1_steamtracker_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SearchAuditLogsRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *SearchAuditLogsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *SearchAuditLogsRequest) GetCursor() string {
+	if x != nil && x.Cursor != nil {
+		return *x.Cursor
+	}
+	return ""
+}
+
+func (x *SearchAuditLogsRequest) GetIncludeTotal() bool {
+	if x != nil && x.IncludeTotal != nil {
+		return *x.IncludeTotal
+	}
+	return false
+}
+
+func (x *SearchAuditLogsRequest) GetQ() string {
+	if x != nil && x.Q != nil {
+		return *x.Q
+	}
+	return ""
+}
+
+func (x *SearchAuditLogsRequest) GetLevel() string {
+	if x != nil && x.Level != nil {
+		return *x.Level
+	}
+	return ""
+}
+
+func (x *SearchAuditLogsRequest) GetAction() string {
+	if x != nil && x.Action != nil {
+		return *x.Action
+	}
+	return ""
+}
+
+func (x *SearchAuditLogsRequest) GetSteamId() int64 {
+	if x != nil && x.SteamId != nil {
+		return *x.SteamId
+	}
+	return 0
+}
+
+func (x *SearchAuditLogsRequest) GetStartLoggedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartLoggedAt
+	}
+	return nil
+}
+
+func (x *SearchAuditLogsRequest) GetEndLoggedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EndLoggedAt
+	}
+	return nil
+}
+
+func (x *SearchAuditLogsRequest) GetHasError() bool {
+	if x != nil && x.HasError != nil {
+		return *x.HasError
+	}
+	return false
+}
+
+func (x *SearchAuditLogsRequest) GetSortById() SortOrder {
+	if x != nil {
+		return x.SortById
+	}
+	return SortOrder_SORT_ORDER_UNSPECIFIED
+}
+
+func (x *SearchAuditLogsRequest) GetSortByLoggedAt() SortOrder {
+	if x != nil {
+		return x.SortByLoggedAt
+	}
+	return SortOrder_SORT_ORDER_UNSPECIFIED
+}
+
+type SearchAuditLogsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AuditLogs     []*AuditLog            `protobuf:"bytes,1,rep,name=audit_logs,json=auditLogs,proto3" json:"audit_logs,omitempty"`
+	Pagination    *Pagination            `protobuf:"bytes,2,opt,name=pagination,proto3" json:"pagination,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchAuditLogsResponse) Reset() {
+	*x = SearchAuditLogsResponse{}
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchAuditLogsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchAuditLogsResponse) ProtoMessage() {}
+
+func (x *SearchAuditLogsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchAuditLogsResponse.ProtoReflect.Descriptor instead.
+func (*SearchAuditLogsResponse) Descriptor() ([]byte, []int) {
+	return file_steamtracker_v1_steamtracker_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SearchAuditLogsResponse) GetAuditLogs() []*AuditLog {
+	if x != nil {
+		return x.AuditLogs
+	}
+	return nil
+}
+
+func (x *SearchAuditLogsResponse) GetPagination() *Pagination {
+	if x != nil {
+		return x.Pagination
+	}
+	return nil
+}
+
+type WatchPlayerEventsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// steam_ids limits the stream to these players, all players if empty.
+	SteamIds []int64 `protobuf:"varint,1,rep,packed,name=steam_ids,json=steamIds,proto3" json:"steam_ids,omitempty"`
+	// last_event_id first sends the events recorded after it.
+	LastEventId   *int64 `protobuf:"varint,2,opt,name=last_event_id,json=lastEventId,proto3,oneof" json:"last_event_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchPlayerEventsRequest) Reset() {
+	*x = WatchPlayerEventsRequest{}
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchPlayerEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchPlayerEventsRequest) ProtoMessage() {}
+
+func (x *WatchPlayerEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchPlayerEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchPlayerEventsRequest) Descriptor() ([]byte, []int) {
+	return file_steamtracker_v1_steamtracker_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *WatchPlayerEventsRequest) GetSteamIds() []int64 {
+	if x != nil {
+		return x.SteamIds
+	}
+	return nil
+}
+
+func (x *WatchPlayerEventsRequest) GetLastEventId() int64 {
+	if x != nil && x.LastEventId != nil {
+		return *x.LastEventId
+	}
+	return 0
+}
+
+type WatchPlayerEventsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PlayerEvent   *PlayerEvent           `protobuf:"bytes,1,opt,name=player_event,json=playerEvent,proto3" json:"player_event,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchPlayerEventsResponse) Reset() {
+	*x = WatchPlayerEventsResponse{}
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchPlayerEventsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchPlayerEventsResponse) ProtoMessage() {}
+
+func (x *WatchPlayerEventsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_steamtracker_v1_steamtracker_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchPlayerEventsResponse.ProtoReflect.Descriptor instead.
+func (*WatchPlayerEventsResponse) Descriptor() ([]byte, []int) {
+	return file_steamtracker_v1_steamtracker_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *WatchPlayerEventsResponse) GetPlayerEvent() *PlayerEvent {
+	if x != nil {
+		return x.PlayerEvent
+	}
+	return nil
+}
+
+var File_steamtracker_v1_steamtracker_proto protoreflect.FileDescriptor
+
+const file_steamtracker_v1_steamtracker_proto_rawDesc = "" +
+	"\n" +
+	"\"steamtracker/v1/steamtracker.proto\x12\x0fsteamtracker.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xd5\x02\n" +
+	"\x06Player\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x19\n" +
+	"\bsteam_id\x18\x02 \x01(\x03R\asteamId\x12#\n" +
+	"\rprofile_state\x18\x03 \x01(\x05R\fprofileState\x12!\n" +
+	"\fpersona_name\x18\x04 \x01(\tR\vpersonaName\x12\x1f\n" +
+	"\vavatar_hash\x18\x05 \x01(\tR\n" +
+	"avatarHash\x12\x1f\n" +
+	"\vlast_logoff\x18\x06 \x01(\x03R\n" +
+	"lastLogoff\x12B\n" +
+	"\rpersona_state\x18\a \x01(\x0e2\x1d.steamtracker.v1.PersonaStateR\fpersonaState\x12\x17\n" +
+	"\agame_id\x18\b \x01(\tR\x06gameId\x129\n" +
+	"\n" +
+	"created_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"\xa9\x02\n" +
+	"\vPlayerEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x19\n" +
+	"\bsteam_id\x18\x02 \x01(\x03R\asteamId\x124\n" +
+	"\x04type\x18\x03 \x01(\x0e2 .steamtracker.v1.PlayerEventTypeR\x04type\x12!\n" +
+	"\fpersona_name\x18\x04 \x01(\tR\vpersonaName\x12B\n" +
+	"\rpersona_state\x18\x05 \x01(\x0e2\x1d.steamtracker.v1.PersonaStateR\fpersonaState\x12\x17\n" +
+	"\agame_id\x18\x06 \x01(\tR\x06gameId\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"\xf6\x02\n" +
+	"\bAuditLog\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x10\n" +
+	"\x03raw\x18\x02 \x01(\tR\x03raw\x12\x14\n" +
+	"\x05level\x18\x03 \x01(\tR\x05level\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\x12\x16\n" +
+	"\x06action\x18\x05 \x01(\tR\x06action\x12\x1e\n" +
+	"\bsteam_id\x18\x06 \x01(\x03H\x00R\asteamId\x88\x01\x01\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x127\n" +
+	"\tlogged_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\bloggedAt\x12\x1b\n" +
+	"\tprev_hash\x18\t \x01(\tR\bprevHash\x12\x12\n" +
+	"\x04hash\x18\n" +
+	" \x01(\tR\x04hash\x129\n" +
+	"\n" +
+	"created_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x18\n" +
+	"\asnippet\x18\f \x01(\tR\asnippetB\v\n" +
+	"\t_steam_id\"\x92\x01\n" +
+	"\n" +
+	"Pagination\x12\x12\n" +
+	"\x04page\x18\x01 \x01(\x05R\x04page\x12\x19\n" +
+	"\bper_page\x18\x02 \x01(\x05R\aperPage\x12$\n" +
+	"\vtotal_count\x18\x03 \x01(\x03H\x00R\n" +
+	"totalCount\x88\x01\x01\x12\x1f\n" +
+	"\vnext_cursor\x18\x04 \x01(\tR\n" +
+	"nextCursorB\x0e\n" +
+	"\f_total_count\"\xa2\x03\n" +
+	"\x14SearchPlayersRequest\x12\x12\n" +
+	"\x04page\x18\x01 \x01(\x05R\x04page\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x1b\n" +
+	"\x06cursor\x18\x03 \x01(\tH\x00R\x06cursor\x88\x01\x01\x12(\n" +
+	"\rinclude_total\x18\x04 \x01(\bH\x01R\fincludeTotal\x88\x01\x01\x12\x1e\n" +
+	"\bsteam_id\x18\x05 \x01(\x03H\x02R\asteamId\x88\x01\x01\x12D\n" +
+	"\x10start_created_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x0estartCreatedAt\x12@\n" +
+	"\x0eend_created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\fendCreatedAt\x12G\n" +
+	"\x12sort_by_created_at\x18\b \x01(\x0e2\x1a.steamtracker.v1.SortOrderR\x0fsortByCreatedAtB\t\n" +
+	"\a_cursorB\x10\n" +
+	"\x0e_include_totalB\v\n" +
+	"\t_steam_id\"\x87\x01\n" +
+	"\x15SearchPlayersResponse\x121\n" +
+	"\aplayers\x18\x01 \x03(\v2\x17.steamtracker.v1.PlayerR\aplayers\x12;\n" +
+	"\n" +
+	"pagination\x18\x02 \x01(\v2\x1b.steamtracker.v1.PaginationR\n" +
+	"pagination\"\xfb\x04\n" +
+	"\x19SearchPlayerEventsRequest\x12\x12\n" +
+	"\x04page\x18\x01 \x01(\x05R\x04page\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x1b\n" +
+	"\x06cursor\x18\x03 \x01(\tH\x00R\x06cursor\x88\x01\x01\x12(\n" +
+	"\rinclude_total\x18\x04 \x01(\bH\x01R\fincludeTotal\x88\x01\x01\x12\x1e\n" +
+	"\bsteam_id\x18\x05 \x01(\x03H\x02R\asteamId\x88\x01\x01\x12\x1b\n" +
+	"\tsteam_ids\x18\x06 \x03(\x03R\bsteamIds\x126\n" +
+	"\x05types\x18\a \x03(\x0e2 .steamtracker.v1.PlayerEventTypeR\x05types\x12D\n" +
+	"\x0epersona_states\x18\b \x03(\x0e2\x1d.steamtracker.v1.PersonaStateR\rpersonaStates\x12&\n" +
+	"\fpersona_name\x18\t \x01(\tH\x03R\vpersonaName\x88\x01\x01\x12D\n" +
+	"\x10start_created_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\x0estartCreatedAt\x12@\n" +
+	"\x0eend_created_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\fendCreatedAt\x12G\n" +
+	"\x12sort_by_created_at\x18\f \x01(\x0e2\x1a.steamtracker.v1.SortOrderR\x0fsortByCreatedAtB\t\n" +
+	"\a_cursorB\x10\n" +
+	"\x0e_include_totalB\v\n" +
+	"\t_steam_idB\x0f\n" +
+	"\r_persona_name\"\x9c\x01\n" +
+	"\x1aSearchPlayerEventsResponse\x12A\n" +
+	"\rplayer_events\x18\x01 \x03(\v2\x1c.steamtracker.v1.PlayerEventR\fplayerEvents\x12;\n" +
+	"\n" +
+	"pagination\x18\x02 \x01(\v2\x1b.steamtracker.v1.PaginationR\n" +
+	"pagination\"\xee\x04\n" +
+	"\x16SearchAuditLogsRequest\x12\x12\n" +
+	"\x04page\x18\x01 \x01(\x05R\x04page\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x1b\n" +
+	"\x06cursor\x18\x03 \x01(\tH\x00R\x06cursor\x88\x01\x01\x12(\n" +
+	"\rinclude_total\x18\x04 \x01(\bH\x01R\fincludeTotal\x88\x01\x01\x12\x11\n" +
+	"\x01q\x18\x05 \x01(\tH\x02R\x01q\x88\x01\x01\x12\x19\n" +
+	"\x05level\x18\x06 \x01(\tH\x03R\x05level\x88\x01\x01\x12\x1b\n" +
+	"\x06action\x18\a \x01(\tH\x04R\x06action\x88\x01\x01\x12\x1e\n" +
+	"\bsteam_id\x18\b \x01(\x03H\x05R\asteamId\x88\x01\x01\x12B\n" +
+	"\x0fstart_logged_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\rstartLoggedAt\x12>\n" +
+	"\rend_logged_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\vendLoggedAt\x12 \n" +
+	"\thas_error\x18\v \x01(\bH\x06R\bhasError\x88\x01\x01\x128\n" +
+	"\n" +
+	"sort_by_id\x18\f \x01(\x0e2\x1a.steamtracker.v1.SortOrderR\bsortById\x12E\n" +
+	"\x11sort_by_logged_at\x18\r \x01(\x0e2\x1a.steamtracker.v1.SortOrderR\x0esortByLoggedAtB\t\n" +
+	"\a_cursorB\x10\n" +
+	"\x0e_include_totalB\x04\n" +
+	"\x02_qB\b\n" +
+	"\x06_levelB\t\n" +
+	"\a_actionB\v\n" +
+	"\t_steam_idB\f\n" +
+	"\n" +
+	"_has_error\"\x90\x01\n" +
+	"\x17SearchAuditLogsResponse\x128\n" +
+	"\n" +
+	"audit_logs\x18\x01 \x03(\v2\x19.steamtracker.v1.AuditLogR\tauditLogs\x12;\n" +
+	"\n" +
+	"pagination\x18\x02 \x01(\v2\x1b.steamtracker.v1.PaginationR\n" +
+	"pagination\"r\n" +
+	"\x18WatchPlayerEventsRequest\x12\x1b\n" +
+	"\tsteam_ids\x18\x01 \x03(\x03R\bsteamIds\x12'\n" +
+	"\rlast_event_id\x18\x02 \x01(\x03H\x00R\vlastEventId\x88\x01\x01B\x10\n" +
+	"\x0e_last_event_id\"\\\n" +
+	"\x19WatchPlayerEventsResponse\x12?\n" +
+	"\fplayer_event\x18\x01 \x01(\v2\x1c.steamtracker.v1.PlayerEventR\vplayerEvent*\x8e\x02\n" +
+	"\fPersonaState\x12\x1d\n" +
+	"\x19PERSONA_STATE_UNSPECIFIED\x10\x00\x12\x19\n" +
+	"\x15PERSONA_STATE_UNKNOWN\x10\x01\x12\x19\n" +
+	"\x15PERSONA_STATE_OFFLINE\x10\x02\x12\x18\n" +
+	"\x14PERSONA_STATE_ONLINE\x10\x03\x12\x16\n" +
+	"\x12PERSONA_STATE_BUSY\x10\x04\x12\x16\n" +
+	"\x12PERSONA_STATE_AWAY\x10\x05\x12\x18\n" +
+	"\x14PERSONA_STATE_SNOOZE\x10\x06\x12\"\n" +
+	"\x1ePERSONA_STATE_LOOKING_TO_TRADE\x10\a\x12!\n" +
+	"\x1dPERSONA_STATE_LOOKING_TO_PLAY\x10\b*\x97\x01\n" +
+	"\x0fPlayerEventType\x12!\n" +
+	"\x1dPLAYER_EVENT_TYPE_UNSPECIFIED\x10\x00\x12 \n" +
+	"\x1cPLAYER_EVENT_TYPE_FIRST_SEEN\x10\x01\x12#\n" +
+	"\x1fPLAYER_EVENT_TYPE_PERSONA_STATE\x10\x02\x12\x1a\n" +
+	"\x16PLAYER_EVENT_TYPE_GAME\x10\x03*P\n" +
+	"\tSortOrder\x12\x1a\n" +
+	"\x16SORT_ORDER_UNSPECIFIED\x10\x00\x12\x12\n" +
+	"\x0eSORT_ORDER_ASC\x10\x01\x12\x13\n" +
+	"\x0fSORT_ORDER_DESC\x10\x022\xb8\x03\n" +
+	"\x13SteamTrackerService\x12^\n" +
+	"\rSearchPlayers\x12%.steamtracker.v1.SearchPlayersRequest\x1a&.steamtracker.v1.SearchPlayersResponse\x12m\n" +
+	"\x12SearchPlayerEvents\x12*.steamtracker.v1.SearchPlayerEventsRequest\x1a+.steamtracker.v1.SearchPlayerEventsResponse\x12d\n" +
+	"\x0fSearchAuditLogs\x12'.steamtracker.v1.SearchAuditLogsRequest\x1a(.steamtracker.v1.SearchAuditLogsResponse\x12l\n" +
+	"\x11WatchPlayerEvents\x12).steamtracker.v1.WatchPlayerEventsRequest\x1a*.steamtracker.v1.WatchPlayerEventsResponse0\x01BIZGgithub.com/willywotz/steam-tracker/proto/steamtracker/v1;steamtrackerv1b\x06proto3"
+
+var (
+	file_steamtracker_v1_steamtracker_proto_rawDescOnce sync.Once
+	file_steamtracker_v1_steamtracker_proto_rawDescData []byte
+)
+
+func file_steamtracker_v1_steamtracker_proto_rawDescGZIP() []byte {
+	file_steamtracker_v1_steamtracker_proto_rawDescOnce.Do(func() {
+		file_steamtracker_v1_steamtracker_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_steamtracker_v1_steamtracker_proto_rawDesc), len(file_steamtracker_v1_steamtracker_proto_rawDesc)))
+	})
+	return file_steamtracker_v1_steamtracker_proto_rawDescData
+}
+
+var file_steamtracker_v1_steamtracker_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_steamtracker_v1_steamtracker_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_steamtracker_v1_steamtracker_proto_goTypes = []any{
+	(PersonaState)(0),                  // 0: steamtracker.v1.PersonaState
+	(PlayerEventType)(0),               // 1: steamtracker.v1.PlayerEventType
+	(SortOrder)(0),                     // 2: steamtracker.v1.SortOrder
+	(*Player)(nil),                     // 3: steamtracker.v1.Player
+	(*PlayerEvent)(nil),                // 4: steamtracker.v1.PlayerEvent
+	(*AuditLog)(nil),                   // 5: steamtracker.v1.AuditLog
+	(*Pagination)(nil),                 // 6: steamtracker.v1.Pagination
+	(*SearchPlayersRequest)(nil),       // 7: steamtracker.v1.SearchPlayersRequest
+	(*SearchPlayersResponse)(nil),      // 8: steamtracker.v1.SearchPlayersResponse
+	(*SearchPlayerEventsRequest)(nil),  // 9: steamtracker.v1.SearchPlayerEventsRequest
+	(*SearchPlayerEventsResponse)(nil), // 10: steamtracker.v1.SearchPlayerEventsResponse
+	(*SearchAuditLogsRequest)(nil),     // 11: steamtracker.v1.SearchAuditLogsRequest
+	(*SearchAuditLogsResponse)(nil),    // 12: steamtracker.v1.SearchAuditLogsResponse
+	(*WatchPlayerEventsRequest)(nil),   // 13: steamtracker.v1.WatchPlayerEventsRequest
+	(*WatchPlayerEventsResponse)(nil),  // 14: steamtracker.v1.WatchPlayerEventsResponse
+	(*timestamppb.Timestamp)(nil),      // 15: google.protobuf.Timestamp
+}
+var file_steamtracker_v1_steamtracker_proto_depIdxs = []int32{
+	0,  // 0: steamtracker.v1.Player.persona_state:type_name -> steamtracker.v1.PersonaState
+	15, // 1: steamtracker.v1.Player.created_at:type_name -> google.protobuf.Timestamp
+	1,  // 2: steamtracker.v1.PlayerEvent.type:type_name -> steamtracker.v1.PlayerEventType
+	0,  // 3: steamtracker.v1.PlayerEvent.persona_state:type_name -> steamtracker.v1.PersonaState
+	15, // 4: steamtracker.v1.PlayerEvent.created_at:type_name -> google.protobuf.Timestamp
+	15, // 5: steamtracker.v1.AuditLog.logged_at:type_name -> google.protobuf.Timestamp
+	15, // 6: steamtracker.v1.AuditLog.created_at:type_name -> google.protobuf.Timestamp
+	15, // 7: steamtracker.v1.SearchPlayersRequest.start_created_at:type_name -> google.protobuf.Timestamp
+	15, // 8: steamtracker.v1.SearchPlayersRequest.end_created_at:type_name -> google.protobuf.Timestamp
+	2,  // 9: steamtracker.v1.SearchPlayersRequest.sort_by_created_at:type_name -> steamtracker.v1.SortOrder
+	3,  // 10: steamtracker.v1.SearchPlayersResponse.players:type_name -> steamtracker.v1.Player
+	6,  // 11: steamtracker.v1.SearchPlayersResponse.pagination:type_name -> steamtracker.v1.Pagination
+	1,  // 12: steamtracker.v1.SearchPlayerEventsRequest.types:type_name -> steamtracker.v1.PlayerEventType
+	0,  // 13: steamtracker.v1.SearchPlayerEventsRequest.persona_states:type_name -> steamtracker.v1.PersonaState
+	15, // 14: steamtracker.v1.SearchPlayerEventsRequest.start_created_at:type_name -> google.protobuf.Timestamp
+	15, // 15: steamtracker.v1.SearchPlayerEventsRequest.end_created_at:type_name -> google.protobuf.Timestamp
+	2,  // 16: steamtracker.v1.SearchPlayerEventsRequest.sort_by_created_at:type_name -> steamtracker.v1.SortOrder
+	4,  // 17: steamtracker.v1.SearchPlayerEventsResponse.player_events:type_name -> steamtracker.v1.PlayerEvent
+	6,  // 18: steamtracker.v1.SearchPlayerEventsResponse.pagination:type_name -> steamtracker.v1.Pagination
+	15, // 19: steamtracker.v1.SearchAuditLogsRequest.start_logged_at:type_name -> google.protobuf.Timestamp
+	15, // 20: steamtracker.v1.SearchAuditLogsRequest.end_logged_at:type_name -> google.protobuf.Timestamp
+	2,  // 21: steamtracker.v1.SearchAuditLogsRequest.sort_by_id:type_name -> steamtracker.v1.SortOrder
+	2,  // 22: steamtracker.v1.SearchAuditLogsRequest.sort_by_logged_at:type_name -> steamtracker.v1.SortOrder
+	5,  // 23: steamtracker.v1.SearchAuditLogsResponse.audit_logs:type_name -> steamtracker.v1.AuditLog
+	6,  // 24: steamtracker.v1.SearchAuditLogsResponse.pagination:type_name -> steamtracker.v1.Pagination
+	4,  // 25: steamtracker.v1.WatchPlayerEventsResponse.player_event:type_name -> steamtracker.v1.PlayerEvent
+	7,  // 26: steamtracker.v1.SteamTrackerService.SearchPlayers:input_type -> steamtracker.v1.SearchPlayersRequest
+	9,  // 27: steamtracker.v1.SteamTrackerService.SearchPlayerEvents:input_type -> steamtracker.v1.SearchPlayerEventsRequest
+	11, // 28: steamtracker.v1.SteamTrackerService.SearchAuditLogs:input_type -> steamtracker.v1.SearchAuditLogsRequest
+	13, // 29: steamtracker.v1.SteamTrackerService.WatchPlayerEvents:input_type -> steamtracker.v1.WatchPlayerEventsRequest
+	8,  // 30: steamtracker.v1.SteamTrackerService.SearchPlayers:output_type -> steamtracker.v1.SearchPlayersResponse
+	10, // 31: steamtracker.v1.SteamTrackerService.SearchPlayerEvents:output_type -> steamtracker.v1.SearchPlayerEventsResponse
+	12, // 32: steamtracker.v1.SteamTrackerService.SearchAuditLogs:output_type -> steamtracker.v1.SearchAuditLogsResponse
+	14, // 33: steamtracker.v1.SteamTrackerService.WatchPlayerEvents:output_type -> steamtracker.v1.WatchPlayerEventsResponse
+	30, // [30:34] is the sub-list for method output_type
+	26, // [26:30] is the sub-list for method input_type
+	26, // [26:26] is the sub-list for extension type_name
+	26, // [26:26] is the sub-list for extension extendee
+	0,  // [0:26] is the sub-list for field type_name
+}
+
+func init() { file_steamtracker_v1_steamtracker_proto_init() }
+func file_steamtracker_v1_steamtracker_proto_init() {
+	if File_steamtracker_v1_steamtracker_proto != nil {
+		return
+	}
+	file_steamtracker_v1_steamtracker_proto_msgTypes[2].OneofWrappers = []any{}
+	file_steamtracker_v1_steamtracker_proto_msgTypes[3].OneofWrappers = []any{}
+	file_steamtracker_v1_steamtracker_proto_msgTypes[4].OneofWrappers = []any{}
+	file_steamtracker_v1_steamtracker_proto_msgTypes[6].OneofWrappers = []any{}
+	file_steamtracker_v1_steamtracker_proto_msgTypes[8].OneofWrappers = []any{}
+	file_steamtracker_v1_steamtracker_proto_msgTypes[10].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_steamtracker_v1_steamtracker_proto_rawDesc), len(file_steamtracker_v1_steamtracker_proto_rawDesc)),
+			NumEnums:      3,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_steamtracker_v1_steamtracker_proto_goTypes,
+		DependencyIndexes: file_steamtracker_v1_steamtracker_proto_depIdxs,
+		EnumInfos:         file_steamtracker_v1_steamtracker_proto_enumTypes,
+		MessageInfos:      file_steamtracker_v1_steamtracker_proto_msgTypes,
+	}.Build()
+	File_steamtracker_v1_steamtracker_proto = out.File
+	file_steamtracker_v1_steamtracker_proto_goTypes = nil
+	file_steamtracker_v1_steamtracker_proto_depIdxs = nil
+}
@@ -0,0 +1,259 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: steamtracker/v1/steamtracker.proto
+
+package steamtrackerv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SteamTrackerService_SearchPlayers_FullMethodName      = "/steamtracker.v1.SteamTrackerService/SearchPlayers"
+	SteamTrackerService_SearchPlayerEvents_FullMethodName = "/steamtracker.v1.SteamTrackerService/SearchPlayerEvents"
+	SteamTrackerService_SearchAuditLogs_FullMethodName    = "/steamtracker.v1.SteamTrackerService/SearchAuditLogs"
+	SteamTrackerService_WatchPlayerEvents_FullMethodName  = "/steamtracker.v1.SteamTrackerService/WatchPlayerEvents"
+)
+
+// SteamTrackerServiceClient is the client API for SteamTrackerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// SteamTrackerService serves the same data as the /api/v1 HTTP API. Invalid
+// requests fail with INVALID_ARGUMENT, with the message the HTTP API would
+// give.
+type SteamTrackerServiceClient interface {
+	// SearchPlayers lists the player snapshots taken by each poll.
+	SearchPlayers(ctx context.Context, in *SearchPlayersRequest, opts ...grpc.CallOption) (*SearchPlayersResponse, error)
+	// SearchPlayerEvents lists the recorded changes of persona state and game.
+	SearchPlayerEvents(ctx context.Context, in *SearchPlayerEventsRequest, opts ...grpc.CallOption) (*SearchPlayerEventsResponse, error)
+	// SearchAuditLogs lists and searches the audit logs.
+	SearchAuditLogs(ctx context.Context, in *SearchAuditLogsRequest, opts ...grpc.CallOption) (*SearchAuditLogsResponse, error)
+	// WatchPlayerEvents sends player events as they are recorded. A client
+	// that falls too far behind gets RESOURCE_EXHAUSTED and resumes with
+	// last_event_id set to the last event it received.
+	WatchPlayerEvents(ctx context.Context, in *WatchPlayerEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchPlayerEventsResponse], error)
+}
+
+type steamTrackerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSteamTrackerServiceClient(cc grpc.ClientConnInterface) SteamTrackerServiceClient {
+	return &steamTrackerServiceClient{cc}
+}
+
+func (c *steamTrackerServiceClient) SearchPlayers(ctx context.Context, in *SearchPlayersRequest, opts ...grpc.CallOption) (*SearchPlayersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchPlayersResponse)
+	err := c.cc.Invoke(ctx, SteamTrackerService_SearchPlayers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *steamTrackerServiceClient) SearchPlayerEvents(ctx context.Context, in *SearchPlayerEventsRequest, opts ...grpc.CallOption) (*SearchPlayerEventsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchPlayerEventsResponse)
+	err := c.cc.Invoke(ctx, SteamTrackerService_SearchPlayerEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *steamTrackerServiceClient) SearchAuditLogs(ctx context.Context, in *SearchAuditLogsRequest, opts ...grpc.CallOption) (*SearchAuditLogsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchAuditLogsResponse)
+	err := c.cc.Invoke(ctx, SteamTrackerService_SearchAuditLogs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *steamTrackerServiceClient) WatchPlayerEvents(ctx context.Context, in *WatchPlayerEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchPlayerEventsResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SteamTrackerService_ServiceDesc.Streams[0], SteamTrackerService_WatchPlayerEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchPlayerEventsRequest, WatchPlayerEventsResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SteamTrackerService_WatchPlayerEventsClient = grpc.ServerStreamingClient[WatchPlayerEventsResponse]
+
+// SteamTrackerServiceServer is the server API for SteamTrackerService service.
+// All implementations must embed UnimplementedSteamTrackerServiceServer
+// for forward compatibility.
+//
+// SteamTrackerService serves the same data as the /api/v1 HTTP API. Invalid
+// requests fail with INVALID_ARGUMENT, with the message the HTTP API would
+// give.
+type SteamTrackerServiceServer interface {
+	// SearchPlayers lists the player snapshots taken by each poll.
+	SearchPlayers(context.Context, *SearchPlayersRequest) (*SearchPlayersResponse, error)
+	// SearchPlayerEvents lists the recorded changes of persona state and game.
+	SearchPlayerEvents(context.Context, *SearchPlayerEventsRequest) (*SearchPlayerEventsResponse, error)
+	// SearchAuditLogs lists and searches the audit logs.
+	SearchAuditLogs(context.Context, *SearchAuditLogsRequest) (*SearchAuditLogsResponse, error)
+	// WatchPlayerEvents sends player events as they are recorded. A client
+	// that falls too far behind gets RESOURCE_EXHAUSTED and resumes with
+	// last_event_id set to the last event it received.
+	WatchPlayerEvents(*WatchPlayerEventsRequest, grpc.ServerStreamingServer[WatchPlayerEventsResponse]) error
+	mustEmbedUnimplementedSteamTrackerServiceServer()
+}
+
+// UnimplementedSteamTrackerServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSteamTrackerServiceServer struct{}
+
+func (UnimplementedSteamTrackerServiceServer) SearchPlayers(context.Context, *SearchPlayersRequest) (*SearchPlayersResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SearchPlayers not implemented")
+}
+func (UnimplementedSteamTrackerServiceServer) SearchPlayerEvents(context.Context, *SearchPlayerEventsRequest) (*SearchPlayerEventsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SearchPlayerEvents not implemented")
+}
+func (UnimplementedSteamTrackerServiceServer) SearchAuditLogs(context.Context, *SearchAuditLogsRequest) (*SearchAuditLogsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SearchAuditLogs not implemented")
+}
+func (UnimplementedSteamTrackerServiceServer) WatchPlayerEvents(*WatchPlayerEventsRequest, grpc.ServerStreamingServer[WatchPlayerEventsResponse]) error {
+	return status.Error(codes.Unimplemented, "method WatchPlayerEvents not implemented")
+}
+func (UnimplementedSteamTrackerServiceServer) mustEmbedUnimplementedSteamTrackerServiceServer() {}
+func (UnimplementedSteamTrackerServiceServer) testEmbeddedByValue()                             {}
+
+// UnsafeSteamTrackerServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SteamTrackerServiceServer will
+// result in compilation errors.
+type UnsafeSteamTrackerServiceServer interface {
+	mustEmbedUnimplementedSteamTrackerServiceServer()
+}
+
+func RegisterSteamTrackerServiceServer(s grpc.ServiceRegistrar, srv SteamTrackerServiceServer) {
+	// If the following call panics, it indicates UnimplementedSteamTrackerServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SteamTrackerService_ServiceDesc, srv)
+}
+
+func _SteamTrackerService_SearchPlayers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchPlayersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SteamTrackerServiceServer).SearchPlayers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SteamTrackerService_SearchPlayers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SteamTrackerServiceServer).SearchPlayers(ctx, req.(*SearchPlayersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SteamTrackerService_SearchPlayerEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchPlayerEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SteamTrackerServiceServer).SearchPlayerEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SteamTrackerService_SearchPlayerEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SteamTrackerServiceServer).SearchPlayerEvents(ctx, req.(*SearchPlayerEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SteamTrackerService_SearchAuditLogs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchAuditLogsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SteamTrackerServiceServer).SearchAuditLogs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SteamTrackerService_SearchAuditLogs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SteamTrackerServiceServer).SearchAuditLogs(ctx, req.(*SearchAuditLogsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SteamTrackerService_WatchPlayerEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPlayerEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SteamTrackerServiceServer).WatchPlayerEvents(m, &grpc.GenericServerStream[WatchPlayerEventsRequest, WatchPlayerEventsResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SteamTrackerService_WatchPlayerEventsServer = grpc.ServerStreamingServer[WatchPlayerEventsResponse]
+
+// SteamTrackerService_ServiceDesc is the grpc.ServiceDesc for SteamTrackerService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SteamTrackerService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "steamtracker.v1.SteamTrackerService",
+	HandlerType: (*SteamTrackerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SearchPlayers",
+			Handler:    _SteamTrackerService_SearchPlayers_Handler,
+		},
+		{
+			MethodName: "SearchPlayerEvents",
+			Handler:    _SteamTrackerService_SearchPlayerEvents_Handler,
+		},
+		{
+			MethodName: "SearchAuditLogs",
+			Handler:    _SteamTrackerService_SearchAuditLogs_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchPlayerEvents",
+			Handler:       _SteamTrackerService_WatchPlayerEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "steamtracker/v1/steamtracker.proto",
+}
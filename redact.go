@@ -0,0 +1,114 @@
+package steamtracker
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"regexp"
+	"sync"
+)
+
+const redacted = "REDACTED"
+
+// sensitiveQueryParams are query parameters whose values are never logged,
+// whatever the secret they hold.
+var sensitiveQueryParams = []string{"key", "access_token", "token"}
+
+var sensitiveQueryParamPattern = regexp.MustCompile(`([?&](?:key|access_token|token)=)[^&\s"\\]*`)
+
+// Redactor removes known secrets from log output. Besides the configured
+// secrets, it blanks the value of any sensitive query parameter, so a URL is
+// safe to log even if it carries a secret the redactor has not been told
+// about.
+type Redactor struct {
+	mu      sync.RWMutex
+	secrets [][]byte
+}
+
+func NewRedactor(secrets ...string) *Redactor {
+	r := &Redactor{}
+	r.SetSecrets(secrets...)
+	return r
+}
+
+// SetSecrets replaces the secrets to redact. Empty values are ignored.
+func (r *Redactor) SetSecrets(secrets ...string) {
+	values := make([][]byte, 0, len(secrets))
+	for _, secret := range secrets {
+		if secret != "" {
+			values = append(values, []byte(secret))
+		}
+	}
+
+	r.mu.Lock()
+	r.secrets = values
+	r.mu.Unlock()
+}
+
+func (r *Redactor) RedactBytes(p []byte) []byte {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, secret := range r.secrets {
+		if bytes.Contains(p, secret) {
+			p = bytes.ReplaceAll(p, secret, []byte(redacted))
+		}
+	}
+
+	return sensitiveQueryParamPattern.ReplaceAll(p, []byte("${1}"+redacted))
+}
+
+func (r *Redactor) Redact(s string) string {
+	return string(r.RedactBytes([]byte(s)))
+}
+
+// Writer wraps w so everything written through it is redacted first.
+func (r *Redactor) Writer(w io.Writer) io.Writer {
+	return &redactingWriter{w: w, r: r}
+}
+
+type redactingWriter struct {
+	w io.Writer
+	r *Redactor
+}
+
+func (rw *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := rw.w.Write(rw.r.RedactBytes(p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// RedactURL blanks the values of sensitive query parameters in rawURL.
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return sensitiveQueryParamPattern.ReplaceAllString(rawURL, "${1}"+redacted)
+	}
+
+	query := u.Query()
+	for _, param := range sensitiveQueryParams {
+		if query.Has(param) {
+			query.Set(param, redacted)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// redactURLError strips secrets from the URL that net/http puts into the
+// errors returned by Client.Do.
+func redactURLError(err error) error {
+	urlErr, ok := err.(*url.Error)
+	if !ok {
+		return err
+	}
+
+	return &url.Error{
+		Op:  urlErr.Op,
+		URL: RedactURL(urlErr.URL),
+		Err: urlErr.Err,
+	}
+}
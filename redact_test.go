@@ -0,0 +1,133 @@
+package steamtracker_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+const testSteamAPIKey = "0123456789ABCDEF0123456789ABCDEF"
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("i/o timeout")
+}
+
+func TestGetPlayerSummariesErrorRedactsKey(t *testing.T) {
+	client := &http.Client{Transport: failingTransport{}}
+
+	_, err := steamtracker.GetPlayerSummaries(client, testSteamAPIKey, "76561197960287930", 1)
+	if err == nil {
+		t.Fatal("Expected an error from a failing transport")
+	}
+	if strings.Contains(err.Error(), testSteamAPIKey) {
+		t.Fatalf("Error contains the API key: %v", err)
+	}
+	if !strings.Contains(err.Error(), "key=REDACTED") {
+		t.Fatalf("Expected the key parameter to be redacted: %v", err)
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	r := steamtracker.NewRedactor(testSteamAPIKey, "")
+
+	tests := map[string]string{
+		"plain " + testSteamAPIKey:                     "plain REDACTED",
+		"https://example.com/?key=other&steamids=1":    "https://example.com/?key=REDACTED&steamids=1",
+		`{"url":"https://example.com/?token=abc"}`:     `{"url":"https://example.com/?token=REDACTED"}`,
+		"nothing to hide":                              "nothing to hide",
+		"https://example.com/?monkey=1&access_token=a": "https://example.com/?monkey=1&access_token=REDACTED",
+	}
+	for input, want := range tests {
+		if got := r.Redact(input); got != want {
+			t.Errorf("Redact(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	r.SetSecrets("rotated")
+	if got := r.Redact("rotated " + testSteamAPIKey); got != "REDACTED "+testSteamAPIKey {
+		t.Errorf("Expected only the new secret to be redacted, got %q", got)
+	}
+}
+
+// TestLogSinksRedactKey logs the API key every way the tracker could and
+// checks that it reaches none of stdout, stderr or the audit log table.
+func TestLogSinksRedactKey(t *testing.T) {
+	dir := t.TempDir()
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	origStdout, origStderr, origLogger, origLevel := os.Stdout, os.Stderr, log.Logger, zerolog.GlobalLevel()
+	os.Stdout, os.Stderr = stdout, stderr
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr, log.Logger = origStdout, origStderr, origLogger
+		zerolog.SetGlobalLevel(origLevel)
+	})
+
+	st, err := steamtracker.New(&steamtracker.Config{
+		DatabaseDSN:           "sqlite://" + filepath.Join(dir, "steamtracker.db"),
+		HTTPPort:              "0",
+		SteamAPIKey:           testSteamAPIKey,
+		SteamID:               "76561197960287930",
+		MaxTaskRetryCount:     1,
+		TaskInterval:          60,
+		LogLevel:              zerolog.DebugLevel,
+		AuditLogBufferSize:    16,
+		AuditLogBatchSize:     4,
+		AuditLogFlushInterval: 10,
+		AuditLogDropPolicy:    steamtracker.AuditLogDropPolicyBlock,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tracker: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	_, pollErr := steamtracker.GetPlayerSummaries(&http.Client{Transport: failingTransport{}}, testSteamAPIKey, "76561197960287930", 1)
+	log.Error().Err(pollErr).Msg("Failed to get player summaries")
+	log.Error().Str("url", "https://api.steampowered.com/?key="+testSteamAPIKey).Msg("Request failed")
+	log.Info().Msgf("Using key %s", testSteamAPIKey)
+
+	if err := st.Stop(); err != nil {
+		t.Fatalf("Failed to stop tracker: %v", err)
+	}
+
+	var audit bytes.Buffer
+	if _, err := st.Export(context.Background(), &audit, &steamtracker.ExportQuery{Table: "audit_logs", Format: "jsonl"}); err != nil {
+		t.Fatalf("Failed to export audit logs: %v", err)
+	}
+
+	sinks := map[string][]byte{"audit_logs": audit.Bytes()}
+	for name, f := range map[string]*os.File{"stdout": stdout, "stderr": stderr} {
+		data, err := os.ReadFile(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		sinks[name] = data
+	}
+
+	for name, data := range sinks {
+		if bytes.Contains(data, []byte(testSteamAPIKey)) {
+			t.Errorf("API key reached %s:\n%s", name, data)
+		}
+		if !bytes.Contains(data, []byte("REDACTED")) {
+			t.Errorf("Expected redacted log lines in %s:\n%s", name, data)
+		}
+	}
+}
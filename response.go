@@ -27,23 +27,31 @@ func (r GetPlayerSummariesResponse) Player() *Player {
 	if len(r.Response.Players) == 0 {
 		return nil
 	}
-	p := r.Response.Players[0]
-	return &Player{
-		SteamID: p.SteamID,
-		// CommunityVisibilityState: p.CommunityVisibilityState,
-		ProfileState: p.ProfileState,
-		PersonaName:  p.PersonaName,
-		// ProfileUrl:               p.ProfileUrl,
-		// Avatar:                   p.Avatar,
-		// AvatarMedium:             p.AvatarMedium,
-		// AvatarFull:               p.AvatarFull,
-		AvatarHash:   p.AvatarHash,
-		LastLogoff:   p.LastLogoff,
-		PersonaState: p.PersonaState,
-		// PrimaryClanID:            p.PrimaryClanID,
-		// TimeCreated:              p.TimeCreated,
-		// PersonaStateFlags:        p.PersonaStateFlags,
-		// GameExtraInfo:            p.GameExtraInfo,
-		GameID: p.GameID,
+	return r.Players()[0]
+}
+
+// Players returns every player in the response, one per requested Steam ID.
+func (r GetPlayerSummariesResponse) Players() []*Player {
+	players := make([]*Player, 0, len(r.Response.Players))
+	for _, p := range r.Response.Players {
+		players = append(players, &Player{
+			SteamID: p.SteamID,
+			// CommunityVisibilityState: p.CommunityVisibilityState,
+			ProfileState: p.ProfileState,
+			PersonaName:  p.PersonaName,
+			// ProfileUrl:               p.ProfileUrl,
+			// Avatar:                   p.Avatar,
+			// AvatarMedium:             p.AvatarMedium,
+			// AvatarFull:               p.AvatarFull,
+			AvatarHash:   p.AvatarHash,
+			LastLogoff:   p.LastLogoff,
+			PersonaState: p.PersonaState,
+			// PrimaryClanID:            p.PrimaryClanID,
+			// TimeCreated:              p.TimeCreated,
+			// PersonaStateFlags:        p.PersonaStateFlags,
+			GameName: p.GameExtraInfo,
+			GameID:   p.GameID,
+		})
 	}
+	return players
 }
@@ -0,0 +1,227 @@
+package steamtracker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// RetentionPolicy describes how long rows of one table are kept.
+//
+// Rows older than DownsampleAfter are thinned to one row per SteamID per
+// DownsampleInterval, and rows older than MaxAge are deleted outright. A zero
+// duration disables the corresponding step.
+type RetentionPolicy struct {
+	Table              string        `json:"table"`
+	MaxAge             time.Duration `json:"max_age"`
+	DownsampleAfter    time.Duration `json:"downsample_after"`
+	DownsampleInterval time.Duration `json:"downsample_interval"`
+}
+
+// downsampleTables are the tables whose rows are per-player snapshots and can
+// therefore be thinned without losing transitions.
+var downsampleTables = map[string]bool{"players": true}
+
+func (p RetentionPolicy) Validate() error {
+	if p.Table == "" {
+		return fmt.Errorf("retention policy table cannot be empty")
+	}
+	if p.MaxAge < 0 || p.DownsampleAfter < 0 || p.DownsampleInterval < 0 {
+		return fmt.Errorf("retention durations for %s cannot be negative", p.Table)
+	}
+	if (p.DownsampleAfter > 0) != (p.DownsampleInterval > 0) {
+		return fmt.Errorf("retention policy for %s must set both downsample_after and downsample_interval", p.Table)
+	}
+	if p.DownsampleAfter > 0 && !downsampleTables[p.Table] {
+		return fmt.Errorf("table %s does not support downsampling", p.Table)
+	}
+	if p.MaxAge > 0 && p.DownsampleAfter > 0 && p.MaxAge <= p.DownsampleAfter {
+		return fmt.Errorf("retention max_age for %s must be longer than downsample_after", p.Table)
+	}
+
+	return nil
+}
+
+type RetentionTableReport struct {
+	Table       string `json:"table"`
+	Expired     int64  `json:"expired"`
+	Downsampled int64  `json:"downsampled"`
+}
+
+type RetentionReport struct {
+	DryRun bool                    `json:"dry_run"`
+	Tables []*RetentionTableReport `json:"tables"`
+}
+
+type retentionRow struct {
+	ID        int64
+	SteamID   SteamID
+	CreatedAt time.Time
+}
+
+// ApplyRetention enforces every configured policy. With dryRun set it only
+// counts the rows that would be removed.
+func (st *SteamTracker) ApplyRetention(ctx context.Context, dryRun bool) (*RetentionReport, error) {
+	report := RetentionReport{
+		DryRun: dryRun,
+		Tables: make([]*RetentionTableReport, 0, len(st.cfg.RetentionPolicies)),
+	}
+
+	now := time.Now()
+	for _, policy := range st.cfg.RetentionPolicies {
+		event := log.Info().
+			Str("action", "apply_retention").
+			Str("table", policy.Table).
+			Bool("dry_run", dryRun)
+
+		tableReport, err := st.applyRetentionPolicy(ctx, policy, now, dryRun)
+		if tableReport != nil {
+			report.Tables = append(report.Tables, tableReport)
+			event.Int64("expired", tableReport.Expired).Int64("downsampled", tableReport.Downsampled)
+		}
+		if err != nil {
+			event.Err(err).Send()
+			return &report, err
+		}
+		event.Send()
+	}
+
+	return &report, nil
+}
+
+func (st *SteamTracker) applyRetentionPolicy(ctx context.Context, policy RetentionPolicy, now time.Time, dryRun bool) (*RetentionTableReport, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	model, err := st.dataTable(policy.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	report := RetentionTableReport{Table: policy.Table}
+
+	var expiredBefore time.Time
+	if policy.MaxAge > 0 {
+		expiredBefore = now.Add(-policy.MaxAge)
+		if report.Expired, err = st.pruneExpired(ctx, model, expiredBefore, dryRun); err != nil {
+			return &report, fmt.Errorf("failed to prune expired %s: %w", policy.Table, err)
+		}
+	}
+
+	if policy.DownsampleAfter > 0 {
+		if report.Downsampled, err = st.downsample(ctx, model, expiredBefore, now.Add(-policy.DownsampleAfter), policy.DownsampleInterval, dryRun); err != nil {
+			return &report, fmt.Errorf("failed to downsample %s: %w", policy.Table, err)
+		}
+	}
+
+	return &report, nil
+}
+
+// pruneExpired deletes rows created before cutoff in batches, so a large
+// backlog never holds one long write lock.
+func (st *SteamTracker) pruneExpired(ctx context.Context, model any, cutoff time.Time, dryRun bool) (int64, error) {
+	if dryRun {
+		var count int64
+		err := st.db.WithContext(ctx).Model(model).Where("created_at < ?", cutoff).Count(&count).Error
+		return count, err
+	}
+
+	var total int64
+	for {
+		ids := make([]int64, 0, st.cfg.RetentionBatchSize)
+		if err := st.db.WithContext(ctx).Model(model).Where("created_at < ?", cutoff).Order("id").Limit(st.cfg.RetentionBatchSize).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+
+		deleted, err := st.deleteIDs(ctx, model, ids)
+		total += deleted
+		if err != nil {
+			return total, err
+		}
+		if len(ids) < st.cfg.RetentionBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// downsample keeps the first row per SteamID per interval among the rows
+// created in [from, before) and deletes the rest. Rows are walked with a
+// keyset on (created_at, id) so only one batch is held in memory at a time.
+func (st *SteamTracker) downsample(ctx context.Context, model any, from, before time.Time, interval time.Duration, dryRun bool) (int64, error) {
+	keptBuckets := make(map[SteamID]time.Time)
+	pending := make([]int64, 0, st.cfg.RetentionBatchSize)
+
+	var total int64
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		if dryRun {
+			total += int64(len(pending))
+			pending = pending[:0]
+			return nil
+		}
+		deleted, err := st.deleteIDs(ctx, model, pending)
+		total += deleted
+		pending = pending[:0]
+		return err
+	}
+
+	var last *retentionRow
+	for {
+		rows := make([]retentionRow, 0, st.cfg.RetentionBatchSize)
+		ss := st.db.WithContext(ctx).Model(model).
+			Select("id", "steam_id", "created_at").
+			Where("created_at >= ? AND created_at < ?", from, before)
+		if last != nil {
+			ss = ss.Where("(created_at > ? OR (created_at = ? AND id > ?))", last.CreatedAt, last.CreatedAt, last.ID)
+		}
+		if err := ss.Order("created_at").Order("id").Limit(st.cfg.RetentionBatchSize).Find(&rows).Error; err != nil {
+			return total, err
+		}
+
+		for i := range rows {
+			bucket := rows[i].CreatedAt.Truncate(interval)
+			if kept, ok := keptBuckets[rows[i].SteamID]; ok && kept.Equal(bucket) {
+				pending = append(pending, rows[i].ID)
+				continue
+			}
+			keptBuckets[rows[i].SteamID] = bucket
+		}
+
+		if err := flush(); err != nil {
+			return total, err
+		}
+		if len(rows) < st.cfg.RetentionBatchSize {
+			return total, nil
+		}
+		last = &rows[len(rows)-1]
+	}
+}
+
+func (st *SteamTracker) deleteIDs(ctx context.Context, model any, ids []int64) (int64, error) {
+	var deleted int64
+	err := st.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id IN ?", ids).Delete(model)
+		deleted = res.RowsAffected
+		return res.Error
+	})
+
+	return deleted, err
+}
+
+func (st *SteamTracker) retentionTask() {
+	st.wg.Add(1)
+	defer st.wg.Done()
+
+	if _, err := st.ApplyRetention(st.ctx, false); err != nil {
+		log.Error().Err(err).Msg("Failed to apply retention policies")
+	}
+}
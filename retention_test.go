@@ -0,0 +1,146 @@
+package steamtracker_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+// importPlayers stores snapshots of steamID taken at the given times through
+// the JSON Lines import, the only way to write history with past timestamps.
+func importPlayers(t *testing.T, st *steamtracker.SteamTracker, steamID steamtracker.SteamID, state steamtracker.PersonaState, times ...time.Time) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, at := range times {
+		row := map[string]any{"steam_id": steamID, "persona_name": "Test Player", "persona_state": state, "created_at": at}
+		if err := enc.Encode(row); err != nil {
+			t.Fatalf("Failed to encode player: %v", err)
+		}
+	}
+
+	result, err := st.Import(t.Context(), &buf, &steamtracker.ImportCommand{Table: "players", Format: steamtracker.ExportFormatJSONL})
+	if err != nil {
+		t.Fatalf("Failed to import players: %v", err)
+	}
+	if result.Inserted != int64(len(times)) {
+		t.Fatalf("Expected %d players to be imported, got %d", len(times), result.Inserted)
+	}
+}
+
+func openRetentionTracker(t *testing.T, policies ...steamtracker.RetentionPolicy) *steamtracker.SteamTracker {
+	t.Helper()
+
+	st, err := steamtracker.Open(&steamtracker.Config{
+		DatabaseDSN:        "sqlite://" + filepath.Join(t.TempDir(), "steamtracker.db"),
+		RetentionPolicies:  policies,
+		RetentionInterval:  60,
+		RetentionBatchSize: 2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open tracker: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	return st
+}
+
+func TestApplyRetention(t *testing.T) {
+	st := openRetentionTracker(t, steamtracker.RetentionPolicy{
+		Table:              "players",
+		MaxAge:             7 * 24 * time.Hour,
+		DownsampleAfter:    24 * time.Hour,
+		DownsampleInterval: time.Hour,
+	})
+	steamID := uniqueSteamID()
+
+	now := time.Now()
+	expired := now.Add(-10 * 24 * time.Hour)
+	hour := now.Add(-3 * 24 * time.Hour).Truncate(time.Hour)
+	importPlayers(t, st, steamID, steamtracker.PersonaStateOnline,
+		// Older than max_age.
+		expired, expired.Add(time.Minute), expired.Add(2*time.Minute),
+		// Old enough to downsample: the first row of each hour is kept.
+		hour, hour.Add(10*time.Minute), hour.Add(20*time.Minute), hour.Add(65*time.Minute),
+		// Recent rows are left alone.
+		now.Add(-time.Hour), now.Add(-time.Hour+time.Minute),
+	)
+
+	report, err := st.ApplyRetention(t.Context(), true)
+	if err != nil {
+		t.Fatalf("Failed to apply retention: %v", err)
+	}
+	if !report.DryRun || len(report.Tables) != 1 || report.Tables[0].Expired != 3 || report.Tables[0].Downsampled != 2 {
+		t.Fatalf("Expected a dry run counting 3 expired and 2 downsampled rows, got %+v", report.Tables[0])
+	}
+	if count := countPlayers(t, st, steamID); count != 9 {
+		t.Fatalf("Expected a dry run to keep all 9 rows, got %d", count)
+	}
+
+	report, err = st.ApplyRetention(t.Context(), false)
+	if err != nil {
+		t.Fatalf("Failed to apply retention: %v", err)
+	}
+	if report.DryRun || report.Tables[0].Expired != 3 || report.Tables[0].Downsampled != 2 {
+		t.Fatalf("Expected 3 expired and 2 downsampled rows, got %+v", report.Tables[0])
+	}
+
+	order := "asc"
+	query := steamtracker.SearchPlayersQuery{SteamID: &steamID, Limit: 100}
+	query.SortBy.CreatedAt = &order
+	if err := query.Validate(); err != nil {
+		t.Fatalf("Invalid query: %v", err)
+	}
+	result, err := st.SearchPlayers(t.Context(), &query)
+	if err != nil {
+		t.Fatalf("Failed to search players: %v", err)
+	}
+
+	want := []time.Time{hour, hour.Add(65 * time.Minute), now.Add(-time.Hour), now.Add(-time.Hour + time.Minute)}
+	if len(result.Players) != len(want) {
+		t.Fatalf("Expected %d players to remain, got %d", len(want), len(result.Players))
+	}
+	for i, player := range result.Players {
+		if !player.CreatedAt.Equal(want[i]) {
+			t.Errorf("Expected player %d at %s, got %s", i, want[i], player.CreatedAt)
+		}
+	}
+
+	report, err = st.ApplyRetention(t.Context(), false)
+	if err != nil {
+		t.Fatalf("Failed to apply retention: %v", err)
+	}
+	if report.Tables[0].Expired != 0 || report.Tables[0].Downsampled != 0 {
+		t.Errorf("Expected a second run to remove nothing, got %+v", report.Tables[0])
+	}
+}
+
+func TestRetentionPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy steamtracker.RetentionPolicy
+		valid  bool
+	}{
+		{"max_age", steamtracker.RetentionPolicy{Table: "audit_logs", MaxAge: time.Hour}, true},
+		{"downsample", steamtracker.RetentionPolicy{Table: "players", DownsampleAfter: time.Hour, DownsampleInterval: time.Minute}, true},
+		{"no table", steamtracker.RetentionPolicy{MaxAge: time.Hour}, false},
+		{"negative", steamtracker.RetentionPolicy{Table: "players", MaxAge: -time.Hour}, false},
+		{"interval without after", steamtracker.RetentionPolicy{Table: "players", DownsampleInterval: time.Minute}, false},
+		{"downsample events", steamtracker.RetentionPolicy{Table: "player_events", DownsampleAfter: time.Hour, DownsampleInterval: time.Minute}, false},
+		{"max_age before downsample", steamtracker.RetentionPolicy{Table: "players", MaxAge: time.Hour, DownsampleAfter: 2 * time.Hour, DownsampleInterval: time.Minute}, false},
+	}
+
+	for _, tt := range tests {
+		if err := tt.policy.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got %v", tt.name, tt.valid, err)
+		}
+	}
+}
@@ -0,0 +1,635 @@
+package steamtracker
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PlayerRollup summarises one player's presence within one UTC time bucket.
+// Intervals between two consecutive snapshots are attributed to the state of
+// the earlier snapshot; gaps longer than the rollup max gap (for example while
+// the tracker was down) are not attributed at all.
+type PlayerRollup struct {
+	SteamID     SteamID   `json:"steam_id" gorm:"primaryKey;autoIncrement:false"`
+	BucketStart time.Time `json:"bucket_start" gorm:"primaryKey"`
+
+	OfflineSeconds        int64 `json:"offline_seconds"`
+	OnlineSeconds         int64 `json:"online_seconds"`
+	BusySeconds           int64 `json:"busy_seconds"`
+	AwaySeconds           int64 `json:"away_seconds"`
+	SnoozeSeconds         int64 `json:"snooze_seconds"`
+	LookingToTradeSeconds int64 `json:"looking_to_trade_seconds"`
+	LookingToPlaySeconds  int64 `json:"looking_to_play_seconds"`
+	InGameSeconds         int64 `json:"in_game_seconds"`
+
+	Sessions      int     `json:"sessions"`
+	Games         GameIDs `json:"games" gorm:"type:text"`
+	DistinctGames int     `json:"distinct_games"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type PlayerHourlyRollup struct {
+	PlayerRollup
+}
+
+type PlayerDailyRollup struct {
+	PlayerRollup
+}
+
+func (r *PlayerHourlyRollup) rollup() *PlayerRollup { return &r.PlayerRollup }
+func (r *PlayerDailyRollup) rollup() *PlayerRollup  { return &r.PlayerRollup }
+
+func (r *PlayerRollup) addStateSeconds(state PersonaState, seconds int64) {
+	switch state {
+	case PersonaStateOffline:
+		r.OfflineSeconds += seconds
+	case PersonaStateOnline:
+		r.OnlineSeconds += seconds
+	case PersonaStateBusy:
+		r.BusySeconds += seconds
+	case PersonaStateAway:
+		r.AwaySeconds += seconds
+	case PersonaStateSnooze:
+		r.SnoozeSeconds += seconds
+	case PersonaStateLookingToTrade:
+		r.LookingToTradeSeconds += seconds
+	case PersonaStateLookingToPlay:
+		r.LookingToPlaySeconds += seconds
+	}
+}
+
+func (r *PlayerRollup) merge(delta *PlayerRollup) {
+	r.OfflineSeconds += delta.OfflineSeconds
+	r.OnlineSeconds += delta.OnlineSeconds
+	r.BusySeconds += delta.BusySeconds
+	r.AwaySeconds += delta.AwaySeconds
+	r.SnoozeSeconds += delta.SnoozeSeconds
+	r.LookingToTradeSeconds += delta.LookingToTradeSeconds
+	r.LookingToPlaySeconds += delta.LookingToPlaySeconds
+	r.InGameSeconds += delta.InGameSeconds
+	r.Sessions += delta.Sessions
+	for _, game := range delta.Games {
+		r.Games = r.Games.add(game)
+	}
+	r.DistinctGames = len(r.Games)
+}
+
+// GameIDs is a sorted set of game IDs stored as a comma-separated string.
+type GameIDs []string
+
+func (g GameIDs) add(gameID string) GameIDs {
+	if gameID == "" {
+		return g
+	}
+	i, found := slices.BinarySearch(g, gameID)
+	if found {
+		return g
+	}
+	return slices.Insert(g, i, gameID)
+}
+
+func (g *GameIDs) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("unsupported type for GameIDs: %T", value)
+	}
+
+	*g = GameIDs{}
+	if s != "" {
+		*g = strings.Split(s, ",")
+	}
+	return nil
+}
+
+func (g GameIDs) Value() (driver.Value, error) {
+	return strings.Join(g, ","), nil
+}
+
+func (g GameIDs) MarshalJSON() ([]byte, error) {
+	if g == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(g))
+}
+
+type rollupKey struct {
+	SteamID     SteamID
+	BucketStart time.Time
+}
+
+// rollupAccumulator collects rollup deltas in memory for one or more pairs of
+// consecutive snapshots.
+type rollupAccumulator struct {
+	maxGap time.Duration
+	hourly map[rollupKey]*PlayerRollup
+	daily  map[rollupKey]*PlayerRollup
+}
+
+func newRollupAccumulator(maxGap time.Duration) *rollupAccumulator {
+	return &rollupAccumulator{
+		maxGap: maxGap,
+		hourly: make(map[rollupKey]*PlayerRollup),
+		daily:  make(map[rollupKey]*PlayerRollup),
+	}
+}
+
+func (a *rollupAccumulator) buckets(steamID SteamID, at time.Time) []*PlayerRollup {
+	at = at.UTC()
+	get := func(m map[rollupKey]*PlayerRollup, start time.Time) *PlayerRollup {
+		key := rollupKey{SteamID: steamID, BucketStart: start}
+		if r, ok := m[key]; ok {
+			return r
+		}
+		r := &PlayerRollup{SteamID: steamID, BucketStart: start}
+		m[key] = r
+		return r
+	}
+
+	return []*PlayerRollup{
+		get(a.hourly, at.Truncate(time.Hour)),
+		get(a.daily, utcDay(at)),
+	}
+}
+
+// add accounts for the interval between prev and cur. prev may be nil for the
+// first snapshot of a player.
+func (a *rollupAccumulator) add(prev, cur *Player) {
+	if prev != nil && (cur.CreatedAt.Sub(prev.CreatedAt) > a.maxGap || !cur.CreatedAt.After(prev.CreatedAt)) {
+		prev = nil
+	}
+
+	if prev != nil {
+		for start := prev.CreatedAt.UTC(); start.Before(cur.CreatedAt); {
+			end := start.Truncate(time.Hour).Add(time.Hour)
+			if end.After(cur.CreatedAt) {
+				end = cur.CreatedAt.UTC()
+			}
+			seconds := int64(end.Sub(start).Round(time.Second) / time.Second)
+
+			for _, r := range a.buckets(prev.SteamID, start) {
+				r.addStateSeconds(prev.PersonaState, seconds)
+				if prev.GameID != "" {
+					r.InGameSeconds += seconds
+					r.Games = r.Games.add(prev.GameID)
+				}
+			}
+			start = end
+		}
+	}
+
+	for _, r := range a.buckets(cur.SteamID, cur.CreatedAt) {
+		r.Games = r.Games.add(cur.GameID)
+		if isPresent(cur.PersonaState) && (prev == nil || !isPresent(prev.PersonaState)) {
+			r.Sessions++
+		}
+	}
+}
+
+// utcDay returns the start of the UTC day t falls in.
+func utcDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func isPresent(state PersonaState) bool {
+	return state != PersonaStateOffline && state != PersonaStateUnknown
+}
+
+func (a *rollupAccumulator) rows() ([]*PlayerHourlyRollup, []*PlayerDailyRollup) {
+	hourly := make([]*PlayerHourlyRollup, 0, len(a.hourly))
+	for _, r := range a.hourly {
+		r.DistinctGames = len(r.Games)
+		hourly = append(hourly, &PlayerHourlyRollup{PlayerRollup: *r})
+	}
+
+	daily := make([]*PlayerDailyRollup, 0, len(a.daily))
+	for _, r := range a.daily {
+		r.DistinctGames = len(r.Games)
+		daily = append(daily, &PlayerDailyRollup{PlayerRollup: *r})
+	}
+
+	return hourly, daily
+}
+
+// rollupMaxGap is the longest interval between two snapshots that is still
+// attributed to the earlier snapshot's state.
+func (st *SteamTracker) rollupMaxGap() time.Duration {
+	return max(3*time.Duration(st.cfg.TaskInterval)*time.Second, 5*time.Minute)
+}
+
+// updatePlayerRollups folds the interval between prev and cur into the
+// rollup tables. It runs in the same transaction that stores cur.
+func (st *SteamTracker) updatePlayerRollups(tx *gorm.DB, prev, cur *Player) error {
+	acc := newRollupAccumulator(st.rollupMaxGap())
+	acc.add(prev, cur)
+
+	hourly, daily := acc.rows()
+	for _, delta := range hourly {
+		if err := mergeRollup(tx, delta); err != nil {
+			return fmt.Errorf("failed to update hourly rollup: %w", err)
+		}
+	}
+	for _, delta := range daily {
+		if err := mergeRollup(tx, delta); err != nil {
+			return fmt.Errorf("failed to update daily rollup: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func mergeRollup[T any, PT interface {
+	*T
+	rollup() *PlayerRollup
+}](tx *gorm.DB, delta PT) error {
+	d := delta.rollup()
+
+	var row T
+	err := tx.Where("steam_id = ? AND bucket_start = ?", d.SteamID, d.BucketStart).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		d.UpdatedAt = time.Now()
+		return tx.Create(delta).Error
+	} else if err != nil {
+		return err
+	}
+
+	PT(&row).rollup().merge(d)
+	return tx.Save(&row).Error
+}
+
+// RebuildPlayerRollupsCommand selects the rollups to recompute. The zero
+// value rebuilds the rollups of every player over the whole history.
+type RebuildPlayerRollupsCommand struct {
+	// SteamIDs limits the rebuild to these players.
+	SteamIDs []SteamID `json:"steam_ids"`
+	// Since and Until limit the rebuild to the UTC days they span.
+	Since *time.Time `json:"since"`
+	Until *time.Time `json:"until"`
+}
+
+type RebuildPlayerRollupsResult struct {
+	Hourly int64 `json:"hourly"` // rows written
+	Daily  int64 `json:"daily"`
+	// KeptBefore is the first day that was recomputed. Rollups of earlier
+	// days were kept, and only missing ones were added, because retention
+	// has deleted or thinned the snapshots they were computed from.
+	KeptBefore *time.Time `json:"kept_before,omitempty"`
+}
+
+// RebuildPlayerRollups recomputes both rollup tables from the stored player
+// snapshots. It is used to backfill history recorded before rollups existed
+// or after importing data.
+//
+// Rollups outlive the snapshots they summarise, so days before the oldest
+// stored snapshot, or before the players downsampling cutoff, are not
+// recomputed: their rows are kept and only missing ones are filled in.
+func (st *SteamTracker) RebuildPlayerRollups(ctx context.Context, cmd *RebuildPlayerRollupsCommand) (*RebuildPlayerRollupsResult, error) {
+	event := log.Info().Str("action", "rebuild_player_rollups")
+	defer func() { event.Send() }()
+
+	result := RebuildPlayerRollupsResult{}
+
+	// Buckets in [from, to) are rebuilt; a zero time leaves that side open.
+	var from, to time.Time
+	setOptional(cmd.Since, func(v time.Time) { from = utcDay(v) })
+	setOptional(cmd.Until, func(v time.Time) { to = utcDay(v).AddDate(0, 0, 1) })
+	event.Int("steam_ids", len(cmd.SteamIDs)).Time("from", from).Time("to", to)
+
+	coveredFrom, err := st.rollupsCoveredFrom(ctx)
+	if err != nil {
+		err = fmt.Errorf("failed to find the oldest player snapshot: %w", err)
+		event.Err(err)
+		return &result, err
+	}
+	if coveredFrom.IsZero() {
+		// Without snapshots there is nothing to rebuild from.
+		return &result, nil
+	}
+	replaceFrom := coveredFrom
+	if from.After(replaceFrom) {
+		replaceFrom = from
+	}
+	result.KeptBefore = &replaceFrom
+	event.Time("kept_before", replaceFrom)
+
+	// Snapshots up to one max gap outside [from, to) still add time to the
+	// buckets at its edges.
+	maxGap := st.rollupMaxGap()
+	players := st.db.WithContext(ctx).Model(&Player{})
+	if len(cmd.SteamIDs) > 0 {
+		players = players.Where("steam_id IN ?", cmd.SteamIDs)
+	}
+	if !from.IsZero() {
+		players = players.Where("created_at >= ?", from.Add(-maxGap))
+	}
+	if !to.IsZero() {
+		players = players.Where("created_at <= ?", to.Add(maxGap))
+	}
+	players = players.Session(&gorm.Session{})
+
+	acc := newRollupAccumulator(maxGap)
+	prevBySteamID := make(map[SteamID]*Player)
+	var last *Player
+	for {
+		batch := make([]*Player, 0, 1000)
+		ss := players
+		if last != nil {
+			ss = ss.Where("(created_at > ? OR (created_at = ? AND id > ?))", last.CreatedAt, last.CreatedAt, last.ID)
+		}
+		if err = ss.Order("created_at").Order("id").Limit(1000).Find(&batch).Error; err != nil {
+			err = fmt.Errorf("failed to read players: %w", err)
+			event.Err(err)
+			return &result, err
+		}
+
+		for _, cur := range batch {
+			acc.add(prevBySteamID[cur.SteamID], cur)
+			prevBySteamID[cur.SteamID] = cur
+		}
+
+		if len(batch) < 1000 {
+			break
+		}
+		last = batch[len(batch)-1]
+	}
+
+	inRange := func(r *PlayerRollup) bool {
+		return !r.BucketStart.Before(from) && (to.IsZero() || r.BucketStart.Before(to))
+	}
+	hourly, daily := acc.rows()
+	hourly = slices.DeleteFunc(hourly, func(r *PlayerHourlyRollup) bool { return !inRange(&r.PlayerRollup) })
+	daily = slices.DeleteFunc(daily, func(r *PlayerDailyRollup) bool { return !inRange(&r.PlayerRollup) })
+
+	err = st.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&PlayerHourlyRollup{}, &PlayerDailyRollup{}} {
+			ss := tx.Where("bucket_start >= ?", replaceFrom)
+			if !to.IsZero() {
+				ss = ss.Where("bucket_start < ?", to)
+			}
+			if len(cmd.SteamIDs) > 0 {
+				ss = ss.Where("steam_id IN ?", cmd.SteamIDs)
+			}
+			if err := ss.Delete(model).Error; err != nil {
+				return fmt.Errorf("failed to clear rollups: %w", err)
+			}
+		}
+
+		now := time.Now()
+		for _, r := range hourly {
+			r.UpdatedAt = now
+		}
+		for _, r := range daily {
+			r.UpdatedAt = now
+		}
+
+		// Rows before replaceFrom were not cleared, so existing ones win.
+		insert := tx.Clauses(clause.OnConflict{DoNothing: true})
+		if len(hourly) > 0 {
+			res := insert.CreateInBatches(hourly, 500)
+			if res.Error != nil {
+				return fmt.Errorf("failed to insert hourly rollups: %w", res.Error)
+			}
+			result.Hourly = res.RowsAffected
+		}
+		if len(daily) > 0 {
+			res := insert.CreateInBatches(daily, 500)
+			if res.Error != nil {
+				return fmt.Errorf("failed to insert daily rollups: %w", res.Error)
+			}
+			result.Daily = res.RowsAffected
+		}
+
+		return nil
+	})
+	if err != nil {
+		event.Err(err)
+		return &result, err
+	}
+
+	event.Int64("hourly", result.Hourly).Int64("daily", result.Daily)
+	return &result, nil
+}
+
+// rollupsCoveredFrom returns the first UTC day whose rollups the stored
+// snapshots can reproduce, or the zero time if there are no snapshots. That
+// is the day after the oldest snapshot, or after the players downsampling
+// cutoff if a retention policy thins them.
+func (st *SteamTracker) rollupsCoveredFrom(ctx context.Context) (time.Time, error) {
+	oldest := Player{}
+	err := st.db.WithContext(ctx).Select("created_at").Order("created_at").Take(&oldest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+
+	coveredFrom := utcDay(oldest.CreatedAt).AddDate(0, 0, 1)
+	for _, policy := range st.cfg.RetentionPolicies {
+		if policy.Table != "players" || policy.DownsampleAfter == 0 {
+			continue
+		}
+		if thinnedUntil := utcDay(time.Now().Add(-policy.DownsampleAfter)).AddDate(0, 0, 1); thinnedUntil.After(coveredFrom) {
+			coveredFrom = thinnedUntil
+		}
+	}
+
+	return coveredFrom, nil
+}
+
+type SearchPlayerRollupsQuery struct {
+	Page  int `query:"page"`
+	Limit int `query:"limit"`
+
+	Granularity      string     `json:"granularity"`
+	SteamID          *SteamID   `json:"steam_id"`
+	StartBucketStart *time.Time `json:"start_bucket_start"`
+	EndBucketStart   *time.Time `json:"end_bucket_start"`
+
+	SortBy struct {
+		BucketStart *string `json:"bucket_start"`
+	} `json:"sort_by"`
+}
+
+func (query *SearchPlayerRollupsQuery) Validate() error {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 25
+	}
+
+	if query.Granularity == "" {
+		query.Granularity = "hour"
+	}
+	bindErr := &BindError{}
+	if query.Granularity != "hour" && query.Granularity != "day" {
+		bindErr.add("granularity", fmt.Sprintf("must be hour or day, got %s", query.Granularity))
+	}
+
+	if query.SteamID != nil && *query.SteamID < 0 {
+		bindErr.add("steam_id", "must not be negative")
+	}
+
+	if query.StartBucketStart != nil && query.EndBucketStart != nil && query.StartBucketStart.After(*query.EndBucketStart) {
+		bindErr.add("start_bucket_start", "must not be after end_bucket_start")
+	}
+
+	validateSortOrder(bindErr, "bucket_start", query.SortBy.BucketStart)
+
+	return bindErr.err()
+}
+
+type SearchPlayerRollupsQueryResult struct {
+	TotalCount int64 `json:"total_count"`
+	Page       int   `json:"page"`
+	PerPage    int   `json:"per_page"`
+
+	Granularity string          `json:"granularity"`
+	Rollups     []*PlayerRollup `json:"rollups"`
+}
+
+func (st *SteamTracker) SearchPlayerRollups(ctx context.Context, query *SearchPlayerRollupsQuery) (*SearchPlayerRollupsQueryResult, error) {
+	event := log.Debug().Str("action", "search_player_rollups").Str("granularity", query.Granularity)
+	defer func() { event.Send() }()
+
+	result := SearchPlayerRollupsQueryResult{
+		Granularity: query.Granularity,
+		Rollups:     make([]*PlayerRollup, 0),
+	}
+
+	var model any = &PlayerHourlyRollup{}
+	if query.Granularity == "day" {
+		model = &PlayerDailyRollup{}
+	}
+
+	err := st.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		whereConditions := make([]string, 0)
+		whereParams := make([]any, 0)
+		ss := tx.Table("(?) as r", tx.Model(model))
+
+		setOptional(query.SteamID, func(v SteamID) {
+			whereConditions = append(whereConditions, "r.steam_id = ?")
+			whereParams = append(whereParams, v)
+			event.Str("steam_id", v.String())
+		})
+
+		setOptional(query.StartBucketStart, func(v time.Time) {
+			whereConditions = append(whereConditions, "r.bucket_start >= ?")
+			whereParams = append(whereParams, v.UTC())
+			event.Time("start_bucket_start", v)
+		})
+
+		setOptional(query.EndBucketStart, func(v time.Time) {
+			whereConditions = append(whereConditions, "r.bucket_start <= ?")
+			whereParams = append(whereParams, v.UTC())
+			event.Time("end_bucket_start", v)
+		})
+
+		if len(whereConditions) > 0 {
+			ss = ss.Where(strings.Join(whereConditions, " AND "), whereParams...)
+		}
+
+		if err := ss.Count(&result.TotalCount).Error; err != nil {
+			return fmt.Errorf("failed to count player rollups: %w", err)
+		}
+
+		setOptional(query.SortBy.BucketStart, func(order string) {
+			ss = ss.Order(orderBy("r", "bucket_start", order))
+			event.Str("sort_by_bucket_start", order)
+		})
+
+		if query.Page > 0 && query.Limit > 0 {
+			result.Page = query.Page
+			result.PerPage = query.Limit
+			ss = ss.Offset((query.Page - 1) * query.Limit).Limit(query.Limit)
+			event.Int("page", query.Page).Int("limit", query.Limit)
+		}
+
+		if err := ss.Find(&result.Rollups).Error; err != nil {
+			return fmt.Errorf("failed to search player rollups: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		event.Err(err)
+	}
+
+	return &result, err
+}
+
+// playerRollups returns up to limit of the newest rollups of each player
+// with a bucket in [start, end], reading all of them at once. A zero start
+// or end leaves that side open. Every player has an entry, empty if they
+// have no rollups.
+func playerRollups(tx *gorm.DB, granularity string, steamIDs []SteamID, start, end time.Time, limit int) (map[SteamID][]*PlayerRollup, error) {
+	var model any = &PlayerHourlyRollup{}
+	if granularity == "day" {
+		model = &PlayerDailyRollup{}
+	}
+
+	ranked := tx.Model(model).
+		Select("*, ROW_NUMBER() OVER (PARTITION BY steam_id ORDER BY bucket_start DESC) AS rn").
+		Where("steam_id IN ?", steamIDs)
+	if !start.IsZero() {
+		ranked = ranked.Where("bucket_start >= ?", start.UTC())
+	}
+	if !end.IsZero() {
+		ranked = ranked.Where("bucket_start <= ?", end.UTC())
+	}
+
+	found := make([]*PlayerRollup, 0)
+	if err := tx.Table("(?) AS r", ranked).Where("r.rn <= ?", limit).
+		Order("r.steam_id").Order("r.bucket_start DESC").
+		Find(&found).Error; err != nil {
+		return nil, fmt.Errorf("failed to get player rollups: %w", err)
+	}
+
+	rollups := make(map[SteamID][]*PlayerRollup, len(steamIDs))
+	for _, steamID := range steamIDs {
+		rollups[steamID] = make([]*PlayerRollup, 0)
+	}
+	for _, r := range found {
+		rollups[r.SteamID] = append(rollups[r.SteamID], r)
+	}
+
+	return rollups, nil
+}
+
+func (st *SteamTracker) GetSearchPlayerRollups(w http.ResponseWriter, r *http.Request) {
+	query := SearchPlayerRollupsQuery{}
+	if err := bindQuery(r, &query); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query parameters: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	result, err := st.SearchPlayerRollups(r.Context(), &query)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to search player rollups: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
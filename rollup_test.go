@@ -0,0 +1,179 @@
+package steamtracker_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+// dailyOnlineSeconds returns the online seconds of each daily rollup of
+// steamID by bucket start.
+func dailyOnlineSeconds(t *testing.T, st *steamtracker.SteamTracker, steamID steamtracker.SteamID) map[time.Time]int64 {
+	t.Helper()
+
+	query := steamtracker.SearchPlayerRollupsQuery{Granularity: "day", SteamID: &steamID, Limit: 100}
+	if err := query.Validate(); err != nil {
+		t.Fatalf("Invalid query: %v", err)
+	}
+	result, err := st.SearchPlayerRollups(t.Context(), &query)
+	if err != nil {
+		t.Fatalf("Failed to search player rollups: %v", err)
+	}
+
+	seconds := make(map[time.Time]int64, len(result.Rollups))
+	for _, r := range result.Rollups {
+		seconds[r.BucketStart.UTC()] = r.OnlineSeconds
+	}
+	return seconds
+}
+
+// minutes returns n+1 times one minute apart, starting at start.
+func minutes(start time.Time, n int) []time.Time {
+	times := make([]time.Time, 0, n+1)
+	for i := range n + 1 {
+		times = append(times, start.Add(time.Duration(i)*time.Minute))
+	}
+	return times
+}
+
+func TestRebuildPlayerRollupsAfterRetention(t *testing.T) {
+	st := openRetentionTracker(t, steamtracker.RetentionPolicy{
+		Table:              "players",
+		MaxAge:             7 * 24 * time.Hour,
+		DownsampleAfter:    24 * time.Hour,
+		DownsampleInterval: time.Hour,
+	})
+	steamID := uniqueSteamID()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	expired := today.AddDate(0, 0, -10)
+	downsampled := today.AddDate(0, 0, -3)
+	recent := time.Now().UTC().Add(-10 * time.Minute)
+
+	// Three minutes online on each day, one snapshot per minute.
+	times := append(minutes(expired.Add(12*time.Hour), 3), minutes(downsampled.Add(12*time.Hour), 3)...)
+	importPlayers(t, st, steamID, steamtracker.PersonaStateOnline, append(times, minutes(recent, 3)...)...)
+
+	if _, err := st.RebuildPlayerRollups(t.Context(), &steamtracker.RebuildPlayerRollupsCommand{}); err != nil {
+		t.Fatalf("Failed to rebuild rollups: %v", err)
+	}
+	before := dailyOnlineSeconds(t, st, steamID)
+	for _, day := range []time.Time{expired, downsampled, utcDay(recent)} {
+		if before[day] != 180 {
+			t.Fatalf("Expected 180 online seconds on %s, got %d", day, before[day])
+		}
+	}
+
+	report, err := st.ApplyRetention(t.Context(), false)
+	if err != nil {
+		t.Fatalf("Failed to apply retention: %v", err)
+	}
+	if report.Tables[0].Expired != 4 || report.Tables[0].Downsampled != 3 {
+		t.Fatalf("Expected 4 expired and 3 downsampled snapshots, got %+v", report.Tables[0])
+	}
+
+	result, err := st.RebuildPlayerRollups(t.Context(), &steamtracker.RebuildPlayerRollupsCommand{})
+	if err != nil {
+		t.Fatalf("Failed to rebuild rollups: %v", err)
+	}
+	if result.KeptBefore == nil || !result.KeptBefore.After(downsampled) {
+		t.Errorf("Expected rollups up to the downsampled day to be kept, got %v", result.KeptBefore)
+	}
+
+	after := dailyOnlineSeconds(t, st, steamID)
+	for day, seconds := range before {
+		if after[day] != seconds {
+			t.Errorf("Expected %d online seconds on %s after the rebuild, got %d", seconds, day, after[day])
+		}
+	}
+}
+
+// utcDay returns the start of the UTC day t falls in.
+func utcDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// rollupRows returns the rollups of steamID at granularity, without the time
+// they were last updated.
+func rollupRows(t *testing.T, st *steamtracker.SteamTracker, steamID steamtracker.SteamID, granularity string, start, end time.Time) []steamtracker.PlayerRollup {
+	t.Helper()
+
+	query := steamtracker.SearchPlayerRollupsQuery{Granularity: granularity, SteamID: &steamID, StartBucketStart: &start, EndBucketStart: &end, Limit: 100}
+	if err := query.Validate(); err != nil {
+		t.Fatalf("Invalid query: %v", err)
+	}
+	result, err := st.SearchPlayerRollups(t.Context(), &query)
+	if err != nil {
+		t.Fatalf("Failed to search player rollups: %v", err)
+	}
+
+	rows := make([]steamtracker.PlayerRollup, 0, len(result.Rollups))
+	for _, r := range result.Rollups {
+		row := *r
+		row.BucketStart = row.BucketStart.UTC()
+		row.UpdatedAt = time.Time{}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func TestAddPlayerUpdatesRollups(t *testing.T) {
+	// Five minute snapshots from 23:50 to 00:10 cross an hour and a day.
+	midnight := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	now := midnight.Add(-10 * time.Minute)
+	st, err := steamtracker.Open(&steamtracker.Config{
+		DatabaseDSN: testDSNs(t)["sqlite"],
+		DisableAuth: true,
+		Now:         func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatalf("Failed to open tracker: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	steamID := uniqueSteamID()
+
+	snapshots := []struct {
+		state  steamtracker.PersonaState
+		gameID string
+	}{
+		{steamtracker.PersonaStateOnline, ""},
+		{steamtracker.PersonaStateOnline, "570"},
+		{steamtracker.PersonaStateAway, "570"},
+		{steamtracker.PersonaStateOffline, ""},
+		{steamtracker.PersonaStateOnline, "730"},
+	}
+	for _, s := range snapshots {
+		addPlayer(t, st, steamID, s.state, s.gameID)
+		now = now.Add(5 * time.Minute)
+	}
+
+	start, end := midnight.Add(-time.Hour), midnight
+	hourly := rollupRows(t, st, steamID, "hour", start, end)
+	daily := rollupRows(t, st, steamID, "day", midnight.AddDate(0, 0, -1), midnight)
+	if len(hourly) != 2 || len(daily) != 2 {
+		t.Fatalf("Expected rollups on both sides of midnight, got %d hourly and %d daily", len(hourly), len(daily))
+	}
+	// 23:50-00:00 was 5 minutes online and 5 online in 570, 00:00-00:10 5
+	// minutes away in 570 and 5 offline, before going online in 730.
+	if before := hourly[0]; before.OnlineSeconds != 600 || before.InGameSeconds != 300 || before.Sessions != 1 || before.DistinctGames != 1 {
+		t.Errorf("Unexpected rollup before midnight: %+v", before)
+	}
+	if after := hourly[1]; after.AwaySeconds != 300 || after.OfflineSeconds != 300 || after.InGameSeconds != 300 || after.Sessions != 1 || after.DistinctGames != 2 {
+		t.Errorf("Unexpected rollup after midnight: %+v", after)
+	}
+
+	if _, err := st.RebuildPlayerRollups(t.Context(), &steamtracker.RebuildPlayerRollupsCommand{}); err != nil {
+		t.Fatalf("Failed to rebuild rollups: %v", err)
+	}
+	if rebuilt := rollupRows(t, st, steamID, "hour", start, end); !reflect.DeepEqual(rebuilt, hourly) {
+		t.Errorf("Expected the rebuilt hourly rollups to match the incremental ones\nwant %+v\ngot  %+v", hourly, rebuilt)
+	}
+	if rebuilt := rollupRows(t, st, steamID, "day", midnight.AddDate(0, 0, -1), midnight); !reflect.DeepEqual(rebuilt, daily) {
+		t.Errorf("Expected the rebuilt daily rollups to match the incremental ones\nwant %+v\ngot  %+v", daily, rebuilt)
+	}
+}
@@ -0,0 +1,165 @@
+package steamtracker
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SecretProvider resolves secret references of one URL scheme, such as
+// file:///run/secrets/steam_api_key or env://STEAM_API_KEY.
+type SecretProvider interface {
+	Resolve(ctx context.Context, ref *url.URL) (string, error)
+}
+
+var (
+	secretProvidersMu sync.RWMutex
+	secretProviders   = map[string]SecretProvider{
+		"file": FileSecretProvider{},
+		"env":  EnvSecretProvider{},
+	}
+)
+
+// RegisterSecretProvider makes references with the given scheme resolvable,
+// replacing any provider already registered for it.
+func RegisterSecretProvider(scheme string, provider SecretProvider) {
+	secretProvidersMu.Lock()
+	defer secretProvidersMu.Unlock()
+
+	secretProviders[scheme] = provider
+}
+
+// ResolveSecret returns the secret that ref points to.
+func ResolveSecret(ctx context.Context, ref string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil || u.Scheme == "" {
+		return "", fmt.Errorf("invalid secret reference %q, expected scheme://...", ref)
+	}
+
+	secretProvidersMu.RLock()
+	provider, ok := secretProviders[u.Scheme]
+	secretProvidersMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unsupported secret reference scheme: %s", u.Scheme)
+	}
+
+	secret, err := provider.Resolve(ctx, u)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s secret: %w", u.Scheme, err)
+	}
+	if secret == "" {
+		return "", fmt.Errorf("%s secret is empty", u.Scheme)
+	}
+
+	return secret, nil
+}
+
+// FileSecretRef returns a reference to the secret stored in the file at path.
+func FileSecretRef(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// FileSecretProvider reads a secret from a file, in the style of Docker and
+// Kubernetes secrets. Surrounding whitespace, such as a trailing newline, is
+// ignored.
+type FileSecretProvider struct{}
+
+func (FileSecretProvider) Resolve(ctx context.Context, ref *url.URL) (string, error) {
+	path := ref.Host + ref.Path
+	if ref.Opaque != "" {
+		path = ref.Opaque
+	}
+
+	data, err := os.ReadFile(filepath.FromSlash(path))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// EnvSecretProvider reads a secret from an environment variable.
+type EnvSecretProvider struct{}
+
+func (EnvSecretProvider) Resolve(ctx context.Context, ref *url.URL) (string, error) {
+	name := ref.Host
+	if ref.Opaque != "" {
+		name = ref.Opaque
+	}
+
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	return value, nil
+}
+
+func (st *SteamTracker) steamAPIKey() string {
+	st.secretMu.RLock()
+	defer st.secretMu.RUnlock()
+
+	return st.apiKey
+}
+
+// reloadSecretsOnSignal calls ReloadSecrets on every SIGHUP until the tracker
+// is stopped. The signal is subscribed to before it returns, so a SIGHUP sent
+// afterwards never falls through to the default action of exiting.
+func (st *SteamTracker) reloadSecretsOnSignal() {
+	reloadCh := make(chan os.Signal, 1)
+	signal.Notify(reloadCh, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(reloadCh)
+
+		for {
+			select {
+			case <-reloadCh:
+				_ = st.ReloadSecrets(st.ctx)
+			case <-st.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// ReloadSecrets resolves the Steam API key again. It is called on SIGHUP so
+// a key stored in a file can be rotated without a restart. On failure the
+// current key stays in use.
+func (st *SteamTracker) ReloadSecrets(ctx context.Context) error {
+	event := log.Info().Str("action", "reload_secrets")
+	defer func() { event.Send() }()
+
+	key := st.cfg.SteamAPIKey
+	if st.cfg.SteamAPIKeyRef != "" {
+		resolved, err := ResolveSecret(ctx, st.cfg.SteamAPIKeyRef)
+		if err != nil {
+			err = fmt.Errorf("failed to load Steam API key: %w", err)
+			event.Err(err)
+			return err
+		}
+		key = resolved
+	}
+
+	st.secretMu.Lock()
+	defer st.secretMu.Unlock()
+
+	// The previous key may still show up in errors from in-flight requests.
+	st.redactor.SetSecrets(key, st.apiKey)
+	event.Bool("changed", st.apiKey != "" && st.apiKey != key)
+	st.apiKey = key
+
+	return nil
+}
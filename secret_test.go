@@ -0,0 +1,57 @@
+package steamtracker_test
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+type staticSecretProvider string
+
+func (p staticSecretProvider) Resolve(ctx context.Context, ref *url.URL) (string, error) {
+	return string(p) + ":" + ref.Host, nil
+}
+
+func TestResolveSecret(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "steam_api_key")
+	if err := os.WriteFile(keyFile, []byte(testSteamAPIKey+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	emptyFile := filepath.Join(dir, "empty")
+	if err := os.WriteFile(emptyFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("STEAMTRACKER_TEST_SECRET", testSteamAPIKey)
+	steamtracker.RegisterSecretProvider("static", staticSecretProvider("secret"))
+
+	tests := []struct {
+		name string
+		ref  string
+		want string // empty if resolving must fail
+	}{
+		{"file", steamtracker.FileSecretRef(keyFile), testSteamAPIKey},
+		{"file URL", "file://" + filepath.ToSlash(keyFile), testSteamAPIKey},
+		{"env", "env://STEAMTRACKER_TEST_SECRET", testSteamAPIKey},
+		{"registered provider", "static://name", "secret:name"},
+		{"missing file", steamtracker.FileSecretRef(filepath.Join(dir, "missing")), ""},
+		{"empty file", steamtracker.FileSecretRef(emptyFile), ""},
+		{"unset env", "env://STEAMTRACKER_TEST_UNSET", ""},
+		{"unknown scheme", "vault://secret/steam", ""},
+		{"no scheme", keyFile, ""},
+	}
+
+	for _, tt := range tests {
+		got, err := steamtracker.ResolveSecret(t.Context(), tt.ref)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("%s: expected an error, got %q", tt.name, got)
+		case tt.want != "" && (err != nil || got != tt.want):
+			t.Errorf("%s: expected %q, got %q, %v", tt.name, tt.want, got, err)
+		}
+	}
+}
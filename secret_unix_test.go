@@ -0,0 +1,113 @@
+//go:build unix
+
+package steamtracker_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	steamtracker "github.com/willywotz/steam-tracker"
+)
+
+// TestReloadSecretsOnSIGHUP rotates the key in its file, signals the tracker
+// and checks that the new key is redacted from the logs from then on, while
+// the old one stays redacted for requests still in flight.
+func TestReloadSecretsOnSIGHUP(t *testing.T) {
+	const rotatedKey = "FEDCBA9876543210FEDCBA9876543210"
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "steam_api_key")
+	if err := os.WriteFile(keyFile, []byte(testSteamAPIKey), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	origLogger, origLevel := log.Logger, zerolog.GlobalLevel()
+	log.Logger = log.Output(io.Discard) // until New routes it to the audit log
+	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	t.Cleanup(func() {
+		log.Logger = origLogger
+		zerolog.SetGlobalLevel(origLevel)
+	})
+
+	st, err := steamtracker.New(&steamtracker.Config{
+		DatabaseDSN:           "sqlite://" + filepath.Join(dir, "steamtracker.db"),
+		HTTPPort:              "0",
+		SteamAPIKeyRef:        steamtracker.FileSecretRef(keyFile),
+		SteamID:               "76561197960287930",
+		MaxTaskRetryCount:     1,
+		TaskInterval:          60,
+		LogLevel:              zerolog.Disabled,
+		DisableAuth:           true,
+		AuditLogBufferSize:    16,
+		AuditLogBatchSize:     4,
+		AuditLogFlushInterval: 10,
+		AuditLogDropPolicy:    steamtracker.AuditLogDropPolicyBlock,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create tracker: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	if err := os.WriteFile(keyFile, []byte(rotatedKey+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("Failed to send SIGHUP: %v", err)
+	}
+
+	action := "reload_secrets"
+	query := steamtracker.SearchAuditLogsQuery{Action: &action}
+	if err := query.Validate(); err != nil {
+		t.Fatalf("Invalid query: %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		result, err := st.SearchAuditLogs(&query)
+		if err != nil {
+			t.Fatalf("Failed to search audit logs: %v", err)
+		}
+		if len(result.AuditLogs) > 0 {
+			if !strings.Contains(string(result.AuditLogs[0].Raw), `"changed":true`) {
+				t.Errorf("Expected the reload to change the key, got %s", result.AuditLogs[0].Raw)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the secrets to be reloaded")
+		}
+	}
+	log.Info().Msgf("Using keys %s and %s", testSteamAPIKey, rotatedKey)
+
+	// A failed reload keeps the current key, and its redaction.
+	if err := os.Remove(keyFile); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.ReloadSecrets(t.Context()); err == nil {
+		t.Error("Expected reloading a missing key file to fail")
+	}
+	log.Info().Msgf("Still using key %s", rotatedKey)
+
+	if err := st.Stop(); err != nil {
+		t.Fatalf("Failed to stop tracker: %v", err)
+	}
+
+	var audit bytes.Buffer
+	if _, err := st.Export(t.Context(), &audit, &steamtracker.ExportQuery{Table: "audit_logs", Format: "jsonl"}); err != nil {
+		t.Fatalf("Failed to export audit logs: %v", err)
+	}
+	for _, key := range []string{testSteamAPIKey, rotatedKey} {
+		if bytes.Contains(audit.Bytes(), []byte(key)) {
+			t.Errorf("Key %s reached the audit log:\n%s", key, audit.Bytes())
+		}
+	}
+	if n := bytes.Count(audit.Bytes(), []byte("REDACTED")); n != 3 {
+		t.Errorf("Expected 3 redacted keys in the audit log, got %d:\n%s", n, audit.Bytes())
+	}
+}
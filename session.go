@@ -0,0 +1,152 @@
+package steamtracker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// PlayerSession is a span of time in which a player was online, away, busy
+// or in any other state but offline. Sessions are derived from player
+// events, so a session spanning tracker downtime is reported as one.
+type PlayerSession struct {
+	SteamID   SteamID   `json:"steam_id"`
+	StartedAt time.Time `json:"started_at"`
+	// EndedAt is nil while the session is ongoing.
+	EndedAt         *time.Time `json:"ended_at"`
+	DurationSeconds int64      `json:"duration_seconds"`
+}
+
+// GetPlayerSessionsQuery selects the sessions overlapping [Since, Until),
+// by default the last 7 days.
+type GetPlayerSessionsQuery struct {
+	SteamID SteamID    `json:"steam_id"`
+	Since   *time.Time `json:"since"`
+	Until   *time.Time `json:"until"`
+}
+
+func (query *GetPlayerSessionsQuery) Validate() error {
+	bindErr := &BindError{}
+	if query.SteamID <= 0 {
+		bindErr.add("steam_id", "must be positive")
+	}
+
+	if query.Until == nil {
+		until := time.Now()
+		query.Until = &until
+	}
+	if query.Since == nil {
+		since := query.Until.Add(-7 * 24 * time.Hour)
+		query.Since = &since
+	}
+
+	if !query.Since.Before(*query.Until) {
+		bindErr.add("since", "must be before until")
+	}
+
+	return bindErr.err()
+}
+
+func (st *SteamTracker) PlayerSessions(ctx context.Context, query *GetPlayerSessionsQuery) ([]*PlayerSession, error) {
+	event := log.Debug().Str("action", "get_player_sessions").
+		Str("steam_id", query.SteamID.String()).
+		Time("since", *query.Since).
+		Time("until", *query.Until)
+	defer func() { event.Send() }()
+
+	var sessions map[SteamID][]*PlayerSession
+	err := st.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		sessions, err = playerSessions(tx, []SteamID{query.SteamID}, *query.Since, *query.Until)
+		return err
+	})
+	if err != nil {
+		event.Err(err)
+		return nil, err
+	}
+
+	event.Int("sessions", len(sessions[query.SteamID]))
+	return sessions[query.SteamID], nil
+}
+
+// playerSessions returns the sessions of each player overlapping
+// [since, until), reading the events of all of them at once. Every player
+// has an entry, empty if they had no session.
+func playerSessions(tx *gorm.DB, steamIDs []SteamID, since, until time.Time) (map[SteamID][]*PlayerSession, error) {
+	events := make([]*PlayerEvent, 0)
+
+	// The state at since is the one set by each player's last event before it.
+	if err := tx.Table("player_events AS pe").Select("pe.*").
+		Where("pe.steam_id IN ? AND pe.created_at < ?", steamIDs, since).
+		Where("NOT EXISTS (?)", tx.Table("player_events AS later").Select("1").
+			Where("later.steam_id = pe.steam_id AND later.created_at < ?", since).
+			Where("(later.created_at > pe.created_at OR (later.created_at = pe.created_at AND later.id > pe.id))")).
+		Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to get player events: %w", err)
+	}
+
+	inRange := make([]*PlayerEvent, 0)
+	if err := tx.Where("steam_id IN ? AND created_at >= ? AND created_at < ?", steamIDs, since, until).
+		Order("created_at").Order("id").
+		Find(&inRange).Error; err != nil {
+		return nil, fmt.Errorf("failed to get player events: %w", err)
+	}
+	events = append(events, inRange...)
+
+	sessions := make(map[SteamID][]*PlayerSession, len(steamIDs))
+	for _, steamID := range steamIDs {
+		sessions[steamID] = make([]*PlayerSession, 0)
+	}
+
+	open := make(map[SteamID]*PlayerSession)
+	for _, e := range events {
+		switch {
+		case isPresent(e.PersonaState) && open[e.SteamID] == nil:
+			open[e.SteamID] = &PlayerSession{SteamID: e.SteamID, StartedAt: e.CreatedAt}
+			sessions[e.SteamID] = append(sessions[e.SteamID], open[e.SteamID])
+		case !isPresent(e.PersonaState) && open[e.SteamID] != nil:
+			endedAt := e.CreatedAt
+			open[e.SteamID].EndedAt = &endedAt
+			delete(open, e.SteamID)
+		}
+	}
+
+	if len(open) > 0 {
+		// The last sessions may have ended after until.
+		openIDs := make([]SteamID, 0, len(open))
+		for steamID := range open {
+			openIDs = append(openIDs, steamID)
+		}
+		absent := []PersonaState{PersonaStateOffline, PersonaStateUnknown}
+
+		ends := make([]*PlayerEvent, 0)
+		if err := tx.Table("player_events AS pe").Select("pe.*").
+			Where("pe.steam_id IN ? AND pe.created_at >= ? AND pe.persona_state IN ?", openIDs, until, absent).
+			Where("NOT EXISTS (?)", tx.Table("player_events AS earlier").Select("1").
+				Where("earlier.steam_id = pe.steam_id AND earlier.created_at >= ? AND earlier.persona_state IN ?", until, absent).
+				Where("(earlier.created_at < pe.created_at OR (earlier.created_at = pe.created_at AND earlier.id < pe.id))")).
+			Find(&ends).Error; err != nil {
+			return nil, fmt.Errorf("failed to get player events: %w", err)
+		}
+		for _, e := range ends {
+			endedAt := e.CreatedAt
+			open[e.SteamID].EndedAt = &endedAt
+		}
+	}
+
+	now := time.Now()
+	for _, list := range sessions {
+		for _, session := range list {
+			endedAt := now
+			if session.EndedAt != nil {
+				endedAt = *session.EndedAt
+			}
+			session.DurationSeconds = int64(endedAt.Sub(session.StartedAt) / time.Second)
+		}
+	}
+
+	return sessions, nil
+}
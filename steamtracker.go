@@ -11,7 +11,6 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -20,7 +19,7 @@ import (
 	"github.com/bwmarrin/snowflake"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"gorm.io/driver/sqlite"
+	"google.golang.org/grpc"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -33,11 +32,31 @@ type SteamTracker struct {
 	wg         *sync.WaitGroup
 	ln         net.Listener
 	hs         *http.Server
-	mux        *http.ServeMux
 	httpClient *http.Client
+	now        func() time.Time
+
+	// gs serves gRPC on grpcLn, if a gRPC port is configured.
+	gs     *grpc.Server
+	grpcLn net.Listener
 
 	db        *gorm.DB
 	snowflake *snowflake.Node
+
+	auditWriter  *AuditLogWriter
+	auditChainMu sync.Mutex
+	redactor     *Redactor
+
+	secretMu sync.RWMutex
+	apiKey   string
+
+	playerEvents *playerEventBroadcaster
+
+	pollNowMu   sync.Mutex
+	lastPollNow time.Time
+
+	// taskMu is held by the running poll, so a manual poll and a scheduled
+	// one never overlap.
+	taskMu sync.Mutex
 }
 
 func New(cfg *Config) (*SteamTracker, error) {
@@ -45,6 +64,75 @@ func New(cfg *Config) (*SteamTracker, error) {
 		return nil, fmt.Errorf("configuration cannot be nil")
 	}
 
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	st, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := st.ReloadSecrets(st.ctx); err != nil {
+		return nil, err
+	}
+
+	if err := st.Migrate(); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+	log.Debug().Msg("Database migration completed successfully")
+
+	if !st.cfg.DisableAuth {
+		var activeTokens int64
+		if err := st.db.Model(&APIToken{}).Where("revoked_at IS NULL").Count(&activeTokens).Error; err != nil {
+			return nil, fmt.Errorf("failed to count API tokens: %w", err)
+		}
+		if activeTokens == 0 {
+			log.Warn().Msg("No active API tokens, the API will refuse every request; create one with `steamtracker token create`")
+		}
+	}
+
+	st.hs = &http.Server{Handler: st.Handler()}
+
+	ln, err := net.Listen("tcp", ":"+st.cfg.HTTPPort)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start HTTP listener on port %s: %w", st.cfg.HTTPPort, err)
+	}
+	st.ln = ln
+	log.Debug().Msgf("HTTP listener started on port %s", st.cfg.HTTPPort)
+
+	if st.cfg.GRPCPort != "" {
+		st.gs = st.GRPCServer()
+
+		grpcLn, err := net.Listen("tcp", ":"+st.cfg.GRPCPort)
+		if err != nil {
+			_ = ln.Close()
+			return nil, fmt.Errorf("failed to start gRPC listener on port %s: %w", st.cfg.GRPCPort, err)
+		}
+		st.grpcLn = grpcLn
+		log.Debug().Msgf("gRPC listener started on port %s", st.cfg.GRPCPort)
+	}
+
+	st.auditWriter = NewAuditLogWriter(st.CreateAuditLogs, st.cfg)
+	log.Logger = log.Output(st.logWriter(true))
+
+	st.reloadSecretsOnSignal()
+
+	return st, nil
+}
+
+// Open connects to the configured database and prepares it for use without
+// starting the HTTP listener or the polling task. It is used by New and by
+// the maintenance commands that only need database access.
+func Open(cfg *Config) (*SteamTracker, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("configuration cannot be nil")
+	}
+
+	if err := cfg.ValidateDatabase(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	st := SteamTracker{
@@ -53,36 +141,33 @@ func New(cfg *Config) (*SteamTracker, error) {
 		ctx:        ctx,
 		cancel:     cancel,
 		wg:         &sync.WaitGroup{},
-		httpClient: &http.Client{Timeout: 10 * time.Second},
-	}
+		httpClient: cfg.HTTPClient,
+		now:        cfg.Now,
+		redactor:   NewRedactor(),
 
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
+		playerEvents: newPlayerEventBroadcaster(),
 	}
 
-	st.mux = http.NewServeMux()
-	st.hs = &http.Server{Handler: st.mux}
+	if st.httpClient == nil {
+		st.httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if st.now == nil {
+		st.now = time.Now
+	}
 
-	ln, err := net.Listen("tcp", ":"+st.cfg.HTTPPort)
+	dialector, err := openDialector(st.cfg.DatabaseDSN)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start HTTP listener on port %s: %w", st.cfg.HTTPPort, err)
+		return nil, fmt.Errorf("failed to select database driver: %w", err)
 	}
-	st.ln = ln
-	log.Debug().Msgf("HTTP listener started on port %s", st.cfg.HTTPPort)
 
-	db, err := gorm.Open(sqlite.Open(st.cfg.DatabaseDSN), &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 	st.db = db
-	log.Debug().Msg("Connected to database successfully")
-
-	if err := st.AutoMigrate(); err != nil {
-		return nil, fmt.Errorf("failed to auto-migrate database: %w", err)
-	}
-	log.Debug().Msg("Database auto-migration completed successfully")
+	log.Debug().Msgf("Connected to %s database successfully", st.Dialect())
 
 	snowflakeNode, err := snowflake.NewNode(st.cfg.SnowflakeNodeID)
 	if err != nil {
@@ -91,38 +176,31 @@ func New(cfg *Config) (*SteamTracker, error) {
 	st.snowflake = snowflakeNode
 	log.Debug().Msgf("Created snowflake node with ID: %d", st.cfg.SnowflakeNodeID)
 
-	if err := st.ResetDatabase(); err != nil {
-		return nil, fmt.Errorf("failed to reset database: %w", err)
-	}
+	return &st, nil
+}
 
-	writers := []io.Writer{
-		&zerolog.FilteredLevelWriter{
-			Writer: zerolog.LevelWriterAdapter{Writer: &st},
+// logWriter builds the log output: stdout at the configured level, stderr
+// for errors and, if audit is set, the audit log. Every sink is redacted.
+func (st *SteamTracker) logWriter(audit bool) zerolog.LevelWriter {
+	writers := make([]io.Writer, 0, 3)
+	if audit {
+		writers = append(writers, &zerolog.FilteredLevelWriter{
+			Writer: zerolog.LevelWriterAdapter{Writer: st.redactor.Writer(st.auditWriter)},
 			Level:  zerolog.DebugLevel,
-		},
+		})
+	}
+	writers = append(writers,
 		&zerolog.FilteredLevelWriter{
-			Writer: zerolog.LevelWriterAdapter{Writer: os.Stdout},
+			Writer: zerolog.LevelWriterAdapter{Writer: st.redactor.Writer(os.Stdout)},
 			Level:  st.cfg.LogLevel,
 		},
 		&zerolog.FilteredLevelWriter{
-			Writer: zerolog.LevelWriterAdapter{Writer: os.Stderr},
+			Writer: zerolog.LevelWriterAdapter{Writer: st.redactor.Writer(os.Stderr)},
 			Level:  zerolog.ErrorLevel,
 		},
-	}
-
-	log.Logger = log.Output(zerolog.MultiLevelWriter(writers...))
+	)
 
-	return &st, nil
-}
-
-func (st *SteamTracker) Write(p []byte) (n int, err error) {
-	if _, err := st.CreateAuditLog(&CreateAuditLogCommand{
-		Raw: JSON(p),
-	}); err != nil {
-		return 0, fmt.Errorf("failed to write audit log: %w", err)
-	}
-
-	return len(p), nil
+	return zerolog.MultiLevelWriter(writers...)
 }
 
 func (st *SteamTracker) Run() error {
@@ -134,19 +212,36 @@ func (st *SteamTracker) Run() error {
 
 	go st.task()
 
-	st.mux.HandleFunc("/api/players", st.GetSearchPlayers)
-	st.mux.HandleFunc("/api/player_events", st.GetSearchPlayerEvents)
-	st.mux.HandleFunc("/api/audit_logs", st.GetSearchAuditLogs)
-	st.mux.HandleFunc("/", st.GetIndex)
+	var retentionC <-chan time.Time
+	if len(st.cfg.RetentionPolicies) > 0 {
+		retentionTicker := time.NewTicker(time.Duration(st.cfg.RetentionInterval) * time.Second)
+		defer retentionTicker.Stop()
+		retentionC = retentionTicker.C
+	}
+
+	var backupC <-chan time.Time
+	if st.cfg.BackupInterval > 0 {
+		backupTicker := time.NewTicker(time.Duration(st.cfg.BackupInterval) * time.Second)
+		defer backupTicker.Stop()
+		backupC = backupTicker.C
+	}
+
 	go func() { _ = st.hs.Serve(st.ln) }()
+	go st.serveGRPC()
 
 	for {
 		select {
 		case <-ticker.C:
 			go st.task()
+		case <-retentionC:
+			go st.retentionTask()
+		case <-backupC:
+			go st.backupTask()
 		case <-stopCh:
 			log.Info().Msg("shutting down...")
 			return st.Stop()
+		case <-st.ctx.Done():
+			return nil
 		}
 	}
 }
@@ -160,38 +255,27 @@ func (st *SteamTracker) Stop() error {
 	if err := st.hs.Shutdown(ctx); err != nil {
 		return fmt.Errorf("failed to shutdown HTTP server: %w", err)
 	}
-
-	st.wg.Wait()
-
-	return nil
-}
-
-var dbModels = []any{&Player{}, &PlayerEvent{}, &AuditLog{}}
-
-func (st *SteamTracker) AutoMigrate() error {
-	if err := st.db.AutoMigrate(dbModels...); err != nil {
-		return fmt.Errorf("failed to migrate database: %w", err)
+	if err := st.stopGRPC(ctx); err != nil {
+		return err
 	}
 
-	return nil
-}
+	st.wg.Wait()
 
-func (st *SteamTracker) ResetDatabase() error {
-	if !st.cfg.ResetDatabase {
-		log.Debug().Msg("Database reset is disabled, skipping...")
-		return nil
-	}
+	stats := st.auditWriter.Stats()
+	log.Info().
+		Uint64("written", stats.Written).
+		Uint64("dropped", stats.Dropped).
+		Uint64("failed", stats.Failed).
+		Msg("Flushing audit logs")
 
-	log.Debug().Msg("Resetting database...")
-	if err := st.db.Migrator().DropTable(dbModels...); err != nil {
-		return fmt.Errorf("failed to drop table: %w", err)
-	}
+	// Restore plain stdout/stderr logging so nothing written after this
+	// point is lost in a closed audit writer.
+	log.Logger = log.Output(st.logWriter(false))
 
-	if err := st.AutoMigrate(); err != nil {
-		return fmt.Errorf("failed to auto-migrate database: %w", err)
+	if err := st.auditWriter.Close(); err != nil {
+		return fmt.Errorf("failed to flush audit logs: %w", err)
 	}
 
-	log.Debug().Msg("Database reset completed successfully")
 	return nil
 }
 
@@ -200,11 +284,17 @@ func (st *SteamTracker) GenerateID() int64 {
 }
 
 func (st *SteamTracker) CreateAuditLog(cmd *CreateAuditLogCommand) (*AuditLog, error) {
+	st.auditChainMu.Lock()
+	defer st.auditChainMu.Unlock()
+
 	auditLog := cmd.AuditLog()
 	auditLog.ID = st.GenerateID()
-	auditLog.CreatedAt = time.Now()
 
 	err := st.db.WithContext(st.ctx).Transaction(func(tx *gorm.DB) error {
+		if err := chainAuditLogs(tx, []*AuditLog{&auditLog}); err != nil {
+			return err
+		}
+
 		if err := tx.Create(&auditLog).Error; err != nil {
 			return fmt.Errorf("failed to create audit log: %w", err)
 		}
@@ -215,6 +305,44 @@ func (st *SteamTracker) CreateAuditLog(cmd *CreateAuditLogCommand) (*AuditLog, e
 	return &auditLog, err
 }
 
+// CreateAuditLogs inserts a batch of audit logs in one transaction. It uses
+// its own context so the final flush in Stop still succeeds after st.ctx is
+// cancelled.
+func (st *SteamTracker) CreateAuditLogs(cmds []*CreateAuditLogCommand) error {
+	if len(cmds) == 0 {
+		return nil
+	}
+
+	st.auditChainMu.Lock()
+	defer st.auditChainMu.Unlock()
+
+	// IDs are generated under the lock so they increase in chain order.
+	auditLogs := make([]*AuditLog, 0, len(cmds))
+	for _, cmd := range cmds {
+		auditLog := cmd.AuditLog()
+		auditLog.ID = st.GenerateID()
+		auditLogs = append(auditLogs, &auditLog)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	return st.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := chainAuditLogs(tx, auditLogs); err != nil {
+			return err
+		}
+
+		if err := tx.CreateInBatches(&auditLogs, 100).Error; err != nil {
+			return fmt.Errorf("failed to create audit logs: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// AddPlayer stores a polled snapshot of a player. In the same transaction it
+// updates the player's current state and rollups and, if the persona state
+// changed, records a player event.
 func (st *SteamTracker) AddPlayer(player *Player) error {
 	event := log.Debug().
 		Str("action", "add_player").
@@ -225,21 +353,50 @@ func (st *SteamTracker) AddPlayer(player *Player) error {
 
 	player.ID = st.GenerateID()
 	event.Int64("id", player.ID)
-	player.CreatedAt = time.Now()
+	player.CreatedAt = st.now()
 	event.Time("created_at", player.CreatedAt)
 
+	var playerEvent *PlayerEvent
 	err := st.db.WithContext(st.ctx).Transaction(func(tx *gorm.DB) error {
+		state, err := getPlayerCurrentState(tx, player.SteamID)
+		if err != nil {
+			return fmt.Errorf("failed to get player current state: %w", err)
+		}
+
+		var prev *Player
+		if state != nil {
+			prev = state.snapshot()
+		}
+
 		if err := tx.Create(player).Error; err != nil {
 			return fmt.Errorf("failed to create player in transaction: %w", err)
 		}
 
+		if err := saveGame(tx, player); err != nil {
+			return fmt.Errorf("failed to save game: %w", err)
+		}
+
+		if err := st.updatePlayerRollups(tx, prev, player); err != nil {
+			return fmt.Errorf("failed to update player rollups: %w", err)
+		}
+
+		playerEvent, err = st.recordPlayerState(tx, state, player)
+		if err != nil {
+			return err
+		}
+		if playerEvent != nil {
+			event.Int64("player_event_id", playerEvent.ID)
+		}
+
 		return nil
 	})
 	if err != nil {
 		event.Err(err)
+		return err
 	}
 
-	return err
+	st.publishPlayerEvent(playerEvent)
+	return nil
 }
 
 func (st *SteamTracker) CreatePlayerEvent(cmd *CreatePlayerEventCommand) (*PlayerEvent, error) {
@@ -265,9 +422,11 @@ func (st *SteamTracker) CreatePlayerEvent(cmd *CreatePlayerEventCommand) (*Playe
 	})
 	if err != nil {
 		event.Err(err)
+		return &playerEvent, err
 	}
 
-	return &playerEvent, err
+	st.publishPlayerEvent(&playerEvent)
+	return &playerEvent, nil
 }
 
 func (st *SteamTracker) GetLatestPlayerEvent(query *GetLatestPlayerEventQuery) (*PlayerEvent, error) {
@@ -281,12 +440,10 @@ func (st *SteamTracker) GetLatestPlayerEvent(query *GetLatestPlayerEventQuery) (
 	}
 
 	err := st.db.WithContext(st.ctx).Transaction(func(tx *gorm.DB) error {
-		ss := tx.Table("(?) as p", tx.Model(&PlayerEvent{}))
-
-		ss = ss.Where("steam_id = ?", query.SteamID)
-		ss = ss.Order("created_at DESC")
+		ss := tx.Model(&PlayerEvent{}).Where("steam_id = ?", query.SteamID)
+		ss = ss.Order(orderBy("", "created_at", "desc")).Order(orderBy("", "id", "desc"))
 
-		if err := ss.First(&playerEvent).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := ss.Take(&playerEvent).Error; errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil // No events found, return empty PlayerEvent
 		} else if err != nil {
 			return fmt.Errorf("failed to get latest player event: %w", err)
@@ -301,6 +458,46 @@ func (st *SteamTracker) GetLatestPlayerEvent(query *GetLatestPlayerEventQuery) (
 	return &playerEvent, err
 }
 
+// pollNowCooldown is the shortest time between two polls started by PollNow,
+// however many clients ask for one.
+const pollNowCooldown = 10 * time.Second
+
+var (
+	ErrPollingDisabled = errors.New("polling is disabled")
+	ErrPollTooSoon     = errors.New("a poll was started recently")
+	ErrPollInProgress  = errors.New("a poll is already running")
+)
+
+// PollNow starts a poll in the background without waiting for the next
+// scheduled one. It fails with ErrPollInProgress while another poll runs,
+// without starting the cooldown.
+func (st *SteamTracker) PollNow() error {
+	if st.cfg.DisableTask {
+		return ErrPollingDisabled
+	}
+
+	st.pollNowMu.Lock()
+	defer st.pollNowMu.Unlock()
+
+	if since := time.Since(st.lastPollNow); since < pollNowCooldown {
+		return fmt.Errorf("%w, try again in %s", ErrPollTooSoon, (pollNowCooldown - since).Round(time.Second))
+	}
+
+	if !st.taskMu.TryLock() {
+		return ErrPollInProgress
+	}
+	st.lastPollNow = time.Now()
+
+	st.wg.Add(1)
+	go func() {
+		defer st.wg.Done()
+		defer st.taskMu.Unlock()
+
+		st.poll()
+	}()
+	return nil
+}
+
 func (st *SteamTracker) task() {
 	if st.cfg.DisableTask {
 		log.Debug().Msg("Task is disabled, skipping...")
@@ -310,42 +507,36 @@ func (st *SteamTracker) task() {
 	st.wg.Add(1)
 	defer st.wg.Done()
 
+	if !st.taskMu.TryLock() {
+		log.Debug().Msg("Task is already running, skipping...")
+		return
+	}
+	defer st.taskMu.Unlock()
+
+	st.poll()
+}
+
+// poll fetches the tracked players from Steam and stores them. The caller
+// must hold taskMu.
+func (st *SteamTracker) poll() {
 	log.Debug().Msg("Starting task...")
 
-	result, err := GetPlayerSummaries(st.httpClient, st.cfg.SteamAPIKey, st.cfg.SteamID, st.cfg.MaxTaskRetryCount)
+	result, err := GetPlayerSummaries(st.httpClient, st.steamAPIKey(), st.cfg.SteamID, st.cfg.MaxTaskRetryCount)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get player summaries")
 		return
 	}
 
-	if result.Player() == nil {
+	players := result.Players()
+	if len(players) == 0 {
 		log.Warn().Msg("No player data found")
 		return
 	}
 
-	player := result.Player()
-
-	if err := st.AddPlayer(player); err != nil {
-		log.Error().Err(err).Msg("Failed to add player")
-	}
-
-	latestEvent, err := st.GetLatestPlayerEvent(&GetLatestPlayerEventQuery{
-		SteamID: player.SteamID,
-	})
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to get latest player event")
-		return
-	}
-	if latestEvent.PersonaState == player.PersonaState {
-		return
-	}
-	if _, err := st.CreatePlayerEvent(&CreatePlayerEventCommand{
-		SteamID:      player.SteamID,
-		PersonaName:  player.PersonaName,
-		PersonaState: player.PersonaState,
-	}); err != nil {
-		log.Error().Err(err).Msg("Failed to create player event")
-		return
+	for _, player := range players {
+		if err := st.AddPlayer(player); err != nil {
+			log.Error().Err(err).Msg("Failed to add player")
+		}
 	}
 }
 
@@ -384,26 +575,49 @@ func (st *SteamTracker) SearchPlayers(ctx context.Context, query *SearchPlayersQ
 			ss = ss.Where(strings.Join(whereConditions, " AND "), whereParams...)
 		}
 
-		if err := ss.Count(&result.TotalCount).Error; err != nil {
-			return fmt.Errorf("failed to count players: %w", err)
+		if includeTotal(query.IncludeTotal, query.Cursor) {
+			var totalCount int64
+			if err := ss.Count(&totalCount).Error; err != nil {
+				return fmt.Errorf("failed to count players: %w", err)
+			}
+			result.TotalCount = &totalCount
 		}
 
-		setOptional(query.SortBy.CreatedAt, func(order string) {
-			ss = ss.Order("p.created_at " + order)
-			event.Str("sort_by_created_at", order)
-		})
+		if query.Cursor != nil {
+			cursor, err := DecodeCursor(*query.Cursor)
+			if err != nil {
+				return err
+			}
 
-		if query.Page > 0 && query.Limit > 0 {
-			result.Page = query.Page
+			order := "desc"
+			setOptional(query.SortBy.CreatedAt, func(v string) { order = v })
 			result.PerPage = query.Limit
-			ss = ss.Offset((query.Page - 1) * query.Limit).Limit(query.Limit)
-			event.Int("page", query.Page).Int("limit", query.Limit)
+			ss = keysetPage(ss, "p", cursor, order, query.Limit)
+			event.Str("cursor", *query.Cursor).Str("sort_by_created_at", order).Int("limit", query.Limit)
+		} else {
+			setOptional(query.SortBy.CreatedAt, func(order string) {
+				ss = ss.Order(orderBy("p", "created_at", order))
+				event.Str("sort_by_created_at", order)
+			})
+
+			if query.Page > 0 && query.Limit > 0 {
+				result.Page = query.Page
+				result.PerPage = query.Limit
+				ss = ss.Offset((query.Page - 1) * query.Limit).Limit(query.Limit)
+				event.Int("page", query.Page).Int("limit", query.Limit)
+			}
 		}
 
 		if err := ss.Find(&result.Players).Error; err != nil {
 			return fmt.Errorf("failed to search players: %w", err)
 		}
 
+		if query.Cursor != nil {
+			result.Players, result.NextCursor = nextCursor(result.Players, query.Limit, func(v *Player) (time.Time, int64) {
+				return v.CreatedAt, v.ID
+			})
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -415,41 +629,7 @@ func (st *SteamTracker) SearchPlayers(ctx context.Context, query *SearchPlayersQ
 
 func (st *SteamTracker) GetSearchPlayers(w http.ResponseWriter, r *http.Request) {
 	query := SearchPlayersQuery{}
-
-	if v := r.URL.Query().Get("page"); v != "" {
-		page, _ := strconv.Atoi(v)
-		query.Page = page
-	}
-
-	if v := r.URL.Query().Get("limit"); v != "" {
-		limit, _ := strconv.Atoi(v)
-		query.Limit = limit
-	}
-
-	if v := r.URL.Query().Get("steam_id"); v != "" {
-		steamIDInt, _ := strconv.ParseInt(v, 10, 64)
-		steamID := SteamID(steamIDInt)
-		query.SteamID = &steamID
-	}
-
-	if v := r.URL.Query().Get("start_created_at"); v != "" {
-		startCreatedAt, _ := time.Parse(time.RFC3339, v)
-		query.StartCreatedAt = &startCreatedAt
-	}
-
-	if v := r.URL.Query().Get("end_created_at"); v != "" {
-		endCreatedAt, _ := time.Parse(time.RFC3339, v)
-		query.EndCreatedAt = &endCreatedAt
-	}
-
-	if v := r.URL.Query().Get("sort_by[created_at]"); v != "" {
-		sortOrder := strings.ToLower(v)
-		query.SortBy.CreatedAt = &sortOrder
-	}
-
-	_ = json.NewDecoder(r.Body).Decode(&query)
-
-	if err := query.Validate(); err != nil {
+	if err := bindQuery(r, &query); err != nil {
 		http.Error(w, fmt.Sprintf("Invalid query parameters: %v", err), http.StatusBadRequest)
 		return
 	}
@@ -486,30 +666,89 @@ func (st *SteamTracker) SearchPlayerEvents(query *SearchPlayerEventsQuery) (*Sea
 			event.Str("steam_id", v.String())
 		})
 
+		if len(query.SteamIDs) > 0 {
+			whereConditions = append(whereConditions, "pe.steam_id IN ?")
+			whereParams = append(whereParams, query.SteamIDs)
+			event.Int("steam_ids", len(query.SteamIDs))
+		}
+
+		if len(query.Types) > 0 {
+			whereConditions = append(whereConditions, "pe.type IN ?")
+			whereParams = append(whereParams, query.Types)
+			event.Interface("types", query.Types)
+		}
+
+		if len(query.PersonaStates) > 0 {
+			whereConditions = append(whereConditions, "pe.persona_state IN ?")
+			whereParams = append(whereParams, query.PersonaStates)
+			event.Interface("persona_states", query.PersonaStates)
+		}
+
+		setOptional(query.PersonaName, func(v string) {
+			whereConditions = append(whereConditions, "LOWER(pe.persona_name) LIKE ? ESCAPE '!'")
+			whereParams = append(whereParams, likePattern(strings.ToLower(v)))
+			event.Str("persona_name", v)
+		})
+
+		setOptional(query.StartCreatedAt, func(v time.Time) {
+			whereConditions = append(whereConditions, "pe.created_at >= ?")
+			whereParams = append(whereParams, v)
+			event.Time("start_created_at", v)
+		})
+
+		setOptional(query.EndCreatedAt, func(v time.Time) {
+			whereConditions = append(whereConditions, "pe.created_at <= ?")
+			whereParams = append(whereParams, v)
+			event.Time("end_created_at", v)
+		})
+
 		if len(whereConditions) > 0 {
 			ss = ss.Where(strings.Join(whereConditions, " AND "), whereParams...)
 		}
 
-		if err := ss.Count(&result.TotalCount).Error; err != nil {
-			return fmt.Errorf("failed to count player events: %w", err)
+		if includeTotal(query.IncludeTotal, query.Cursor) {
+			var totalCount int64
+			if err := ss.Count(&totalCount).Error; err != nil {
+				return fmt.Errorf("failed to count player events: %w", err)
+			}
+			result.TotalCount = &totalCount
 		}
 
-		setOptional(query.SortBy.CreatedAt, func(order string) {
-			ss = ss.Order("pe.created_at " + order)
-			event.Str("sort_by_created_at", order)
-		})
+		if query.Cursor != nil {
+			cursor, err := DecodeCursor(*query.Cursor)
+			if err != nil {
+				return err
+			}
 
-		if query.Page > 0 && query.Limit > 0 {
-			result.Page = query.Page
+			order := "desc"
+			setOptional(query.SortBy.CreatedAt, func(v string) { order = v })
 			result.PerPage = query.Limit
-			ss = ss.Offset((query.Page - 1) * query.Limit).Limit(query.Limit)
-			event.Int("page", query.Page).Int("limit", query.Limit)
+			ss = keysetPage(ss, "pe", cursor, order, query.Limit)
+			event.Str("cursor", *query.Cursor).Str("sort_by_created_at", order).Int("limit", query.Limit)
+		} else {
+			setOptional(query.SortBy.CreatedAt, func(order string) {
+				ss = ss.Order(orderBy("pe", "created_at", order))
+				event.Str("sort_by_created_at", order)
+			})
+
+			if query.Page > 0 && query.Limit > 0 {
+				result.Page = query.Page
+				result.PerPage = query.Limit
+				ss = ss.Offset((query.Page - 1) * query.Limit).Limit(query.Limit)
+				event.Int("page", query.Page).Int("limit", query.Limit)
+			}
 		}
 
 		if err := ss.Find(&result.PlayerEvents).Error; err != nil {
 			return fmt.Errorf("failed to search player events: %w", err)
 		}
 
+		if query.Cursor != nil {
+			result.PlayerEvents, result.NextCursor = nextCursor(result.PlayerEvents, query.Limit, func(v *PlayerEvent) (time.Time, int64) {
+				return v.CreatedAt, v.ID
+			})
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -519,33 +758,35 @@ func (st *SteamTracker) SearchPlayerEvents(query *SearchPlayerEventsQuery) (*Sea
 	return &result, err
 }
 
-func (st *SteamTracker) GetSearchPlayerEvents(w http.ResponseWriter, r *http.Request) {
-	query := SearchPlayerEventsQuery{}
+// recentPlayerEvents returns up to limit of the newest events of each
+// player, newest first, reading all of them at once. Every player has an
+// entry, empty if they have no events.
+func recentPlayerEvents(tx *gorm.DB, steamIDs []SteamID, limit int) (map[SteamID][]*PlayerEvent, error) {
+	ranked := tx.Model(&PlayerEvent{}).
+		Select("*, ROW_NUMBER() OVER (PARTITION BY steam_id ORDER BY created_at DESC, id DESC) AS rn").
+		Where("steam_id IN ?", steamIDs)
 
-	if v := r.URL.Query().Get("page"); v != "" {
-		page, _ := strconv.Atoi(v)
-		query.Page = page
+	found := make([]*PlayerEvent, 0)
+	if err := tx.Table("(?) AS pe", ranked).Where("pe.rn <= ?", limit).
+		Order("pe.steam_id").Order("pe.created_at DESC").Order("pe.id DESC").
+		Find(&found).Error; err != nil {
+		return nil, fmt.Errorf("failed to get player events: %w", err)
 	}
 
-	if v := r.URL.Query().Get("limit"); v != "" {
-		limit, _ := strconv.Atoi(v)
-		query.Limit = limit
+	events := make(map[SteamID][]*PlayerEvent, len(steamIDs))
+	for _, steamID := range steamIDs {
+		events[steamID] = make([]*PlayerEvent, 0)
 	}
-
-	if v := r.URL.Query().Get("steam_id"); v != "" {
-		steamIDInt, _ := strconv.ParseInt(v, 10, 64)
-		steamID := SteamID(steamIDInt)
-		query.SteamID = &steamID
+	for _, e := range found {
+		events[e.SteamID] = append(events[e.SteamID], e)
 	}
 
-	if v := r.URL.Query().Get("sort_by[created_at]"); v != "" {
-		sortOrder := strings.ToLower(v)
-		query.SortBy.CreatedAt = &sortOrder
-	}
-
-	_ = json.NewDecoder(r.Body).Decode(&query)
+	return events, nil
+}
 
-	if err := query.Validate(); err != nil {
+func (st *SteamTracker) GetSearchPlayerEvents(w http.ResponseWriter, r *http.Request) {
+	query := SearchPlayerEventsQuery{}
+	if err := bindQuery(r, &query); err != nil {
 		http.Error(w, fmt.Sprintf("Invalid query parameters: %v", err), http.StatusBadRequest)
 		return
 	}
@@ -572,27 +813,122 @@ func (st *SteamTracker) SearchAuditLogs(query *SearchAuditLogsQuery) (*SearchAud
 	}
 
 	err := st.db.WithContext(st.ctx).Transaction(func(tx *gorm.DB) error {
+		whereConditions := make([]string, 0)
+		whereParams := make([]any, 0)
 		ss := tx.Table("(?) as al", tx.Model(&AuditLog{}))
 
-		if err := ss.Count(&result.TotalCount).Error; err != nil {
-			return fmt.Errorf("failed to count audit logs: %w", err)
+		search := query.Q != nil && hasAuditLogSearch(tx)
+		setOptional(query.Q, func(v string) {
+			if search {
+				ss = ss.Joins("JOIN audit_logs_fts ON audit_logs_fts.rowid = al.id")
+				whereConditions = append(whereConditions, "audit_logs_fts MATCH ?")
+				whereParams = append(whereParams, ftsPhrase(v))
+			} else {
+				whereConditions = append(whereConditions, "al.raw LIKE ? ESCAPE '!'")
+				whereParams = append(whereParams, likePattern(v))
+			}
+			event.Str("q", v).Bool("full_text", search)
+		})
+
+		setOptional(query.Level, func(v string) {
+			whereConditions = append(whereConditions, "al.level = ?")
+			whereParams = append(whereParams, v)
+			event.Str("level", v)
+		})
+
+		setOptional(query.Action, func(v string) {
+			whereConditions = append(whereConditions, "al.action = ?")
+			whereParams = append(whereParams, v)
+			event.Str("filter_action", v)
+		})
+
+		setOptional(query.SteamID, func(v SteamID) {
+			whereConditions = append(whereConditions, "al.steam_id = ?")
+			whereParams = append(whereParams, v)
+			event.Str("steam_id", v.String())
+		})
+
+		setOptional(query.StartLoggedAt, func(v time.Time) {
+			whereConditions = append(whereConditions, "al.logged_at >= ?")
+			whereParams = append(whereParams, v)
+			event.Time("start_logged_at", v)
+		})
+
+		setOptional(query.EndLoggedAt, func(v time.Time) {
+			whereConditions = append(whereConditions, "al.logged_at <= ?")
+			whereParams = append(whereParams, v)
+			event.Time("end_logged_at", v)
+		})
+
+		setOptional(query.HasError, func(v bool) {
+			if v {
+				whereConditions = append(whereConditions, "al.error <> ''")
+			} else {
+				whereConditions = append(whereConditions, "(al.error IS NULL OR al.error = '')")
+			}
+			event.Bool("has_error", v)
+		})
+
+		if len(whereConditions) > 0 {
+			ss = ss.Where(strings.Join(whereConditions, " AND "), whereParams...)
 		}
 
-		if query.Page > 0 && query.Limit > 0 {
-			result.Page = query.Page
-			result.PerPage = query.Limit
-			ss = ss.Offset((query.Page - 1) * query.Limit).Limit(query.Limit)
-			event.Int("page", query.Page).Int("limit", query.Limit)
+		if includeTotal(query.IncludeTotal, query.Cursor) {
+			var totalCount int64
+			if err := ss.Count(&totalCount).Error; err != nil {
+				return fmt.Errorf("failed to count audit logs: %w", err)
+			}
+			result.TotalCount = &totalCount
 		}
 
-		setOptional(query.SortBy.ID, func(order string) {
-			ss = ss.Order("al.id " + order)
-			event.Str("sort_by_id", order)
-		})
+		if search {
+			ss = ss.Select("al.*, " + auditLogSnippetColumn)
+		}
+
+		if query.Cursor != nil {
+			cursor, err := DecodeCursor(*query.Cursor)
+			if err != nil {
+				return err
+			}
+
+			order := "desc"
+			setOptional(query.SortBy.ID, func(v string) { order = v })
+			result.PerPage = query.Limit
+			ss = idKeysetPage(ss, "al", cursor, order, query.Limit)
+			event.Str("cursor", *query.Cursor).Str("sort_by_id", order).Int("limit", query.Limit)
+		} else {
+			if query.Page > 0 && query.Limit > 0 {
+				result.Page = query.Page
+				result.PerPage = query.Limit
+				ss = ss.Offset((query.Page - 1) * query.Limit).Limit(query.Limit)
+				event.Int("page", query.Page).Int("limit", query.Limit)
+			}
+
+			setOptional(query.SortBy.LoggedAt, func(order string) {
+				ss = ss.Order(orderBy("al", "logged_at", order))
+				event.Str("sort_by_logged_at", order)
+			})
+
+			setOptional(query.SortBy.ID, func(order string) {
+				ss = ss.Order(orderBy("al", "id", order))
+				event.Str("sort_by_id", order)
+			})
+		}
 
 		if err := ss.Find(&result.AuditLogs).Error; err != nil {
 			return fmt.Errorf("failed to search audit logs: %w", err)
 		}
+		for _, auditLog := range result.AuditLogs {
+			if auditLog.Snippet != "" {
+				auditLog.Snippet = highlightSnippet(auditLog.Snippet)
+			}
+		}
+
+		if query.Cursor != nil {
+			result.AuditLogs, result.NextCursor = nextCursor(result.AuditLogs, query.Limit, func(v *AuditLog) (time.Time, int64) {
+				return time.Time{}, v.ID
+			})
+		}
 
 		return nil
 	})
@@ -605,25 +941,7 @@ func (st *SteamTracker) SearchAuditLogs(query *SearchAuditLogsQuery) (*SearchAud
 
 func (st *SteamTracker) GetSearchAuditLogs(w http.ResponseWriter, r *http.Request) {
 	query := SearchAuditLogsQuery{}
-
-	if v := r.URL.Query().Get("page"); v != "" {
-		page, _ := strconv.Atoi(v)
-		query.Page = page
-	}
-
-	if v := r.URL.Query().Get("limit"); v != "" {
-		limit, _ := strconv.Atoi(v)
-		query.Limit = limit
-	}
-
-	if v := r.URL.Query().Get("sort_by[id]"); v != "" {
-		sortOrder := strings.ToLower(v)
-		query.SortBy.ID = &sortOrder
-	}
-
-	_ = json.NewDecoder(r.Body).Decode(&query)
-
-	if err := query.Validate(); err != nil {
+	if err := bindQuery(r, &query); err != nil {
 		http.Error(w, fmt.Sprintf("Invalid query parameters: %v", err), http.StatusBadRequest)
 		return
 	}
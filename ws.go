@@ -0,0 +1,319 @@
+package steamtracker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+// Message types of the /api/v1/ws protocol. Clients send subscribe,
+// unsubscribe and poll_now, and get a message of the same type back, or an
+// error. The server sends event for each new event of a subscribed player.
+const (
+	WSMessageSubscribe   = "subscribe"
+	WSMessageUnsubscribe = "unsubscribe"
+	WSMessagePollNow     = "poll_now"
+	WSMessageEvent       = "event"
+	WSMessageError       = "error"
+)
+
+const (
+	APIErrorInvalidMessage  = "invalid_message"
+	APIErrorRateLimited     = "rate_limited"
+	APIErrorLimitExceeded   = "limit_exceeded"
+	APIErrorUnavailable     = "unavailable"
+	APIErrorForbidden       = "forbidden"
+	APIErrorConflict        = "conflict"
+	APIErrorUpgradeRequired = "upgrade_required"
+)
+
+// Per-connection limits of /api/v1/ws.
+const (
+	wsReadLimit        = 4096
+	wsMaxSubscriptions = 100
+	// At most wsMaxMessages client messages are handled per wsMessageWindow,
+	// the rest are answered with rate_limited.
+	wsMaxMessages   = 20
+	wsMessageWindow = 10 * time.Second
+	wsWriteTimeout  = 10 * time.Second
+	wsPingInterval  = 30 * time.Second
+)
+
+// WSMessage is a message of the /api/v1/ws protocol, in either direction.
+type WSMessage struct {
+	Type string `json:"type"`
+	// ID is chosen by the client and repeated in the reply to its message.
+	ID string `json:"id,omitempty"`
+	// SteamIDs are the players to subscribe to or unsubscribe from and, in
+	// replies, every player the connection is subscribed to.
+	SteamIDs []SteamID    `json:"steam_ids,omitempty"`
+	Event    *PlayerEvent `json:"event,omitempty"`
+	Error    *APIError    `json:"error,omitempty"`
+}
+
+type wsConn struct {
+	st   *SteamTracker
+	conn *websocket.Conn
+	// principal opened the connection. poll_now requires the admin scope.
+	principal *Principal
+
+	mu         sync.Mutex
+	subscribed map[SteamID]bool
+
+	windowStart time.Time
+	messages    int
+}
+
+// GetV1WebSocket upgrades the request to a WebSocket that subscribes to
+// players, receives their events and can ask for an immediate poll.
+func (st *SteamTracker) GetV1WebSocket(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		w.Header().Set("Upgrade", "websocket")
+		writeAPIError(w, http.StatusUpgradeRequired, APIErrorUpgradeRequired, "this endpoint only accepts WebSocket connections")
+		return
+	}
+	if !st.webSocketOriginAllowed(r) {
+		writeAPIError(w, http.StatusForbidden, APIErrorForbidden, fmt.Sprintf("origin %s is not allowed", r.Header.Get("Origin")))
+		return
+	}
+
+	// The origin was checked above, in the same way Accept would.
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to accept WebSocket connection")
+		return
+	}
+	defer func() { _ = conn.CloseNow() }()
+	conn.SetReadLimit(wsReadLimit)
+
+	event := log.Debug().Str("action", "websocket").Str("remote_addr", r.RemoteAddr)
+	defer func() { event.Send() }()
+
+	ctx, cancel := context.WithCancel(st.ctx)
+	defer cancel()
+
+	c := &wsConn{st: st, conn: conn, principal: PrincipalFromContext(r.Context()), subscribed: make(map[SteamID]bool)}
+	sub := st.playerEvents.subscribe()
+	defer st.playerEvents.unsubscribe(sub)
+
+	go func() {
+		defer cancel()
+		c.readLoop(ctx)
+	}()
+
+	status, reason := c.writeLoop(ctx, sub)
+	event.Int("close_status", int(status)).Int("subscriptions", len(c.subscriptions()))
+	_ = conn.Close(status, reason)
+}
+
+// webSocketOriginAllowed accepts requests without an Origin, which do not
+// come from browsers, from the server's own host and from the configured
+// origins.
+func (st *SteamTracker) webSocketOriginAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	for _, pattern := range st.cfg.WebSocketOrigins {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(u.Host)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// readLoop handles client messages until the connection is closed. Reads
+// do not use ctx: canceling a read closes the connection at once, which
+// would cut off the close handshake.
+func (c *wsConn) readLoop(ctx context.Context) {
+	for {
+		_, data, err := c.conn.Read(context.WithoutCancel(ctx))
+		if err != nil {
+			return
+		}
+
+		// Every message counts toward the limit, so invalid ones cannot be
+		// used to keep the server busy parsing.
+		if !c.allowMessage() {
+			c.writeError(ctx, "", APIErrorRateLimited, fmt.Sprintf("at most %d messages per %s", wsMaxMessages, wsMessageWindow))
+			continue
+		}
+
+		msg := WSMessage{}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.writeError(ctx, "", APIErrorInvalidMessage, fmt.Sprintf("message is not valid JSON: %v", err))
+			continue
+		}
+
+		c.handle(ctx, &msg)
+	}
+}
+
+func (c *wsConn) allowMessage() bool {
+	now := time.Now()
+	if now.Sub(c.windowStart) >= wsMessageWindow {
+		c.windowStart = now
+		c.messages = 0
+	}
+
+	c.messages++
+	return c.messages <= wsMaxMessages
+}
+
+func (c *wsConn) handle(ctx context.Context, msg *WSMessage) {
+	switch msg.Type {
+	case WSMessageSubscribe:
+		if len(msg.SteamIDs) == 0 {
+			c.writeError(ctx, msg.ID, APIErrorInvalidMessage, "steam_ids cannot be empty")
+			return
+		}
+		for _, steamID := range msg.SteamIDs {
+			if steamID <= 0 {
+				c.writeError(ctx, msg.ID, APIErrorInvalidMessage, fmt.Sprintf("invalid SteamID: %d", steamID))
+				return
+			}
+		}
+
+		c.mu.Lock()
+		added := make([]SteamID, 0, len(msg.SteamIDs))
+		for _, steamID := range msg.SteamIDs {
+			if !c.subscribed[steamID] {
+				c.subscribed[steamID] = true
+				added = append(added, steamID)
+			}
+		}
+		if len(c.subscribed) > wsMaxSubscriptions {
+			for _, steamID := range added {
+				delete(c.subscribed, steamID)
+			}
+			c.mu.Unlock()
+			c.writeError(ctx, msg.ID, APIErrorLimitExceeded, fmt.Sprintf("at most %d players per connection", wsMaxSubscriptions))
+			return
+		}
+		c.mu.Unlock()
+
+		c.write(ctx, &WSMessage{Type: WSMessageSubscribe, ID: msg.ID, SteamIDs: c.subscriptions()})
+	case WSMessageUnsubscribe:
+		// Without steam_ids, unsubscribe from every player.
+		c.mu.Lock()
+		if len(msg.SteamIDs) == 0 {
+			clear(c.subscribed)
+		}
+		for _, steamID := range msg.SteamIDs {
+			delete(c.subscribed, steamID)
+		}
+		c.mu.Unlock()
+
+		c.write(ctx, &WSMessage{Type: WSMessageUnsubscribe, ID: msg.ID, SteamIDs: c.subscriptions()})
+	case WSMessagePollNow:
+		if c.principal == nil || !c.principal.Scopes.Has(ScopeAdmin) {
+			c.writeError(ctx, msg.ID, APIErrorForbidden, fmt.Sprintf("poll_now requires the %s scope", ScopeAdmin))
+			return
+		}
+
+		err := c.st.PollNow()
+		switch {
+		case errors.Is(err, ErrPollingDisabled):
+			c.writeError(ctx, msg.ID, APIErrorUnavailable, err.Error())
+		case errors.Is(err, ErrPollTooSoon):
+			c.writeError(ctx, msg.ID, APIErrorRateLimited, err.Error())
+		case errors.Is(err, ErrPollInProgress):
+			c.writeError(ctx, msg.ID, APIErrorConflict, err.Error())
+		case err != nil:
+			c.writeError(ctx, msg.ID, APIErrorInternal, err.Error())
+		default:
+			c.write(ctx, &WSMessage{Type: WSMessagePollNow, ID: msg.ID})
+		}
+	default:
+		c.writeError(ctx, msg.ID, APIErrorInvalidMessage, fmt.Sprintf("unknown message type: %q", msg.Type))
+	}
+}
+
+func (c *wsConn) isSubscribed(steamID SteamID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.subscribed[steamID]
+}
+
+// subscriptions returns the subscribed players in ascending order.
+func (c *wsConn) subscriptions() []SteamID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	steamIDs := make([]SteamID, 0, len(c.subscribed))
+	for steamID := range c.subscribed {
+		steamIDs = append(steamIDs, steamID)
+	}
+	slices.Sort(steamIDs)
+	return steamIDs
+}
+
+// writeLoop sends the events of subscribed players and keeps the connection
+// alive until ctx is done or the client falls behind. It returns the status
+// to close the connection with.
+func (c *wsConn) writeLoop(ctx context.Context, sub *playerEventSubscription) (websocket.StatusCode, string) {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case e, ok := <-sub.events:
+			if !ok {
+				return websocket.StatusTryAgainLater, "too slow to receive events"
+			}
+			if !c.isSubscribed(e.SteamID) {
+				continue
+			}
+			if err := c.write(ctx, &WSMessage{Type: WSMessageEvent, Event: e}); err != nil {
+				return websocket.StatusInternalError, "failed to send event"
+			}
+		case <-ping.C:
+			pingCtx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+			err := c.conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return websocket.StatusGoingAway, "ping timed out"
+			}
+		case <-ctx.Done():
+			if c.st.ctx.Err() != nil {
+				return websocket.StatusGoingAway, "server is shutting down"
+			}
+			return websocket.StatusNormalClosure, ""
+		}
+	}
+}
+
+func (c *wsConn) write(ctx context.Context, msg *WSMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+	defer cancel()
+	return c.conn.Write(ctx, websocket.MessageText, data)
+}
+
+func (c *wsConn) writeError(ctx context.Context, id, code, message string) {
+	_ = c.write(ctx, &WSMessage{Type: WSMessageError, ID: id, Error: &APIError{Code: code, Message: message}})
+}